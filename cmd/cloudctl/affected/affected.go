@@ -0,0 +1,88 @@
+package affected
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/affected"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/factories"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputFormatTable = "table"
+	outputFormatJSON  = "json"
+)
+
+func NewAffectedCmd(locator *factories.SharedServicesLocator) *cobra.Command {
+	var since, env, output string
+
+	affectedCmd := &cobra.Command{
+		Use:   "affected",
+		Short: "List the services affected by the changes since a git revision",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if since == "" {
+				return fmt.Errorf("%w - git revision to compare with is required (--since)", lib.BadUserInputError)
+			}
+			if output != outputFormatTable && output != outputFormatJSON {
+				return fmt.Errorf("%w - unsupported output format '%s', supported are %s, %s", lib.BadUserInputError, output, outputFormatTable, outputFormatJSON)
+			}
+
+			serviceLocator := locator
+			if env != "" {
+				envSpecificConfig, err := locator.Config.WithEnvironment(env)
+				if err != nil {
+					return fmt.Errorf("loading environment specific config: %w", err)
+				}
+				serviceLocator = locator.WithConfig(envSpecificConfig)
+			}
+
+			results, err := affected.FindSince(serviceLocator, since)
+			if err != nil {
+				return err
+			}
+
+			if output == outputFormatJSON {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(results)
+			}
+			return writeAffectedTable(cmd.OutOrStdout(), results)
+		},
+	}
+
+	affectedCmd.Flags().StringVar(&since, "since", "", "Git revision the working tree is compared with, like main or HEAD~1")
+	affectedCmd.Flags().StringVar(&env, "env", "", "Environment whose config is used, the pipeline config may differ per environment")
+	affectedCmd.Flags().StringVarP(&output, "output", "o", outputFormatTable, "Output format (table, json)")
+
+	return affectedCmd
+}
+
+func writeAffectedTable(out io.Writer, results []affected.Result) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tAPP\tAFFECTED\tCHANGES")
+	for _, result := range results {
+		affectedValue := "no"
+		if result.Affected {
+			affectedValue = "yes"
+		}
+		changes := strings.Join(result.Changes, ", ")
+		if result.Affected && result.App == "" {
+			changes = "(no pipeline app)"
+		}
+		if changes == "" {
+			changes = "-"
+		}
+		app := result.App
+		if app == "" {
+			app = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.Service, app, affectedValue, changes)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,17 @@
+package cache
+
+import (
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/factories"
+	"github.com/spf13/cobra"
+)
+
+func NewCacheCmd(locator *factories.SharedServicesLocator) *cobra.Command {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the local cache of recompressed image layers",
+	}
+
+	cacheCmd.AddCommand(newCachePruneCmd(locator))
+
+	return cacheCmd
+}
@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/layercache"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/factories"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+)
+
+func newCachePruneCmd(locator *factories.SharedServicesLocator) *cobra.Command {
+	var serviceID, env, dir, maxSize string
+	var all bool
+
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove the least recently used cached layers until the cache fits the size limit",
+		Long: `Remove the least recently used cached layers until the cache fits the size limit.
+
+The cache directory and size limit come from the compression.cache config of the services,
+every distinct directory is pruned to the smallest limit configured for it. --dir and --max-size
+override the config.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var limitOverride *int64
+			if maxSize != "" {
+				limit, err := units.RAMInBytes(maxSize)
+				if err != nil {
+					return fmt.Errorf("%w - invalid max size '%s': %w", lib.BadUserInputError, maxSize, err)
+				}
+				limitOverride = &limit
+			}
+
+			limits, err := getCacheLimits(locator, serviceID, env, dir)
+			if err != nil {
+				return err
+			}
+
+			for _, cacheDir := range slices.Sorted(maps.Keys(limits)) {
+				cache := layercache.NewCache(cacheDir)
+
+				if all {
+					if err := cache.Clear(); err != nil {
+						return err
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "Removed layer cache %s\n", cacheDir)
+					continue
+				}
+
+				limit := limits[cacheDir]
+				if limitOverride != nil {
+					limit = *limitOverride
+				}
+
+				result, err := cache.Prune(limit)
+				if err != nil {
+					return err
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "Removed %d cached layers and %d stale digests, freed %s, %s left in %s\n",
+					result.RemovedBlobs,
+					result.RemovedMetadata,
+					units.BytesSize(float64(result.FreedBytes)),
+					units.BytesSize(float64(result.RemainingBytes)),
+					cacheDir)
+			}
+
+			return nil
+		},
+	}
+
+	pruneCmd.Flags().StringVar(&serviceID, "name", "", "Service whose cache config is used, defaults to all services")
+	pruneCmd.Flags().StringVar(&env, "env", "", "Environment whose config is used, the cache config may differ per environment")
+	pruneCmd.Flags().StringVar(&dir, "dir", "", "Cache directory, overrides the configured one")
+	pruneCmd.Flags().StringVar(&maxSize, "max-size", "", "Size the cached layers are pruned to, e.g. 2GB or 0, overrides the configured one")
+	pruneCmd.Flags().BoolVar(&all, "all", false, "Remove the whole cache, including the digests of already pruned layers")
+
+	return pruneCmd
+}
+
+// getCacheLimits returns the size limit of every cache directory the services use. Services sharing a directory
+// prune it to the smallest of their limits. The default directory and size are used when no service caches layers.
+func getCacheLimits(locator *factories.SharedServicesLocator, serviceID, env, dir string) (map[string]int64, error) {
+	if env != "" {
+		envSpecificConfig, err := locator.Config.WithEnvironment(env)
+		if err != nil {
+			return nil, fmt.Errorf("loading environment specific config: %w", err)
+		}
+		locator = locator.WithConfig(envSpecificConfig)
+	}
+
+	serviceIDs := slices.Sorted(maps.Keys(locator.Config.Services))
+	if serviceID != "" {
+		if _, ok := locator.Config.Services[serviceID]; !ok {
+			return nil, fmt.Errorf("%w - service %s not found in config", lib.BadUserInputError, serviceID)
+		}
+		serviceIDs = []string{serviceID}
+	}
+
+	limits := map[string]int64{}
+	for _, id := range serviceIDs {
+		cache, limit, err := factories.NewServiceFactory(id, locator).NewLayerCache()
+		if err != nil {
+			return nil, fmt.Errorf("getting layer cache of service %s: %w", id, err)
+		}
+		if cache == nil {
+			continue
+		}
+
+		cacheDir := cache.Dir()
+		if dir != "" {
+			cacheDir = dir
+		}
+		if current, ok := limits[cacheDir]; !ok || limit < current {
+			limits[cacheDir] = limit
+		}
+	}
+
+	if len(limits) == 0 {
+		if dir == "" {
+			defaultDir, err := layercache.DefaultDir()
+			if err != nil {
+				return nil, err
+			}
+			dir = defaultDir
+		}
+		limits[dir] = layercache.DefaultMaxSize
+	}
+
+	return limits, nil
+}
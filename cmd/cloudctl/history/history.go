@@ -0,0 +1,165 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/factories"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/ledger"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputFormatTable = "table"
+	outputFormatJSON  = "json"
+)
+
+func NewHistoryCmd(locator *factories.SharedServicesLocator) *cobra.Command {
+	var serviceID, env, output string
+	var limit int
+
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "List the local history of builds, pushes and deploys",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutputFormat(output); err != nil {
+				return err
+			}
+
+			entries, err := locator.Ledger.List(serviceID, env)
+			if err != nil {
+				return err
+			}
+			if limit > 0 && len(entries) > limit {
+				entries = entries[:limit]
+			}
+
+			if output == outputFormatJSON {
+				return writeJSON(cmd.OutOrStdout(), entries)
+			}
+			return writeHistoryTable(cmd.OutOrStdout(), entries)
+		},
+	}
+
+	historyCmd.PersistentFlags().StringVarP(&output, "output", "o", outputFormatTable, "Output format (table, json)")
+	historyCmd.Flags().StringVar(&serviceID, "name", "", "Only list the entries of the service")
+	historyCmd.Flags().StringVar(&env, "env", "", "Only list the entries of the environment")
+	historyCmd.Flags().IntVar(&limit, "limit", 20, "Maximum number of entries listed, newest first (0 lists all)")
+
+	historyCmd.AddCommand(newHistoryShowCmd(locator, &output))
+
+	return historyCmd
+}
+
+func newHistoryShowCmd(locator *factories.SharedServicesLocator, output *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <id>",
+		Short: "Print the details of a history entry, a unique prefix of the ID is enough",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutputFormat(*output); err != nil {
+				return err
+			}
+
+			entry, err := locator.Ledger.Get(args[0])
+			if err != nil {
+				return err
+			}
+
+			if *output == outputFormatJSON {
+				return writeJSON(cmd.OutOrStdout(), entry)
+			}
+			return writeHistoryEntry(cmd.OutOrStdout(), entry)
+		},
+	}
+}
+
+func checkOutputFormat(output string) error {
+	if output != outputFormatTable && output != outputFormatJSON {
+		return fmt.Errorf("%w - unsupported output format '%s', supported are %s, %s", lib.BadUserInputError, output, outputFormatTable, outputFormatJSON)
+	}
+	return nil
+}
+
+func writeJSON(out io.Writer, value any) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func writeHistoryTable(out io.Writer, entries []ledger.Entry) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTARTED\tCOMMAND\tSERVICE\tENV\tOUTCOME\tDURATION\tDIGEST\tCOMMIT\tUSER")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.ID,
+			entry.StartedAt.Local().Format(time.RFC3339),
+			entry.Command,
+			entry.Service,
+			lib.ValueOrDash(entry.Environment),
+			entry.Outcome,
+			entry.Duration().Round(time.Millisecond),
+			lib.ValueOrDash(shortDigest(entry.Digest)),
+			lib.ValueOrDash(shortCommit(entry.Commit)),
+			lib.ValueOrDash(entry.User))
+	}
+	return w.Flush()
+}
+
+func writeHistoryEntry(out io.Writer, entry ledger.Entry) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%s\n", entry.ID)
+	fmt.Fprintf(w, "Command:\t%s\n", entry.Command)
+	fmt.Fprintf(w, "Service:\t%s\n", entry.Service)
+	fmt.Fprintf(w, "Environment:\t%s\n", lib.ValueOrDash(entry.Environment))
+	fmt.Fprintf(w, "Outcome:\t%s\n", entry.Outcome)
+	if entry.Error != "" {
+		fmt.Fprintf(w, "Error:\t%s\n", entry.Error)
+	}
+	fmt.Fprintf(w, "User:\t%s\n", lib.ValueOrDash(entry.User))
+	fmt.Fprintf(w, "Commit:\t%s\n", lib.ValueOrDash(entry.Commit))
+	fmt.Fprintf(w, "Image:\t%s\n", lib.ValueOrDash(entry.ImageRef))
+	fmt.Fprintf(w, "Digest:\t%s\n", lib.ValueOrDash(entry.Digest))
+	fmt.Fprintf(w, "Started:\t%s\n", entry.StartedAt.Local().Format(time.RFC3339))
+	fmt.Fprintf(w, "Finished:\t%s\n", entry.FinishedAt.Local().Format(time.RFC3339))
+	fmt.Fprintf(w, "Duration:\t%s\n", entry.Duration().Round(time.Millisecond))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(entry.Phases) == 0 {
+		return nil
+	}
+	fmt.Fprintln(out)
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PHASE\tSTARTED\tDURATION\tERROR")
+	for _, phase := range entry.Phases {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			phase.Phase,
+			phase.StartedAt.Local().Format(time.RFC3339),
+			time.Duration(phase.DurationMs)*time.Millisecond,
+			lib.ValueOrDash(phase.Error))
+	}
+	return w.Flush()
+}
+
+func shortDigest(digest string) string {
+	_, hex, ok := strings.Cut(digest, ":")
+	if !ok || len(hex) <= 12 {
+		return digest
+	}
+	return hex[:12]
+}
+
+func shortCommit(commit string) string {
+	if len(commit) <= 7 {
+		return commit
+	}
+	return commit[:7]
+}
@@ -0,0 +1,19 @@
+package image
+
+import (
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/factories"
+	"github.com/spf13/cobra"
+)
+
+func NewImageCmd(locator *factories.SharedServicesLocator) *cobra.Command {
+	imageCmd := &cobra.Command{
+		Use:   "image",
+		Short: "Sign, verify and inspect service images",
+	}
+
+	imageCmd.AddCommand(newImageVerifyCmd(locator))
+	imageCmd.AddCommand(newImageKeyCmd(locator))
+	imageCmd.AddCommand(newImageProvenanceCmd(locator))
+
+	return imageCmd
+}
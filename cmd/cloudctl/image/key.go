@@ -0,0 +1,134 @@
+package image
+
+import (
+	"crypto"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/signing"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/factories"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/spf13/cobra"
+)
+
+func newImageKeyCmd(locator *factories.SharedServicesLocator) *cobra.Command {
+	keyCmd := &cobra.Command{
+		Use:   "key",
+		Short: "Manage image signing keys",
+	}
+
+	keyCmd.AddCommand(newImageKeyGenerateCmd(locator))
+	keyCmd.AddCommand(newImageKeyImportCmd(locator))
+
+	return keyCmd
+}
+
+func newImageKeyGenerateCmd(locator *factories.SharedServicesLocator) *cobra.Command {
+	var keyType, keyringKey, file string
+
+	generateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate a signing key into the keyring or a file and print its public key",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (keyringKey == "") == (file == "") {
+				return fmt.Errorf("%w - exactly one of --keyring and --file is required", lib.BadUserInputError)
+			}
+
+			key, err := signing.GenerateKey(signing.KeyType(keyType))
+			if err != nil {
+				return err
+			}
+			privatePem, err := signing.MarshalPrivateKey(key)
+			if err != nil {
+				return err
+			}
+
+			if file != "" {
+				// O_EXCL keeps an existing key from being overwritten
+				f, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+				if err != nil {
+					return fmt.Errorf("creating key file %s: %w", file, err)
+				}
+				_, err = f.Write(privatePem)
+				if closeErr := f.Close(); err == nil {
+					err = closeErr
+				}
+				if err != nil {
+					return fmt.Errorf("writing key file %s: %w", file, err)
+				}
+			} else {
+				if err := storeKey(locator.SigningKeysStorage, keyringKey, privatePem); err != nil {
+					return err
+				}
+			}
+
+			return writePublicKey(cmd.OutOrStdout(), key)
+		},
+	}
+
+	generateCmd.Flags().StringVar(&keyType, "type", string(signing.KeyTypeEcdsa), "Key type (ecdsa, ed25519)")
+	generateCmd.Flags().StringVar(&keyringKey, "keyring", "", "Name of the keyring entry to store the key in")
+	generateCmd.Flags().StringVar(&file, "file", "", "File to write the PEM private key to")
+
+	return generateCmd
+}
+
+func newImageKeyImportCmd(locator *factories.SharedServicesLocator) *cobra.Command {
+	var keyringKey, file string
+
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Store a PEM signing key file in the keyring and print its public key",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if keyringKey == "" || file == "" {
+				return fmt.Errorf("%w - --keyring and --file are required", lib.BadUserInputError)
+			}
+
+			// The key is stored decrypted, so it is usable without the password afterwards
+			key, err := signing.LoadPrivateKey(signing.KeyConfig{File: file}, nil)
+			if err != nil {
+				return err
+			}
+			privatePem, err := signing.MarshalPrivateKey(key)
+			if err != nil {
+				return err
+			}
+
+			if err := storeKey(locator.SigningKeysStorage, keyringKey, privatePem); err != nil {
+				return err
+			}
+
+			return writePublicKey(cmd.OutOrStdout(), key)
+		},
+	}
+
+	importCmd.Flags().StringVar(&keyringKey, "keyring", "", "Name of the keyring entry to store the key in")
+	importCmd.Flags().StringVar(&file, "file", "", "PEM private key file, cosign encrypted keys are decrypted with "+lib.SigningKeyPasswordEnv)
+
+	return importCmd
+}
+
+func storeKey(storage lib.CredentialsStorage, name string, privatePem []byte) error {
+	existing, err := storage.Get(name)
+	if err != nil {
+		return err
+	}
+	if existing != "" {
+		return fmt.Errorf("%w - the keyring already has a signing key %s", lib.BadUserInputError, name)
+	}
+
+	return storage.Set(name, string(privatePem), lib.KeyExtras{
+		Label:       fmt.Sprintf("cloudctl signing key %s", name),
+		Description: "Private key cloudctl signs container images with",
+	})
+}
+
+func writePublicKey(out io.Writer, key crypto.Signer) error {
+	publicPem, err := signing.MarshalPublicKey(key.Public())
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(publicPem)
+	return err
+}
@@ -0,0 +1,68 @@
+package image
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/factories"
+	"github.com/spf13/cobra"
+)
+
+func newImageProvenanceCmd(locator *factories.SharedServicesLocator) *cobra.Command {
+	var serviceID, env, ref, publicKey string
+
+	provenanceCmd := &cobra.Command{
+		Use:   "provenance",
+		Short: "Print the provenance attestations attached to the service image",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if serviceID == "" {
+				return fmt.Errorf("service is required")
+			}
+			if env == "" {
+				return fmt.Errorf("environment is required")
+			}
+
+			envSpecificConfig, err := locator.Config.WithEnvironment(env)
+			if err != nil {
+				return fmt.Errorf("loading environment specific config: %w", err)
+			}
+
+			serviceFactory := factories.NewServiceFactory(serviceID, locator.WithConfig(envSpecificConfig))
+			imageSvc, err := serviceFactory.NewImageService()
+			if err != nil {
+				return fmt.Errorf("getting image for service %s: %w", serviceID, err)
+			}
+
+			ctx := cmd.Context()
+
+			image, err := imageSvc.ResolveImageDigestRef(ctx, ref)
+			if err != nil {
+				return err
+			}
+
+			statements, err := imageSvc.GetImageProvenance(ctx, image, publicKey)
+			if err != nil {
+				return err
+			}
+			if len(statements) == 0 {
+				return fmt.Errorf("no provenance attestation found for %s", image)
+			}
+
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			for _, statement := range statements {
+				if err := encoder.Encode(statement); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	provenanceCmd.Flags().StringVar(&serviceID, "name", "", "Service whose image provenance is printed")
+	provenanceCmd.Flags().StringVar(&env, "env", "", "Target environment")
+	provenanceCmd.Flags().StringVar(&ref, "ref", "", "Image reference, defaults to the service registry image")
+	provenanceCmd.Flags().StringVar(&publicKey, "key", "", "PEM public key file, only attestations signed with it are printed")
+
+	return provenanceCmd
+}
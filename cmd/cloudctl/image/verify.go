@@ -0,0 +1,58 @@
+package image
+
+import (
+	"fmt"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/factories"
+	"github.com/spf13/cobra"
+)
+
+func newImageVerifyCmd(locator *factories.SharedServicesLocator) *cobra.Command {
+	var serviceID, env, ref, publicKey string
+
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check that the service image has a valid signature",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if serviceID == "" {
+				return fmt.Errorf("service is required")
+			}
+			if env == "" {
+				return fmt.Errorf("environment is required")
+			}
+
+			envSpecificConfig, err := locator.Config.WithEnvironment(env)
+			if err != nil {
+				return fmt.Errorf("loading environment specific config: %w", err)
+			}
+
+			serviceFactory := factories.NewServiceFactory(serviceID, locator.WithConfig(envSpecificConfig))
+			imageSvc, err := serviceFactory.NewImageService()
+			if err != nil {
+				return fmt.Errorf("getting image for service %s: %w", serviceID, err)
+			}
+
+			ctx := cmd.Context()
+
+			image, err := imageSvc.ResolveImageDigestRef(ctx, ref)
+			if err != nil {
+				return err
+			}
+
+			valid, err := imageSvc.VerifyImageSignature(ctx, image, publicKey)
+			if err != nil {
+				return err
+			}
+
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Verified %s: %d valid signature(s)\n", image, valid)
+			return err
+		},
+	}
+
+	verifyCmd.Flags().StringVar(&serviceID, "name", "", "Service whose image is verified")
+	verifyCmd.Flags().StringVar(&env, "env", "", "Target environment")
+	verifyCmd.Flags().StringVar(&ref, "ref", "", "Image reference to verify, defaults to the service registry image")
+	verifyCmd.Flags().StringVar(&publicKey, "key", "", "PEM public key file, defaults to the signing config of the service")
+
+	return verifyCmd
+}
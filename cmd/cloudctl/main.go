@@ -7,11 +7,17 @@ import (
 	"os"
 	"strings"
 
+	"github.com/AnotherFullstackDev/cloud-ctl/cmd/cloudctl/affected"
+	"github.com/AnotherFullstackDev/cloud-ctl/cmd/cloudctl/cache"
+	"github.com/AnotherFullstackDev/cloud-ctl/cmd/cloudctl/history"
+	"github.com/AnotherFullstackDev/cloud-ctl/cmd/cloudctl/image"
 	"github.com/AnotherFullstackDev/cloud-ctl/cmd/cloudctl/service"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/config"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/factories"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/keyring"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/ledger"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lockfile"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/placeholders"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/placeholders/git"
 	"github.com/spf13/cobra"
@@ -52,11 +58,29 @@ func main() {
 
 	registryCredentialsStorage := keyring.MustNewService("container-registry")
 	cloudApiCredentialsStorage := keyring.MustNewService("cloud-api-credentials")
+	signingKeysStorage := keyring.MustNewService("signing-keys")
 	placeholdersService := placeholders.NewService(gitRepository)
-	sharedServicesLocator := factories.NewSharedServicesLocator(cfg, registryCredentialsStorage, cloudApiCredentialsStorage, placeholdersService)
+
+	lockfilePath := os.Getenv(lib.LockfilePathEnv)
+	if lockfilePath == "" {
+		lockfilePath = lockfile.DefaultPath
+	}
+	deployLockfile := lockfile.NewService(lockfilePath)
+
+	historyPath := os.Getenv(lib.HistoryPathEnv)
+	if historyPath == "" {
+		historyPath = ledger.DefaultPath
+	}
+	deployHistory := ledger.NewService(historyPath)
+
+	sharedServicesLocator := factories.NewSharedServicesLocator(cfg, registryCredentialsStorage, cloudApiCredentialsStorage, signingKeysStorage, placeholdersService, gitRepository, deployLockfile, deployHistory)
 
 	RootCmd.AddCommand(
 		service.NewServiceCmd(sharedServicesLocator),
+		cache.NewCacheCmd(sharedServicesLocator),
+		image.NewImageCmd(sharedServicesLocator),
+		history.NewHistoryCmd(sharedServicesLocator),
+		affected.NewAffectedCmd(sharedServicesLocator),
 	)
 
 	if err := RootCmd.Execute(); err != nil {
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/batch"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/config"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+)
+
+// resolveServiceIDs returns the services selected either by name or with the "all" flag.
+func resolveServiceIDs(cfg *config.Config, names []string, all bool) ([]string, error) {
+	if all && len(names) > 0 {
+		return nil, fmt.Errorf("%w - service names and --all can not be used together", lib.BadUserInputError)
+	}
+	if all {
+		return slices.Sorted(maps.Keys(cfg.Services)), nil
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("service is required")
+	}
+
+	serviceIDs := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || slices.Contains(serviceIDs, name) {
+			continue
+		}
+		if _, ok := cfg.Services[name]; !ok {
+			return nil, fmt.Errorf("%w - service %s not found in config", lib.BadUserInputError, name)
+		}
+		serviceIDs = append(serviceIDs, name)
+	}
+
+	return serviceIDs, nil
+}
+
+// runForServices runs the job for every service honoring the 'depends_on' ordering from the config.
+// A single service behaves as before and returns the job error as is,
+// for several services a summary is written to out once all of them are processed.
+func runForServices(ctx context.Context, out io.Writer, cfg *config.Config, serviceIDs []string, opts batch.Options, job batch.Job) error {
+	dependsOn := make(map[string][]string, len(serviceIDs))
+	for _, serviceID := range serviceIDs {
+		for _, dep := range cfg.Services[serviceID].DependsOn {
+			if _, ok := cfg.Services[dep]; !ok {
+				return fmt.Errorf("%w - service %s depends on unknown service %s", lib.BadUserInputError, serviceID, dep)
+			}
+		}
+		dependsOn[serviceID] = cfg.Services[serviceID].DependsOn
+	}
+
+	results, err := batch.Run(ctx, serviceIDs, dependsOn, opts, job)
+	if err != nil {
+		return fmt.Errorf("scheduling services: %w", err)
+	}
+
+	if len(results) == 1 {
+		return results[0].Err
+	}
+
+	if err := writeBatchSummary(out, results); err != nil {
+		return fmt.Errorf("writing summary: %w", err)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Status != batch.StatusSucceeded {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d services did not complete successfully", failed, len(results))
+	}
+
+	return nil
+}
+
+func writeBatchSummary(out io.Writer, results []batch.Result) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tRESULT\tDURATION\tERROR")
+	for _, result := range results {
+		duration := "-"
+		if result.Duration > 0 {
+			duration = result.Duration.Round(100 * time.Millisecond).String()
+		}
+		errMessage := "-"
+		if result.Err != nil {
+			errMessage = result.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.ID, result.Status, duration, errMessage)
+	}
+
+	return w.Flush()
+}
@@ -1,22 +1,27 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/batch"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/factories"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/ledger"
 	"github.com/spf13/cobra"
 )
 
 func newServiceBuildCmd(locator *factories.SharedServicesLocator) *cobra.Command {
-	var serviceID, env string
+	var serviceIDs []string
+	var env string
+	var all, failFast bool
+	var concurrency int
 
 	buildCmd := &cobra.Command{
 		Use:   "build",
 		Short: "Build a service's container image",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if serviceID == "" {
-				return fmt.Errorf("must provide a service ID")
-			}
 			if env == "" {
 				return fmt.Errorf("must provide a environment name")
 			}
@@ -26,25 +31,58 @@ func newServiceBuildCmd(locator *factories.SharedServicesLocator) *cobra.Command
 				return fmt.Errorf("loading environment specific config: %w", err)
 			}
 
-			serviceFactory := factories.NewServiceFactory(serviceID, locator.WithConfig(envSpecificConfig))
-
-			imageSvc, err := serviceFactory.NewImageService()
+			selectedServiceIDs, err := resolveServiceIDs(envSpecificConfig, serviceIDs, all)
 			if err != nil {
-				return fmt.Errorf("getting image for service %s: %w", serviceID, err)
-			}
-
-			ctx := cmd.Context()
-
-			if err := imageSvc.BuildImage(ctx); err != nil {
-				return fmt.Errorf("building image for service %s: %w", serviceID, err)
+				return err
 			}
 
-			return nil
+			envLocator := locator.WithConfig(envSpecificConfig)
+			return runForServices(cmd.Context(), cmd.OutOrStdout(), envSpecificConfig, selectedServiceIDs, batch.Options{
+				Concurrency: concurrency,
+				FailFast:    failFast,
+			}, func(ctx context.Context, serviceID string) error {
+				return buildService(ctx, envLocator, serviceID, env)
+			})
 		},
 	}
 
-	buildCmd.PersistentFlags().StringVar(&serviceID, "name", "", "Service to build")
+	buildCmd.PersistentFlags().StringSliceVar(&serviceIDs, "name", nil, "Services to build (comma separated or repeated)")
 	buildCmd.PersistentFlags().StringVar(&env, "env", "", "Target environment")
+	buildCmd.PersistentFlags().BoolVar(&all, "all", false, "Build all configured services")
+	buildCmd.PersistentFlags().IntVar(&concurrency, "concurrency", 4, "Maximum number of services built in parallel")
+	buildCmd.PersistentFlags().BoolVar(&failFast, "fail-fast", false, "Stop starting new builds after the first failure")
 
 	return buildCmd
 }
+
+func buildService(ctx context.Context, locator *factories.SharedServicesLocator, serviceID, env string) (err error) {
+	run := startRun(ctx, locator, "build", serviceID, env)
+	defer func() { finishRun(ctx, locator, run, err) }()
+
+	serviceFactory := factories.NewServiceFactory(serviceID, locator)
+
+	imageSvc, err := serviceFactory.NewImageService()
+	if err != nil {
+		return fmt.Errorf("getting image for service %s: %w", serviceID, err)
+	}
+	defer closeImageService(ctx, imageSvc, serviceID)
+	if err := imageSvc.CheckBuildOnly(); err != nil {
+		return fmt.Errorf("building image for service %s: %w", serviceID, err)
+	}
+
+	err = run.Phase(ledger.PhaseBuild, func() error {
+		return imageSvc.BuildImage(ctx)
+	})
+	if err != nil {
+		return fmt.Errorf("building image for service %s: %w", serviceID, err)
+	}
+
+	return nil
+}
+
+// closeImageService releases the temporary files of the image build, a failure is only logged.
+func closeImageService(ctx context.Context, imageSvc *container_image.Service, serviceID string) {
+	if err := imageSvc.Close(); err != nil {
+		slog.WarnContext(ctx, "failed to clean up the image build", "service", serviceID, "error", err)
+	}
+}
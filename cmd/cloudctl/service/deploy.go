@@ -1,22 +1,31 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"io"
 
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/affected"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/batch"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/factories"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/ledger"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/spf13/cobra"
 )
 
 func newServiceDeployCmd(locator *factories.SharedServicesLocator) *cobra.Command {
-	var serviceID, env string
+	var serviceIDs []string
+	var env, since string
+	var all, affectedOnly, failFast, dryRun bool
+	var concurrency int
 
 	deployImageCmd := &cobra.Command{
 		Use:   "deploy [name]",
 		Short: "Deploy a service to the cloud provider",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if serviceID == "" {
-				return fmt.Errorf("service is required")
-			}
 			if env == "" {
 				return fmt.Errorf("environment is required")
 			}
@@ -26,34 +35,143 @@ func newServiceDeployCmd(locator *factories.SharedServicesLocator) *cobra.Comman
 				return fmt.Errorf("loading environment specific config: %w", err)
 			}
 
-			serviceFactory := factories.NewServiceFactory(serviceID, locator.WithConfig(envSpecificConfig))
-
-			serviceProvider, err := serviceFactory.NewCloudProvider()
-			if err != nil {
-				return fmt.Errorf("getting provider for service %s: %w", serviceID, err)
-			}
-
-			imageSvc, err := serviceFactory.NewImageService()
-			if err != nil {
-				return fmt.Errorf("getting image for service %s: %w", serviceID, err)
-			}
+			envLocator := locator.WithConfig(envSpecificConfig)
 
-			ctx := cmd.Context()
+			var selectedServiceIDs []string
+			if affectedOnly {
+				if all || len(serviceIDs) > 0 {
+					return fmt.Errorf("%w - --affected can not be used together with service names or --all", lib.BadUserInputError)
+				}
+				if since == "" {
+					return fmt.Errorf("%w - --affected requires the git revision to compare with (--since)", lib.BadUserInputError)
+				}
 
-			if err := imageSvc.BuildImage(ctx); err != nil {
-				return fmt.Errorf("building image for service %s: %w", serviceID, err)
+				results, err := affected.FindSince(envLocator, since)
+				if err != nil {
+					return err
+				}
+				selectedServiceIDs = affected.AffectedServices(results)
+				if len(selectedServiceIDs) == 0 {
+					_, err := fmt.Fprintf(cmd.OutOrStdout(), "No services affected by the changes since %s\n", since)
+					return err
+				}
+			} else {
+				if since != "" {
+					return fmt.Errorf("%w - --since is only used with --affected", lib.BadUserInputError)
+				}
+				selectedServiceIDs, err = resolveServiceIDs(envSpecificConfig, serviceIDs, all)
+				if err != nil {
+					return err
+				}
 			}
 
-			if err := imageSvc.PushImage(ctx); err != nil {
-				return fmt.Errorf("pushing image for service %s: %w", serviceID, err)
+			out := &syncWriter{out: cmd.OutOrStdout()}
+			if dryRun {
+				return runForServices(cmd.Context(), cmd.OutOrStdout(), envSpecificConfig, selectedServiceIDs, batch.Options{
+					Concurrency: concurrency,
+					FailFast:    failFast,
+				}, func(ctx context.Context, serviceID string) error {
+					return planService(ctx, envLocator, serviceID, out)
+				})
 			}
 
-			return serviceProvider.DeployServiceFromImage(cmd.Context(), imageSvc.GetRegistry())
+			return runForServices(cmd.Context(), cmd.OutOrStdout(), envSpecificConfig, selectedServiceIDs, batch.Options{
+				Concurrency: concurrency,
+				FailFast:    failFast,
+			}, func(ctx context.Context, serviceID string) error {
+				return deployService(ctx, envLocator, serviceID, env, out)
+			})
 		},
 	}
 
-	deployImageCmd.PersistentFlags().StringVar(&serviceID, "name", "", "Service to deploy")
+	deployImageCmd.PersistentFlags().StringSliceVar(&serviceIDs, "name", nil, "Services to deploy (comma separated or repeated)")
 	deployImageCmd.PersistentFlags().StringVar(&env, "env", "", "Target environment")
+	deployImageCmd.PersistentFlags().BoolVar(&all, "all", false, "Deploy all configured services")
+	deployImageCmd.PersistentFlags().BoolVar(&affectedOnly, "affected", false, "Deploy only the services affected by the changes since the --since revision")
+	deployImageCmd.PersistentFlags().StringVar(&since, "since", "", "Git revision the working tree is compared with for --affected, like main or HEAD~1")
+	deployImageCmd.PersistentFlags().IntVar(&concurrency, "concurrency", 4, "Maximum number of services deployed in parallel")
+	deployImageCmd.PersistentFlags().BoolVar(&failFast, "fail-fast", false, "Stop starting new deploys after the first failure")
+	deployImageCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Print what the deploy would change without building, pushing or updating anything")
 
 	return deployImageCmd
 }
+
+func deployService(ctx context.Context, locator *factories.SharedServicesLocator, serviceID, env string, out io.Writer) (err error) {
+	run := startRun(ctx, locator, "deploy", serviceID, env)
+	defer func() { finishRun(ctx, locator, run, err) }()
+
+	serviceFactory := factories.NewServiceFactory(serviceID, locator)
+
+	serviceProvider, err := serviceFactory.NewCloudProvider()
+	if err != nil {
+		return fmt.Errorf("getting provider for service %s: %w", serviceID, err)
+	}
+
+	imageSvc, err := serviceFactory.NewImageService()
+	if err != nil {
+		return fmt.Errorf("getting image for service %s: %w", serviceID, err)
+	}
+	defer closeImageService(ctx, imageSvc, serviceID)
+
+	platform, err := serviceProvider.GetRuntimePlatform(ctx)
+	if err != nil {
+		return fmt.Errorf("getting runtime platform for service %s: %w", serviceID, err)
+	}
+
+	err = run.Phase(ledger.PhaseBuild, func() error {
+		return imageSvc.BuildImage(ctx)
+	})
+	if err != nil {
+		return fmt.Errorf("building image for service %s: %w", serviceID, err)
+	}
+
+	var digest v1.Hash
+	err = run.Phase(ledger.PhasePush, func() error {
+		pushedDigest, err := imageSvc.PushImage(ctx, container_image.PushOptions{Platform: platform})
+		if err != nil {
+			return fmt.Errorf("pushing image for service %s: %w", serviceID, err)
+		}
+		digest = pushedDigest
+
+		pushedImage, err := imageSvc.GetImageDigestRef(digest)
+		if err != nil {
+			return fmt.Errorf("resolving pushed image for service %s: %w", serviceID, err)
+		}
+		if imageSvc.IsProvenanceEnabled() {
+			if err := imageSvc.AttachProvenance(ctx, pushedImage); err != nil {
+				return fmt.Errorf("attaching provenance for service %s: %w", serviceID, err)
+			}
+		}
+		if imageSvc.IsSigningEnabled() {
+			if err := imageSvc.SignImage(ctx, pushedImage); err != nil {
+				return fmt.Errorf("signing image for service %s: %w", serviceID, err)
+			}
+		}
+		if imageSvc.IsSignatureRequired() {
+			if _, err := imageSvc.VerifyImageSignature(ctx, pushedImage, ""); err != nil {
+				return fmt.Errorf("verifying image signature for service %s: %w", serviceID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	deployRef, err := imageSvc.GetDeployImageRef(digest)
+	if err != nil {
+		return fmt.Errorf("resolving deploy image for service %s: %w", serviceID, err)
+	}
+	run.SetImage(deployRef, digest.String())
+
+	err = run.Phase(ledger.PhaseDeploy, func() error {
+		return serviceProvider.DeployServiceFromImage(ctx, clouds.ImageRef(deployRef))
+	})
+	if err != nil {
+		return err
+	}
+	recordDeploy(ctx, locator, serviceFactory, serviceID, env, deployRef, digest.String())
+
+	_, err = fmt.Fprintf(out, "Deployed %s: %s (digest %s)\n", serviceID, deployRef, digest)
+	return err
+}
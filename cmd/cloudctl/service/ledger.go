@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/factories"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/ledger"
+)
+
+// startRun begins the history entry of the command, with the git user and commit it runs from.
+func startRun(ctx context.Context, locator *factories.SharedServicesLocator, command, serviceID, env string) *ledger.Run {
+	entry := ledger.Entry{Command: command, Service: serviceID, Environment: env}
+
+	if locator.GitRepository != nil {
+		if user, err := locator.GitRepository.CurrentUser(); err == nil {
+			entry.User = user
+		} else {
+			slog.DebugContext(ctx, "no git user for the history entry", "error", err)
+		}
+		if commit, err := locator.GitRepository.CurrentCommit(); err == nil {
+			entry.Commit = commit.Hash.String()
+		} else {
+			slog.DebugContext(ctx, "no git commit for the history entry", "error", err)
+		}
+	}
+
+	return locator.Ledger.Start(entry)
+}
+
+// finishRun appends the history entry. A failure is only logged, the command outcome does not depend on the history.
+func finishRun(ctx context.Context, locator *factories.SharedServicesLocator, run *ledger.Run, err error) {
+	entry, appendErr := run.Finish(err)
+	if appendErr != nil {
+		slog.WarnContext(ctx, "failed to append to the history", "path", locator.Ledger.GetPath(), "error", appendErr)
+		return
+	}
+	slog.DebugContext(ctx, "history entry recorded", "id", entry.ID, "outcome", entry.Outcome)
+}
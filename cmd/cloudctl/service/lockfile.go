@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/factories"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lockfile"
+)
+
+// recordDeploy adds the deploy to the lockfile. A failure is only logged, since the service is deployed already.
+func recordDeploy(ctx context.Context, locator *factories.SharedServicesLocator, serviceFactory *factories.ServiceFactory, serviceID, env, imageRef, digest string) {
+	if locator.Lockfile == nil {
+		return
+	}
+
+	entry := lockfile.Entry{
+		Service:     serviceID,
+		Environment: env,
+		ImageRef:    imageRef,
+		Digest:      digest,
+		DeployedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+	if entry.Digest == "" {
+		_, entry.Digest, _ = strings.Cut(imageRef, "@")
+	}
+
+	provider, err := serviceFactory.GetCloudProviderKey()
+	if err != nil {
+		slog.WarnContext(ctx, "no provider for the lockfile entry", "service", serviceID, "error", err)
+	}
+	entry.Provider = provider
+
+	if locator.GitRepository != nil {
+		if commit, err := locator.GitRepository.CurrentCommit(); err == nil {
+			entry.Commit = commit.Hash.String()
+		} else {
+			slog.DebugContext(ctx, "no git commit for the lockfile entry", "service", serviceID, "error", err)
+		}
+	}
+
+	if err := locator.Lockfile.Record(entry); err != nil {
+		slog.WarnContext(ctx, "failed to record the deploy in the lockfile", "service", serviceID, "env", env, "path", locator.Lockfile.GetPath(), "error", err)
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/factories"
+)
+
+// syncWriter serializes output of services processed in parallel so their reports are not interleaved.
+type syncWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.out.Write(p)
+}
+
+// planService resolves the destination image references and asks the provider what a deploy would change.
+// Nothing is built, pushed or updated.
+func planService(ctx context.Context, locator *factories.SharedServicesLocator, serviceID string, out io.Writer) error {
+	serviceFactory := factories.NewServiceFactory(serviceID, locator)
+
+	providerKey, err := serviceFactory.GetCloudProviderKey()
+	if err != nil {
+		return err
+	}
+
+	serviceProvider, err := serviceFactory.NewCloudProvider()
+	if err != nil {
+		return fmt.Errorf("getting provider for service %s: %w", serviceID, err)
+	}
+
+	imageSvc, err := serviceFactory.NewImageService()
+	if err != nil {
+		return fmt.Errorf("getting image for service %s: %w", serviceID, err)
+	}
+
+	sourceRef, err := imageSvc.GetSourceImageRef()
+	if err != nil {
+		return fmt.Errorf("resolving source image for service %s: %w", serviceID, err)
+	}
+
+	destRefs, err := imageSvc.GetDestinationImageRefs(ctx)
+	if err != nil {
+		return fmt.Errorf("resolving destination images for service %s: %w", serviceID, err)
+	}
+
+	plan, err := serviceProvider.PlanDeployServiceFromImage(ctx, imageSvc.GetRegistry())
+	if err != nil {
+		return fmt.Errorf("planning deploy of service %s: %w", serviceID, err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Service %s (%s)\n", serviceID, providerKey)
+	fmt.Fprintf(&buf, "  build %s\n", sourceRef)
+	for _, destRef := range destRefs {
+		fmt.Fprintf(&buf, "  push  %s\n", destRef)
+	}
+	writePlanChanges(&buf, plan)
+	buf.WriteString("\n")
+
+	_, err = out.Write(buf.Bytes())
+	return err
+}
+
+func writePlanChanges(out io.Writer, plan clouds.DeployPlan) {
+	if len(plan.Changes) == 0 {
+		fmt.Fprintln(out, "  no changes")
+		return
+	}
+
+	for _, change := range plan.Changes {
+		switch {
+		case change.From == "":
+			fmt.Fprintf(out, "  + %s: %s\n", change.Subject, change.To)
+		case change.From == change.To:
+			fmt.Fprintf(out, "  = %s: %s (unchanged)\n", change.Subject, change.To)
+		default:
+			fmt.Fprintf(out, "  ~ %s:\n      - %s\n      + %s\n", change.Subject, change.From, change.To)
+		}
+	}
+}
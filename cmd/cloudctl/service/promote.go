@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/factories"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/ledger"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lockfile"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/spf13/cobra"
+)
+
+func newServicePromoteCmd(locator *factories.SharedServicesLocator) *cobra.Command {
+	var serviceID, fromEnv, toEnv string
+
+	promoteCmd := &cobra.Command{
+		Use:   "promote",
+		Short: "Deploy the image a service runs in one environment to another one, without rebuilding it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if serviceID == "" {
+				return fmt.Errorf("service is required")
+			}
+			if fromEnv == "" || toEnv == "" {
+				return fmt.Errorf("source and target environments are required")
+			}
+			if fromEnv == toEnv {
+				return fmt.Errorf("%w - source and target environments are the same: %s", lib.BadUserInputError, fromEnv)
+			}
+
+			fromConfig, err := locator.Config.WithEnvironment(fromEnv)
+			if err != nil {
+				return fmt.Errorf("loading %s environment config: %w", fromEnv, err)
+			}
+			toConfig, err := locator.Config.WithEnvironment(toEnv)
+			if err != nil {
+				return fmt.Errorf("loading %s environment config: %w", toEnv, err)
+			}
+
+			return promoteService(cmd.Context(), locator.WithConfig(fromConfig), locator.WithConfig(toConfig), serviceID, fromEnv, toEnv, cmd.OutOrStdout())
+		},
+	}
+
+	promoteCmd.PersistentFlags().StringVar(&serviceID, "name", "", "Service to promote")
+	promoteCmd.PersistentFlags().StringVar(&fromEnv, "from", "", "Environment whose running image is promoted")
+	promoteCmd.PersistentFlags().StringVar(&toEnv, "to", "", "Environment the image is deployed to")
+
+	return promoteCmd
+}
+
+func promoteService(ctx context.Context, fromLocator, toLocator *factories.SharedServicesLocator, serviceID, fromEnv, toEnv string, out io.Writer) (err error) {
+	run := startRun(ctx, toLocator, "promote", serviceID, toEnv)
+	defer func() { finishRun(ctx, toLocator, run, err) }()
+
+	fromFactory := factories.NewServiceFactory(serviceID, fromLocator)
+	toFactory := factories.NewServiceFactory(serviceID, toLocator)
+
+	sourceRef, err := getPromotedImageRef(ctx, fromLocator, fromFactory, serviceID, fromEnv)
+	if err != nil {
+		return err
+	}
+
+	fromImageSvc, err := fromFactory.NewImageService()
+	if err != nil {
+		return fmt.Errorf("getting %s image for service %s: %w", fromEnv, serviceID, err)
+	}
+
+	toProvider, err := toFactory.NewCloudProvider()
+	if err != nil {
+		return fmt.Errorf("getting %s provider for service %s: %w", toEnv, serviceID, err)
+	}
+	toImageSvc, err := toFactory.NewImageService()
+	if err != nil {
+		return fmt.Errorf("getting %s image for service %s: %w", toEnv, serviceID, err)
+	}
+
+	platform, err := toProvider.GetRuntimePlatform(ctx)
+	if err != nil {
+		return fmt.Errorf("getting runtime platform for service %s: %w", serviceID, err)
+	}
+
+	var digest v1.Hash
+	err = run.Phase(ledger.PhasePush, func() error {
+		promotedDigest, err := toImageSvc.PromoteImage(ctx, fromImageSvc, sourceRef, container_image.PushOptions{Platform: platform})
+		if err != nil {
+			return fmt.Errorf("promoting image for service %s: %w", serviceID, err)
+		}
+		digest = promotedDigest
+
+		promotedImage, err := toImageSvc.GetImageDigestRef(digest)
+		if err != nil {
+			return fmt.Errorf("resolving promoted image for service %s: %w", serviceID, err)
+		}
+		if toImageSvc.IsSigningEnabled() {
+			if err := toImageSvc.SignImage(ctx, promotedImage); err != nil {
+				return fmt.Errorf("signing image for service %s: %w", serviceID, err)
+			}
+		}
+		if toImageSvc.IsSignatureRequired() {
+			if _, err := toImageSvc.VerifyImageSignature(ctx, promotedImage, ""); err != nil {
+				return fmt.Errorf("verifying image signature for service %s: %w", serviceID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	deployRef, err := toImageSvc.GetDeployImageRef(digest)
+	if err != nil {
+		return fmt.Errorf("resolving deploy image for service %s: %w", serviceID, err)
+	}
+
+	run.SetImage(deployRef, digest.String())
+
+	err = run.Phase(ledger.PhaseDeploy, func() error {
+		return toProvider.DeployServiceFromImage(ctx, clouds.ImageRef(deployRef))
+	})
+	if err != nil {
+		return err
+	}
+	recordDeploy(ctx, toLocator, toFactory, serviceID, toEnv, deployRef, digest.String())
+
+	_, err = fmt.Fprintf(out, "Promoted %s from %s to %s: %s (digest %s)\n", serviceID, fromEnv, toEnv, deployRef, digest)
+	return err
+}
+
+// getPromotedImageRef returns the image the service runs in the environment. The lockfile records exactly what was
+// deployed, the provider is asked when it has no entry for the service.
+func getPromotedImageRef(ctx context.Context, locator *factories.SharedServicesLocator, serviceFactory *factories.ServiceFactory, serviceID, env string) (string, error) {
+	serviceProvider, err := serviceFactory.NewCloudProvider()
+	if err != nil {
+		return "", fmt.Errorf("getting %s provider for service %s: %w", env, serviceID, err)
+	}
+	status, statusErr := serviceProvider.GetServiceStatus(ctx)
+	if closer, ok := serviceProvider.(io.Closer); ok {
+		closer.Close()
+	}
+
+	if locator.Lockfile != nil {
+		entry, err := locator.Lockfile.Latest(serviceID, env)
+		switch {
+		case err == nil:
+			if statusErr == nil && status.ImageRef != "" && !entry.Matches(status.ImageRef) {
+				slog.WarnContext(ctx, "the running image differs from the lockfile, promoting the locked one",
+					"service", serviceID,
+					"env", env,
+					"running", status.ImageRef,
+					"locked", entry.PinnedRef())
+			}
+			return entry.PinnedRef(), nil
+		case !errors.Is(err, lockfile.EntryNotFoundError):
+			return "", fmt.Errorf("reading lockfile: %w", err)
+		}
+	}
+
+	if statusErr != nil {
+		return "", fmt.Errorf("getting %s status of service %s: %w", env, serviceID, statusErr)
+	}
+	if status.ImageRef == "" {
+		return "", fmt.Errorf("service %s has no running image in %s", serviceID, env)
+	}
+	return status.ImageRef, nil
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/factories"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/ledger"
+	"github.com/spf13/cobra"
+)
+
+func newServiceRollbackCmd(locator *factories.SharedServicesLocator) *cobra.Command {
+	var serviceID, env string
+
+	rollbackCmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Redeploy the image the service was running before the current one",
+		Long: `Redeploy the image the service was running before the current one, without rebuilding it.
+
+The previous image is taken from the deploy history of the provider. Providers that keep no image history,
+such as AWS App Runner, go back to the previous deploy recorded in the lockfile, so their rollbacks need
+a lockfile recording an earlier deploy of another image of the service in the environment.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if serviceID == "" {
+				return fmt.Errorf("service is required")
+			}
+			if env == "" {
+				return fmt.Errorf("environment is required")
+			}
+
+			envSpecificConfig, err := locator.Config.WithEnvironment(env)
+			if err != nil {
+				return fmt.Errorf("loading environment specific config: %w", err)
+			}
+
+			return rollbackService(cmd.Context(), locator.WithConfig(envSpecificConfig), serviceID, env)
+		},
+	}
+
+	rollbackCmd.PersistentFlags().StringVar(&serviceID, "name", "", "Service to roll back")
+	rollbackCmd.PersistentFlags().StringVar(&env, "env", "", "Target environment")
+
+	return rollbackCmd
+}
+
+func rollbackService(ctx context.Context, locator *factories.SharedServicesLocator, serviceID, env string) (err error) {
+	run := startRun(ctx, locator, "rollback", serviceID, env)
+	defer func() { finishRun(ctx, locator, run, err) }()
+
+	serviceFactory := factories.NewServiceFactory(serviceID, locator)
+
+	serviceProvider, err := serviceFactory.NewCloudProvider()
+	if err != nil {
+		return fmt.Errorf("getting provider for service %s: %w", serviceID, err)
+	}
+
+	imageRef, err := getPreviousImageRef(ctx, locator, serviceProvider, serviceID, env)
+	if err != nil {
+		return fmt.Errorf("rolling back service %s: %w", serviceID, err)
+	}
+
+	// The previous image is already in the registry, so the build and push steps are skipped entirely
+	err = run.Phase(ledger.PhaseDeploy, func() error {
+		if err := verifyPreviousImage(ctx, serviceFactory, serviceID, imageRef); err != nil {
+			return err
+		}
+		return serviceProvider.DeployServiceFromImage(ctx, clouds.ImageRef(imageRef))
+	})
+	if err != nil {
+		return fmt.Errorf("rolling back service %s: %w", serviceID, err)
+	}
+
+	_, digest, _ := strings.Cut(imageRef, "@")
+	run.SetImage(imageRef, digest)
+	recordDeploy(ctx, locator, serviceFactory, serviceID, env, imageRef, digest)
+
+	slog.InfoContext(ctx, "service rolled back", "service", serviceID, "env", env, "image", imageRef)
+
+	return nil
+}
+
+// getPreviousImageRef returns the image the service ran before the current one. Providers without a deploy history
+// can still go back to the image recorded in the lockfile.
+func getPreviousImageRef(ctx context.Context, locator *factories.SharedServicesLocator, serviceProvider clouds.CloudProvider, serviceID, env string) (string, error) {
+	imageRef, err := serviceProvider.GetPreviousImageRef(ctx)
+	if err == nil {
+		return imageRef, nil
+	}
+	if !errors.Is(err, clouds.PreviousImageNotFoundError) || locator.Lockfile == nil {
+		return "", fmt.Errorf("getting previous image reference: %w", err)
+	}
+
+	entry, lockErr := locator.Lockfile.Previous(serviceID, env)
+	if lockErr != nil {
+		slog.DebugContext(ctx, "no previous image in the lockfile", "service", serviceID, "error", lockErr)
+		return "", fmt.Errorf("getting previous image reference: %w", err)
+	}
+	return entry.PinnedRef(), nil
+}
+
+// verifyPreviousImage checks the signature of the image when the service requires signed images, so a rollback can
+// not deploy an image a regular deploy would refuse.
+func verifyPreviousImage(ctx context.Context, serviceFactory *factories.ServiceFactory, serviceID, imageRef string) error {
+	imageSvc, err := serviceFactory.NewImageService()
+	if err != nil {
+		return fmt.Errorf("getting image for service %s: %w", serviceID, err)
+	}
+	defer closeImageService(ctx, imageSvc, serviceID)
+
+	if !imageSvc.IsSignatureRequired() {
+		return nil
+	}
+
+	image, err := imageSvc.ResolveImageDigestRef(ctx, imageRef)
+	if err != nil {
+		return err
+	}
+	if _, err := imageSvc.VerifyImageSignature(ctx, image, ""); err != nil {
+		return fmt.Errorf("verifying image signature for service %s: %w", serviceID, err)
+	}
+	return nil
+}
@@ -13,6 +13,9 @@ func NewServiceCmd(locator *factories.SharedServicesLocator) *cobra.Command {
 
 	serviceCmd.AddCommand(newServiceDeployCmd(locator))
 	serviceCmd.AddCommand(newServiceBuildCmd(locator))
+	serviceCmd.AddCommand(newServiceStatusCmd(locator))
+	serviceCmd.AddCommand(newServiceRollbackCmd(locator))
+	serviceCmd.AddCommand(newServicePromoteCmd(locator))
 
 	return serviceCmd
 }
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/factories"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lockfile"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputFormatTable = "table"
+	outputFormatJSON  = "json"
+)
+
+type serviceStatusRow struct {
+	Service  string `json:"service"`
+	Provider string `json:"provider"`
+	clouds.ServiceStatus
+	// Revision is the git commit of the running image, read from its org.opencontainers.image.revision label
+	Revision string `json:"revision,omitempty"`
+	// Locked is the image the lockfile recorded for the last deploy, Drift is set when the service runs another one
+	Locked string `json:"locked,omitempty"`
+	Drift  bool   `json:"drift,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func newServiceStatusCmd(locator *factories.SharedServicesLocator) *cobra.Command {
+	var env, output string
+	var showRevision bool
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the live state of every configured service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if env == "" {
+				return fmt.Errorf("environment is required")
+			}
+			if output != outputFormatTable && output != outputFormatJSON {
+				return fmt.Errorf("%w - unsupported output format '%s', supported are %s, %s", lib.BadUserInputError, output, outputFormatTable, outputFormatJSON)
+			}
+
+			envSpecificConfig, err := locator.Config.WithEnvironment(env)
+			if err != nil {
+				return fmt.Errorf("loading environment specific config: %w", err)
+			}
+			envLocator := locator.WithConfig(envSpecificConfig)
+
+			ctx := cmd.Context()
+
+			serviceIDs := slices.Sorted(maps.Keys(envSpecificConfig.Services))
+			rows := make([]serviceStatusRow, 0, len(serviceIDs))
+			for _, serviceID := range serviceIDs {
+				row := serviceStatusRow{Service: serviceID}
+
+				serviceFactory := factories.NewServiceFactory(serviceID, envLocator)
+				row.Provider, err = serviceFactory.GetCloudProviderKey()
+				if err != nil {
+					row.Error = err.Error()
+					rows = append(rows, row)
+					continue
+				}
+
+				serviceProvider, err := serviceFactory.NewCloudProvider()
+				if err != nil {
+					row.Error = fmt.Sprintf("getting provider: %s", err)
+					rows = append(rows, row)
+					continue
+				}
+
+				row.ServiceStatus, err = serviceProvider.GetServiceStatus(ctx)
+				if err != nil {
+					slog.DebugContext(ctx, "failed to get service status", "service", serviceID, "error", err)
+					row.Error = fmt.Sprintf("getting status: %s", err)
+				}
+				if closer, ok := serviceProvider.(io.Closer); ok {
+					closer.Close()
+				}
+
+				if locator.Lockfile != nil {
+					entry, err := locator.Lockfile.Latest(serviceID, env)
+					switch {
+					case err == nil:
+						row.Locked = entry.PinnedRef()
+						row.Drift = row.ImageRef != "" && !entry.Matches(row.ImageRef)
+					case !errors.Is(err, lockfile.EntryNotFoundError):
+						slog.WarnContext(ctx, "failed to read the lockfile", "service", serviceID, "error", err)
+					}
+				}
+
+				if showRevision && row.ImageRef != "" {
+					row.Revision, err = getImageRevision(ctx, serviceFactory, row.ImageRef)
+					if err != nil {
+						slog.WarnContext(ctx, "failed to read image revision", "service", serviceID, "image", row.ImageRef, "error", err)
+					}
+				}
+
+				rows = append(rows, row)
+			}
+
+			if output == outputFormatJSON {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(rows)
+			}
+
+			return writeServiceStatusTable(cmd.OutOrStdout(), rows, showRevision)
+		},
+	}
+
+	statusCmd.PersistentFlags().StringVar(&env, "env", "", "Target environment")
+	statusCmd.PersistentFlags().StringVarP(&output, "output", "o", outputFormatTable, "Output format (table, json)")
+	statusCmd.PersistentFlags().BoolVar(&showRevision, "revision", false, "Read the git revision of the running images from their labels in the registry")
+
+	return statusCmd
+}
+
+func writeServiceStatusTable(out io.Writer, rows []serviceStatusRow, showRevision bool) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	header := "SERVICE\tPROVIDER\tIMAGE\tSTATUS\tURL\tLAST UPDATE\tLOCK"
+	if showRevision {
+		header += "\tREVISION"
+	}
+	fmt.Fprintln(w, header)
+	for _, row := range rows {
+		status := row.Status
+		if row.Error != "" {
+			status = fmt.Sprintf("ERROR: %s", row.Error)
+		}
+		lastUpdate := "-"
+		if !row.LastDeployAt.IsZero() {
+			lastUpdate = row.LastDeployAt.Local().Format(time.RFC3339)
+		}
+
+		lock := "-"
+		switch {
+		case row.Drift:
+			lock = fmt.Sprintf("DRIFT (locked %s)", row.Locked)
+		case row.Locked != "":
+			lock = "ok"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s",
+			row.Service,
+			lib.ValueOrDash(row.Provider),
+			lib.ValueOrDash(row.ImageRef),
+			lib.ValueOrDash(status),
+			lib.ValueOrDash(row.URL),
+			lastUpdate,
+			lock)
+		if showRevision {
+			fmt.Fprintf(w, "\t%s", lib.ValueOrDash(row.Revision))
+		}
+		fmt.Fprintln(w)
+	}
+
+	return w.Flush()
+}
+
+// getImageRevision reads the revision label of the image the service runs.
+func getImageRevision(ctx context.Context, serviceFactory *factories.ServiceFactory, imageRef string) (string, error) {
+	imageSvc, err := serviceFactory.NewImageService()
+	if err != nil {
+		return "", err
+	}
+
+	labels, err := imageSvc.GetImageLabels(ctx, imageRef)
+	if err != nil {
+		return "", err
+	}
+	return labels[container_image.LabelRevision], nil
+}
@@ -0,0 +1,91 @@
+package affected
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/build/pipeline"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/factories"
+)
+
+const (
+	ReasonChanges = "changes in the app or its workspace dependencies"
+	ReasonNoApp   = "no pipeline app configured, every change counts"
+)
+
+// Result tells whether the changes affect the service and which of them do. Changes lists workspace package names for
+// package changes and repository relative paths for root and extra files.
+type Result struct {
+	Service  string   `json:"service"`
+	App      string   `json:"app,omitempty"`
+	Affected bool     `json:"affected"`
+	Reason   string   `json:"reason,omitempty"`
+	Changes  []string `json:"changes,omitempty"`
+}
+
+// PipelineLookup returns the build pipeline of the service.
+type PipelineLookup func(serviceID string) (*pipeline.Service, error)
+
+// Find checks the changed files, given as absolute paths, against the pipeline of every service.
+// A service whose pipeline has no app can not be mapped to the workspace, so it is affected by any change.
+func Find(serviceIDs []string, changedFiles []string, lookup PipelineLookup) ([]Result, error) {
+	results := make([]Result, 0, len(serviceIDs))
+	for _, serviceID := range serviceIDs {
+		pipelineSvc, err := lookup(serviceID)
+		if err != nil {
+			return nil, fmt.Errorf("getting pipeline of service %s: %w", serviceID, err)
+		}
+
+		result := Result{Service: serviceID, App: pipelineSvc.GetApp()}
+		if result.App == "" {
+			result.Affected = len(changedFiles) > 0
+			if result.Affected {
+				result.Reason = ReasonNoApp
+			}
+			results = append(results, result)
+			continue
+		}
+
+		changes, err := pipelineSvc.GetAffectingChanges(changedFiles)
+		if err != nil {
+			return nil, fmt.Errorf("mapping changes of service %s: %w", serviceID, err)
+		}
+		result.Changes = changes
+		result.Affected = len(changes) > 0
+		if result.Affected {
+			result.Reason = ReasonChanges
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// FindSince checks every configured service against the files changed in the repository since the git revision.
+func FindSince(locator *factories.SharedServicesLocator, since string) ([]Result, error) {
+	if locator.GitRepository == nil {
+		return nil, fmt.Errorf("no git repository to compare with %s", since)
+	}
+
+	changedFiles, err := locator.GitRepository.ChangedFiles(since)
+	if err != nil {
+		return nil, fmt.Errorf("getting files changed since %s: %w", since, err)
+	}
+
+	serviceIDs := slices.Sorted(maps.Keys(locator.Config.Services))
+	return Find(serviceIDs, changedFiles, func(serviceID string) (*pipeline.Service, error) {
+		return factories.NewServiceFactory(serviceID, locator).NewPipelineService()
+	})
+}
+
+// AffectedServices returns the IDs of the affected services, in the order of the results.
+func AffectedServices(results []Result) []string {
+	var serviceIDs []string
+	for _, result := range results {
+		if result.Affected {
+			serviceIDs = append(serviceIDs, result.Service)
+		}
+	}
+	return serviceIDs
+}
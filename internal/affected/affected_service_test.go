@@ -0,0 +1,54 @@
+package affected
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/build/pipeline"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFind(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	for path, content := range map[string]string{
+		"pnpm-workspace.yaml":          "packages:\n  - \"apps/*\"\n  - \"packages/*\"\n",
+		"packages/shared/package.json": `{ "name": "shared" }`,
+		"apps/api/package.json":        `{"name": "api", "dependencies": {"shared": "workspace:*"}}`,
+		"apps/web/package.json":        `{ "name": "web" }`,
+	} {
+		fullPath := filepath.Join(root, filepath.FromSlash(path))
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0o755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0o644))
+	}
+
+	apps := map[string]string{"api-service": "api", "web-service": "web", "worker": ""}
+	lookup := func(serviceID string) (*pipeline.Service, error) {
+		return pipeline.NewService(pipeline.Config{App: apps[serviceID]}, root, pipeline.NewPnpmMonorepo(root), nil), nil
+	}
+	serviceIDs := []string{"api-service", "web-service", "worker"}
+
+	t.Run("maps changes to the services depending on them", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		results, err := Find(serviceIDs, []string{filepath.Join(root, "packages", "shared", "index.ts")}, lookup)
+		r.NoError(err)
+		r.Len(results, 3)
+		r.Equal(Result{Service: "api-service", App: "api", Affected: true, Reason: ReasonChanges, Changes: []string{"shared"}}, results[0])
+		r.False(results[1].Affected)
+		r.Equal(ReasonNoApp, results[2].Reason)
+		r.Equal([]string{"api-service", "worker"}, AffectedServices(results))
+	})
+
+	t.Run("nothing is affected without changes", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		results, err := Find(serviceIDs, nil, lookup)
+		r.NoError(err)
+		r.Empty(AffectedServices(results))
+	})
+}
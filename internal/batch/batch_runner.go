@@ -0,0 +1,201 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+)
+
+var (
+	SkippedError = errors.New("skipped")
+)
+
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusSkipped   Status = "skipped"
+)
+
+type Options struct {
+	Concurrency int
+	FailFast    bool
+}
+
+type Result struct {
+	ID       string
+	Status   Status
+	Err      error
+	Duration time.Duration
+}
+
+type Job func(ctx context.Context, id string) error
+
+// Run executes the job for every id with at most opts.Concurrency jobs running at a time.
+// A job starts only after the jobs of all its dependencies succeeded; dependencies outside ids are ignored.
+// When a job fails its dependents are skipped, and with opts.FailFast no new jobs are started at all while the running
+// ones finish.
+// Results are returned in the order of ids.
+func Run(ctx context.Context, ids []string, dependsOn map[string][]string, opts Options, job Job) ([]Result, error) {
+	l := slog.With("context", "batch_runner")
+
+	pendingDeps, dependents, err := buildGraph(ids, dependsOn)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	// stopped only gates starting new jobs, the running ones keep ctx and are never interrupted by a failure
+	stopped := false
+
+	results := make(map[string]Result, len(ids))
+	ready := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if pendingDeps[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+
+	var skip func(id string, reason error)
+	skip = func(id string, reason error) {
+		for _, dependent := range dependents[id] {
+			if _, ok := results[dependent]; ok {
+				continue
+			}
+			results[dependent] = Result{ID: dependent, Status: StatusSkipped, Err: reason}
+			skip(dependent, reason)
+		}
+	}
+
+	done := make(chan Result)
+	running := 0
+	for len(results) < len(ids) {
+		for running < concurrency && len(ready) > 0 && !stopped && ctx.Err() == nil {
+			id := ready[0]
+			ready = ready[1:]
+			if _, ok := results[id]; ok {
+				continue
+			}
+
+			running++
+			l.Debug("starting job", "id", id)
+			go func() {
+				startTime := time.Now()
+				err := job(ctx, id)
+				result := Result{ID: id, Status: StatusSucceeded, Err: err, Duration: time.Since(startTime)}
+				if err != nil {
+					result.Status = StatusFailed
+				}
+				done <- result
+			}()
+		}
+
+		if running == 0 {
+			reason := fmt.Errorf("%w - run was cancelled", SkippedError)
+			if ctx.Err() == nil {
+				reason = fmt.Errorf("%w - fail fast after an earlier failure", SkippedError)
+			}
+			for _, id := range ids {
+				if _, ok := results[id]; !ok {
+					results[id] = Result{ID: id, Status: StatusSkipped, Err: reason}
+				}
+			}
+			break
+		}
+
+		result := <-done
+		running--
+		results[result.ID] = result
+		l.Debug("job finished", "id", result.ID, "status", result.Status, "duration", result.Duration)
+
+		if result.Err != nil {
+			if opts.FailFast {
+				stopped = true
+			}
+			skip(result.ID, fmt.Errorf("%w - dependency %s failed", SkippedError, result.ID))
+			continue
+		}
+
+		for _, dependent := range dependents[result.ID] {
+			pendingDeps[dependent]--
+			if pendingDeps[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	ordered := make([]Result, 0, len(ids))
+	for _, id := range ids {
+		ordered = append(ordered, results[id])
+	}
+
+	return ordered, nil
+}
+
+// buildGraph counts the dependencies of every id within ids and rejects dependency cycles.
+func buildGraph(ids []string, dependsOn map[string][]string) (map[string]int, map[string][]string, error) {
+	pendingDeps := make(map[string]int, len(ids))
+	dependents := make(map[string][]string, len(ids))
+	for _, id := range ids {
+		if _, ok := pendingDeps[id]; ok {
+			return nil, nil, fmt.Errorf("%w - '%s' is listed more than once", lib.BadUserInputError, id)
+		}
+		pendingDeps[id] = 0
+	}
+
+	for _, id := range ids {
+		for _, dep := range dependsOn[id] {
+			if _, ok := pendingDeps[dep]; !ok || slices.Contains(dependents[dep], id) {
+				continue
+			}
+			if dep == id {
+				return nil, nil, fmt.Errorf("%w - '%s' depends on itself", lib.BadUserInputError, id)
+			}
+			pendingDeps[id]++
+			dependents[dep] = append(dependents[dep], id)
+		}
+	}
+
+	// Kahn's algorithm - if not every id can be sorted, the remaining ones form a cycle
+	remaining := make(map[string]int, len(pendingDeps))
+	queue := make([]string, 0, len(ids))
+	for _, id := range ids {
+		remaining[id] = pendingDeps[id]
+		if remaining[id] == 0 {
+			queue = append(queue, id)
+		}
+	}
+	sorted := 0
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		sorted++
+		for _, dependent := range dependents[id] {
+			remaining[dependent]--
+			if remaining[dependent] == 0 {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	if sorted != len(ids) {
+		cycle := make([]string, 0, len(ids)-sorted)
+		for _, id := range ids {
+			if remaining[id] > 0 {
+				cycle = append(cycle, id)
+			}
+		}
+		return nil, nil, fmt.Errorf("%w - circular dependency between %v", lib.BadUserInputError, cycle)
+	}
+
+	return pendingDeps, dependents, nil
+}
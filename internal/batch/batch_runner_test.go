@@ -0,0 +1,183 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/stretchr/testify/require"
+)
+
+func resultStatuses(results []Result) map[string]Status {
+	statuses := make(map[string]Status, len(results))
+	for _, result := range results {
+		statuses[result.ID] = result.Status
+	}
+	return statuses
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	t.Run("runs every job and keeps the order of ids", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		results, err := Run(context.Background(), []string{"c", "a", "b"}, nil, Options{Concurrency: 2}, func(ctx context.Context, id string) error {
+			return nil
+		})
+		r.NoError(err)
+		r.Len(results, 3)
+		r.Equal("c", results[0].ID)
+		r.Equal("a", results[1].ID)
+		r.Equal("b", results[2].ID)
+		r.Equal(map[string]Status{"a": StatusSucceeded, "b": StatusSucceeded, "c": StatusSucceeded}, resultStatuses(results))
+	})
+
+	t.Run("never exceeds the concurrency limit", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		var running, maxRunning atomic.Int32
+		_, err := Run(context.Background(), []string{"a", "b", "c", "d", "e", "f"}, nil, Options{Concurrency: 2}, func(ctx context.Context, id string) error {
+			current := running.Add(1)
+			defer running.Add(-1)
+			for {
+				observed := maxRunning.Load()
+				if current <= observed || maxRunning.CompareAndSwap(observed, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		})
+		r.NoError(err)
+		r.LessOrEqual(maxRunning.Load(), int32(2))
+	})
+
+	t.Run("starts dependents after their dependencies", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		var mu sync.Mutex
+		order := make([]string, 0, 3)
+		dependsOn := map[string][]string{
+			"api":    {"db"},
+			"worker": {"api", "db"},
+		}
+		results, err := Run(context.Background(), []string{"worker", "api", "db"}, dependsOn, Options{Concurrency: 3}, func(ctx context.Context, id string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, id)
+			return nil
+		})
+		r.NoError(err)
+		r.Equal([]string{"db", "api", "worker"}, order)
+		r.Equal(map[string]Status{"api": StatusSucceeded, "db": StatusSucceeded, "worker": StatusSucceeded}, resultStatuses(results))
+	})
+
+	t.Run("ignores dependencies that are not selected", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		results, err := Run(context.Background(), []string{"api"}, map[string][]string{"api": {"db"}}, Options{}, func(ctx context.Context, id string) error {
+			return nil
+		})
+		r.NoError(err)
+		r.Equal(map[string]Status{"api": StatusSucceeded}, resultStatuses(results))
+	})
+
+	t.Run("skips dependents of a failed job and continues with the others", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		dependsOn := map[string][]string{
+			"api":    {"db"},
+			"worker": {"api"},
+		}
+		results, err := Run(context.Background(), []string{"db", "api", "worker", "web"}, dependsOn, Options{Concurrency: 1}, func(ctx context.Context, id string) error {
+			if id == "db" {
+				return errors.New("boom")
+			}
+			return nil
+		})
+		r.NoError(err)
+		r.Equal(map[string]Status{
+			"db":     StatusFailed,
+			"api":    StatusSkipped,
+			"worker": StatusSkipped,
+			"web":    StatusSucceeded,
+		}, resultStatuses(results))
+		r.ErrorIs(results[1].Err, SkippedError)
+	})
+
+	t.Run("stops starting jobs on fail fast", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		results, err := Run(context.Background(), []string{"a", "b", "c"}, nil, Options{Concurrency: 1, FailFast: true}, func(ctx context.Context, id string) error {
+			if id == "a" {
+				return errors.New("boom")
+			}
+			return nil
+		})
+		r.NoError(err)
+		r.Equal(map[string]Status{"a": StatusFailed, "b": StatusSkipped, "c": StatusSkipped}, resultStatuses(results))
+	})
+
+	t.Run("lets running jobs finish on fail fast", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		bStarted := make(chan struct{})
+		results, err := Run(context.Background(), []string{"a", "b", "c"}, nil, Options{Concurrency: 2, FailFast: true}, func(ctx context.Context, id string) error {
+			switch id {
+			case "a":
+				<-bStarted
+				return errors.New("boom")
+			case "b":
+				close(bStarted)
+				// Still running well after the failure of a was handled
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(100 * time.Millisecond):
+					return nil
+				}
+			}
+			return nil
+		})
+		r.NoError(err)
+		r.Equal(map[string]Status{"a": StatusFailed, "b": StatusSucceeded, "c": StatusSkipped}, resultStatuses(results))
+	})
+
+	t.Run("rejects circular dependencies", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		dependsOn := map[string][]string{
+			"a": {"b"},
+			"b": {"c"},
+			"c": {"a"},
+		}
+		_, err := Run(context.Background(), []string{"a", "b", "c", "d"}, dependsOn, Options{}, func(ctx context.Context, id string) error {
+			return nil
+		})
+		r.ErrorIs(err, lib.BadUserInputError)
+		r.ErrorContains(err, "circular dependency")
+	})
+
+	t.Run("rejects duplicated ids", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		_, err := Run(context.Background(), []string{"a", "a"}, nil, Options{}, func(ctx context.Context, id string) error {
+			return nil
+		})
+		r.ErrorIs(err, lib.BadUserInputError)
+	})
+}
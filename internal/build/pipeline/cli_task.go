@@ -65,7 +65,7 @@ func (t *CliTask) GetCmd() ([][]string, error) {
 	}
 
 	// Likely not the ideal approach, might worth to investigate a better way to handle this in dagger
-	mainCmd := append([]string{"sh", "-lc", strings.Join(append([]string{"cd", workdirStr, "&&"}, cmdSlice...), " ")})
+	mainCmd := []string{"sh", "-lc", strings.Join(append([]string{"cd", workdirStr, "&&"}, cmdSlice...), " ")}
 
 	return [][]string{
 		mainCmd,
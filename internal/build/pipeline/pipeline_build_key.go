@@ -0,0 +1,233 @@
+package pipeline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	ignore "github.com/sabhiram/go-gitignore"
+)
+
+// buildKeyVersion is hashed into every build key, changing it invalidates the keys of images built by an older pipeline.
+const buildKeyVersion = "cloudctl/pipeline/v1"
+
+// buildExcludePatterns are never copied from the repository into the builder, on top of the configured exclude_files.
+var buildExcludePatterns = []string{
+	"**/node_modules",
+	"**/dist",
+	"**/build",
+	"**/out",
+	"**/.next",
+	"**/.cache",
+	"**/.turbo",
+}
+
+// GetBuildKey returns a content-addressed key of the pipeline build, the hex SHA-256 of the pipeline config and of the
+// files the runtime image includes: the app, its workspace dependencies, the workspace root files and the extra files.
+// Placeholders of the config are resolved first, so values baked into the image like the git commit change the key.
+// Files excluded from the build or ignored by git do not change the key.
+func (s *Service) GetBuildKey() (string, error) {
+	if s.config.App == "" {
+		return "", fmt.Errorf("%w - no app specified in pipeline config", lib.BadUserInputError)
+	}
+
+	appPackage, workspacePackages, err := s.getAppPackage()
+	if err != nil {
+		return "", err
+	}
+	dependencies := s.monorepo.GetPackageDependencies(appPackage, workspacePackages, PackageDependencyTypeDependencies, PackageDependencyTypeDevDependencies)
+
+	files, err := s.listBuildFiles(s.getIncludePaths(appPackage, dependencies))
+	if err != nil {
+		return "", err
+	}
+
+	config, err := s.getResolvedConfig(getPlaceholderResolvers(appPackage))
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n", buildKeyVersion, config)
+	for _, file := range files {
+		if err := hashBuildFile(hash, s.repoRoot, file); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// getResolvedConfig encodes the pipeline config with the placeholders of every string resolved, the way the steps and
+// cmd are resolved when the pipeline runs.
+func (s *Service) getResolvedConfig(resolvers PlaceholderResolvers) ([]byte, error) {
+	encoded, err := json.Marshal(s.config)
+	if err != nil {
+		return nil, fmt.Errorf("encoding pipeline config: %w", err)
+	}
+	if s.placeholders == nil {
+		return encoded, nil
+	}
+
+	var config any
+	if err := json.Unmarshal(encoded, &config); err != nil {
+		return nil, fmt.Errorf("decoding pipeline config: %w", err)
+	}
+	config, err = s.resolveValuePlaceholders(config, resolvers)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("encoding resolved pipeline config: %w", err)
+	}
+	return resolved, nil
+}
+
+func (s *Service) resolveValuePlaceholders(value any, resolvers PlaceholderResolvers) (any, error) {
+	switch typed := value.(type) {
+	case string:
+		if !strings.Contains(typed, "{{") {
+			return typed, nil
+		}
+		resolved, err := s.placeholders.ResolvePlaceholders(typed, resolvers)
+		if err != nil {
+			return nil, fmt.Errorf("resolving placeholders in '%s' for the build key: %w", typed, err)
+		}
+		return resolved, nil
+	case []any:
+		for i, item := range typed {
+			resolved, err := s.resolveValuePlaceholders(item, resolvers)
+			if err != nil {
+				return nil, err
+			}
+			typed[i] = resolved
+		}
+		return typed, nil
+	case map[string]any:
+		for key, item := range typed {
+			resolved, err := s.resolveValuePlaceholders(item, resolvers)
+			if err != nil {
+				return nil, err
+			}
+			typed[key] = resolved
+		}
+		return typed, nil
+	default:
+		return value, nil
+	}
+}
+
+// listBuildFiles returns the sorted repository relative paths of the files under the include paths, or matching them
+// when they are patterns, leaving out the excluded and git ignored ones.
+func (s *Service) listBuildFiles(includePaths []string) ([]string, error) {
+	repoRoot := filepath.Clean(s.repoRoot)
+
+	gitIgnore, err := ignore.CompileIgnoreFile(filepath.Join(repoRoot, ".gitignore"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("compile gitignore: %w", err)
+	}
+	exclude := append(slices.Clone(buildExcludePatterns), s.config.ExcludeFiles...)
+
+	var files []string
+	walkErr := filepath.WalkDir(repoRoot, func(absPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(repoRoot, absPath)
+		if err != nil {
+			return fmt.Errorf("get relative path: %w", err)
+		}
+		relPath = filepath.ToSlash(relPath)
+		if relPath == "." {
+			return nil
+		}
+
+		skip := func() error {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if relPath == ".git" {
+			return skip()
+		}
+		if gitIgnore != nil && (gitIgnore.MatchesPath(relPath) || (d.IsDir() && gitIgnore.MatchesPath(relPath+"/"))) {
+			return skip()
+		}
+		excluded, err := lib.PathMatchesOneOfPatterns(relPath, exclude)
+		if err != nil {
+			return fmt.Errorf("matching exclude patterns: %w", err)
+		}
+		if excluded {
+			return skip()
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+		included, err := isIncludedPath(relPath, includePaths)
+		if err != nil {
+			return err
+		}
+		if included {
+			files = append(files, relPath)
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return nil, fmt.Errorf("listing build files: %w", walkErr)
+	}
+
+	return files, nil
+}
+
+// hashBuildFile adds the path, the executable bit and the content of the file to the hash. Symbolic links are hashed by
+// their target, other special files are skipped. Other permission bits depend on the umask of the checkout and are left out.
+func hashBuildFile(hash io.Writer, repoRoot, relPath string) error {
+	absPath := filepath.Join(repoRoot, filepath.FromSlash(relPath))
+	info, err := os.Lstat(absPath)
+	if err != nil {
+		return fmt.Errorf("stat build file %s: %w", relPath, err)
+	}
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(absPath)
+		if err != nil {
+			return fmt.Errorf("read build file link %s: %w", relPath, err)
+		}
+		fmt.Fprintf(hash, "%s\x00link\x00%s\n", relPath, target)
+		return nil
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(absPath)
+	if err != nil {
+		return fmt.Errorf("open build file %s: %w", relPath, err)
+	}
+	defer f.Close()
+
+	content := sha256.New()
+	if _, err := io.Copy(content, f); err != nil {
+		return fmt.Errorf("read build file %s: %w", relPath, err)
+	}
+
+	mode := "file"
+	if info.Mode().Perm()&0o111 != 0 {
+		mode = "exec"
+	}
+	fmt.Fprintf(hash, "%s\x00%s\x00%x\n", relPath, mode, content.Sum(nil))
+	return nil
+}
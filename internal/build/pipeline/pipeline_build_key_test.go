@@ -0,0 +1,136 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/placeholders"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/placeholders/git"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/stretchr/testify/require"
+)
+
+// testCommitRepository reports a fixed current commit, the other git info is not used by the build key tests.
+type testCommitRepository struct {
+	git.RepositoryInfoService
+	commit string
+}
+
+func (r testCommitRepository) CurrentCommit() (*object.Commit, error) {
+	return &object.Commit{Hash: plumbing.NewHash(r.commit)}, nil
+}
+
+func TestService_GetBuildKey(t *testing.T) {
+	t.Parallel()
+
+	newWorkspace := func(t *testing.T) string {
+		root := t.TempDir()
+		DirectorySpec{
+			".": {
+				{Name: "pnpm-workspace.yaml", Content: `
+packages:
+  - "apps/*"
+  - "packages/*"
+`},
+				{Name: "pnpm-lock.yaml", Content: `lockfileVersion: '9.0'`},
+				{Name: ".gitignore", Content: "*.log\n.env\n"},
+				{Name: "README.md", Content: `docs`},
+			},
+			"packages/shared": {
+				{Name: "package.json", Content: `{ "name": "shared" }`},
+				{Name: "index.ts", Content: `export const shared = 1`},
+			},
+			"apps/api": {
+				{Name: "package.json", Content: `{"name": "api", "dependencies": {"shared": "workspace:*"}}`},
+				{Name: "index.ts", Content: `console.log("api")`},
+			},
+			"apps/web": {
+				{Name: "package.json", Content: `{ "name": "web" }`},
+				{Name: "index.ts", Content: `console.log("web")`},
+			},
+			"proto": {
+				{Name: "api.proto", Content: `syntax = "proto3";`},
+			},
+		}.Build(t, root)
+		return root
+	}
+	config := Config{App: "api", NodeVersion: "22", ExtraFiles: []string{"proto"}, Cmd: []string{"node", "{{ app.dir }}/index.js"}}
+	buildKey := func(t *testing.T, root string, config Config) string {
+		key, err := NewService(config, root, NewPnpmMonorepo(root), nil).GetBuildKey()
+		require.NoError(t, err)
+		require.Len(t, key, 64)
+		return key
+	}
+
+	t.Run("same inputs give the same key", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		r.Equal(buildKey(t, newWorkspace(t), config), buildKey(t, newWorkspace(t), config))
+	})
+
+	for name, tc := range map[string]struct {
+		change  func(t *testing.T, root string)
+		changed bool
+	}{
+		"app file":               {func(t *testing.T, root string) { writeFile(t, root, "apps/api/index.ts", `console.log("v2")`) }, true},
+		"dependency file":        {func(t *testing.T, root string) { writeFile(t, root, "packages/shared/util.ts", `export {}`) }, true},
+		"lockfile":               {func(t *testing.T, root string) { writeFile(t, root, "pnpm-lock.yaml", `lockfileVersion: '9.1'`) }, true},
+		"extra file":             {func(t *testing.T, root string) { writeFile(t, root, "proto/api.proto", `syntax = "proto2";`) }, true},
+		"unrelated package":      {func(t *testing.T, root string) { writeFile(t, root, "apps/web/index.ts", `console.log("v2")`) }, false},
+		"file outside the build": {func(t *testing.T, root string) { writeFile(t, root, "README.md", `more docs`) }, false},
+		"git ignored file":       {func(t *testing.T, root string) { writeFile(t, root, "apps/api/debug.log", `log`) }, false},
+		"excluded build output":  {func(t *testing.T, root string) { writeFile(t, root, "apps/api/dist/index.js", `built`) }, false},
+		"installed node modules": {func(t *testing.T, root string) { writeFile(t, root, "apps/api/node_modules/x/index.js", `x`) }, false},
+		"executable bit of a file": {func(t *testing.T, root string) {
+			require.NoError(t, os.Chmod(filepath.Join(root, "apps", "api", "index.ts"), 0o755))
+		}, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			root := newWorkspace(t)
+			before := buildKey(t, root, config)
+			tc.change(t, root)
+			after := buildKey(t, root, config)
+			if tc.changed {
+				r.NotEqual(before, after)
+			} else {
+				r.Equal(before, after)
+			}
+		})
+	}
+
+	t.Run("pipeline config", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		root := newWorkspace(t)
+		changedConfig := config
+		changedConfig.NodeVersion = "24"
+		r.NotEqual(buildKey(t, root, config), buildKey(t, root, changedConfig))
+	})
+
+	t.Run("resolved placeholders", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		root := newWorkspace(t)
+		commitConfig := config
+		commitConfig.Cmd = []string{"node", "index.js", "--release={{ git.commit | upper }}"}
+		commitKey := func(commit string) string {
+			placeholdersSvc := placeholders.NewService(testCommitRepository{commit: commit})
+			key, err := NewService(commitConfig, root, NewPnpmMonorepo(root), placeholdersSvc).GetBuildKey()
+			r.NoError(err)
+			return key
+		}
+
+		first := "1111111111111111111111111111111111111111"
+		second := "2222222222222222222222222222222222222222"
+		r.Equal(commitKey(first), commitKey(first))
+		r.NotEqual(commitKey(first), commitKey(second))
+	})
+}
@@ -22,6 +22,7 @@ type PnpmMonorepo struct {
 
 type PackageJson struct {
 	Name             string            `json:"name"`
+	Version          string            `json:"version"`
 	Dependencies     map[string]string `json:"dependencies"`
 	DevDependencies  map[string]string `json:"devDependencies"`
 	PeerDependencies map[string]string `json:"peerDependencies"`
@@ -159,6 +160,31 @@ func (p *PnpmMonorepo) GetPackageDependencies(pkg WorkspacePackage, workspacePac
 	return p.getPackageDependencies(dependencies, pkg, workspacePackages, dependencyTypes...)
 }
 
+// GetDependentPackages returns the workspace packages depending on any of the packages, directly or through other
+// workspace packages, by walking GetPackageDependencies in reverse.
+func (p *PnpmMonorepo) GetDependentPackages(pkgs []WorkspacePackage, workspacePackages []WorkspacePackage, dependencyTypes ...PackageDependencyType) []WorkspacePackage {
+	names := make(map[string]struct{}, len(pkgs))
+	for _, pkg := range pkgs {
+		names[pkg.Manifest.Name] = struct{}{}
+	}
+
+	var dependents []WorkspacePackage
+	for _, candidate := range workspacePackages {
+		if _, ok := names[candidate.Manifest.Name]; ok {
+			continue
+		}
+		// getPackageDependencies removes the processed packages from the slice it gets
+		for _, dependency := range p.GetPackageDependencies(candidate, slices.Clone(workspacePackages), dependencyTypes...) {
+			if _, ok := names[dependency.Manifest.Name]; ok {
+				dependents = append(dependents, candidate)
+				break
+			}
+		}
+	}
+
+	return dependents
+}
+
 func (p *PnpmMonorepo) getPackageDependencies(dependencies map[string]WorkspacePackage, pkg WorkspacePackage, workspacePackages []WorkspacePackage, dependencyTypes ...PackageDependencyType) []WorkspacePackage {
 	totalDependencies := make(map[string]string, len(workspacePackages))
 	for _, dependencyType := range dependencyTypes {
@@ -3,6 +3,7 @@ package pipeline
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"testing"
 
@@ -373,6 +374,59 @@ packages:
 	})
 }
 
+func TestPnpmMonorepo_GetDependentPackages(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	root := t.TempDir()
+	DirectorySpec{
+		".": {
+			{Name: "pnpm-workspace.yaml", Content: `
+packages:
+  - "apps/*"
+  - "packages/*"
+`},
+		},
+		"packages/lib-a": {
+			{Name: "package.json", Content: `{ "name": "lib-a" }`},
+		},
+		"packages/lib-b": {
+			{Name: "package.json", Content: `{"name": "lib-b", "dependencies": {"lib-a": "workspace:*"}}`},
+		},
+		"apps/api": {
+			{Name: "package.json", Content: `{"name": "api", "dependencies": {"lib-b": "workspace:*"}}`},
+		},
+		"apps/web": {
+			{Name: "package.json", Content: `{"name": "web", "devDependencies": {"lib-a": "workspace:*"}}`},
+		},
+		"apps/admin": {
+			{Name: "package.json", Content: `{ "name": "admin" }`},
+		},
+	}.Build(t, root)
+
+	workspace := NewPnpmMonorepo(root)
+	packages, err := workspace.GetWorkspacePackages()
+	r.NoError(err)
+	packageNames := func(pkgs []WorkspacePackage) []string {
+		names := make([]string, 0, len(pkgs))
+		for _, pkg := range pkgs {
+			names = append(names, pkg.Manifest.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+	libA := packages[slices.IndexFunc(packages, func(p WorkspacePackage) bool { return p.Manifest.Name == "lib-a" })]
+
+	dependents := workspace.GetDependentPackages([]WorkspacePackage{libA}, packages, PackageDependencyTypeDependencies)
+	r.Equal([]string{"api", "lib-b"}, packageNames(dependents))
+
+	dependents = workspace.GetDependentPackages([]WorkspacePackage{libA}, packages, PackageDependencyTypeDependencies, PackageDependencyTypeDevDependencies)
+	r.Equal([]string{"api", "lib-b", "web"}, packageNames(dependents))
+
+	// The workspace packages are left as they were
+	r.Len(packages, 5)
+}
+
 // --- helpers ---
 
 func mkdirAll(t *testing.T, root string, rel string) {
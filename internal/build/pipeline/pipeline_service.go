@@ -16,28 +16,30 @@ import (
 )
 
 type Config struct {
-	NodeVersion  string       `mapstructure:"node_version"`
-	PnpmVersion  string       `mapstructure:"pnpm_version"`
-	App          string       `mapstructure:"app"`
-	Root         string       `mapstructure:"root"`
-	ExtraFiles   []string     `mapstructure:"extra_files"`
-	ExcludeFiles []string     `mapstructure:"exclude_files"`
-	Steps        []Step       `mapstructure:"steps"`
-	RuntimeSteps []Step       `mapstructure:"runtime_steps"`
-	Platform     lib.Platform `mapstructure:"platform"`
-	Cmd          []string     `mapstructure:"cmd"`
-	Opt          Options      `mapstructure:"opt"`
+	NodeVersion  string       `mapstructure:"node_version" json:"node_version,omitempty"`
+	PnpmVersion  string       `mapstructure:"pnpm_version" json:"pnpm_version,omitempty"`
+	App          string       `mapstructure:"app" json:"app,omitempty"`
+	Root         string       `mapstructure:"root" json:"root,omitempty"`
+	ExtraFiles   []string     `mapstructure:"extra_files" json:"extra_files,omitempty"`
+	ExcludeFiles []string     `mapstructure:"exclude_files" json:"exclude_files,omitempty"`
+	Steps        []Step       `mapstructure:"steps" json:"steps,omitempty"`
+	RuntimeSteps []Step       `mapstructure:"runtime_steps" json:"runtime_steps,omitempty"`
+	Platform     lib.Platform `mapstructure:"platform" json:"platform,omitempty"`
+	// Platforms builds one image per platform and exports them as an OCI image index, used instead of Platform
+	Platforms []lib.Platform `mapstructure:"platforms" json:"platforms,omitempty"`
+	Cmd       []string       `mapstructure:"cmd" json:"cmd,omitempty"`
+	Opt       Options        `mapstructure:"opt" json:"opt"`
 }
 
 type Options struct {
-	PackagesPrune *bool `mapstructure:"packages_prune"`
-	NodePrune     *bool `mapstructure:"node_prune"`
+	PackagesPrune *bool `mapstructure:"packages_prune" json:"packages_prune,omitempty"`
+	NodePrune     *bool `mapstructure:"node_prune" json:"node_prune,omitempty"`
 }
 
 type Step struct {
-	Task             TaskID         `mapstructure:"task"`
-	WorkingDirectory *string        `mapstructure:"working_directory"`
-	Extra            map[string]any `mapstructure:",remain"`
+	Task             TaskID         `mapstructure:"task" json:"task,omitempty"`
+	WorkingDirectory *string        `mapstructure:"working_directory" json:"working_directory,omitempty"`
+	Extra            map[string]any `mapstructure:",remain" json:"extra,omitempty"`
 }
 
 type processStepsResult struct {
@@ -55,6 +57,12 @@ type Task interface {
 	GetCmd() ([][]string, error)
 }
 
+// Output is where the built image goes: the docker daemon under Image, or an OCI tarball at TarballPath when it is set.
+type Output struct {
+	Image       string
+	TarballPath string
+}
+
 type PlaceholderResolvers map[string]placeholders.PlaceholderResolver
 
 type Service struct {
@@ -77,65 +85,89 @@ func NewService(config Config, repoRoot string, monorepo *PnpmMonorepo, placehol
 	return &Service{config, repoRoot, monorepo, placeholders}
 }
 
-func (s *Service) ProcessPipeline(ctx context.Context, outputImage string) error {
-	l := slog.With("context", "pipeline_service")
+// pipelineBuild holds the platform independent inputs of the build stages, shared by the builds of every platform.
+type pipelineBuild struct {
+	appPackage                  WorkspacePackage
+	dependencies                []WorkspacePackage
+	cmd                         []string
+	baseImage                   string
+	workdir                     string
+	filesForPackageInstallation []string
+	includePaths                []string
+	steps                       processStepsResult
+	runtimeSteps                processStepsResult
+}
 
-	if s.config.App == "" {
-		return fmt.Errorf("%w - no app specified in pipeline config", lib.BadUserInputError)
+// GetPlatforms returns the platforms to build, linux/amd64 when none is configured.
+func (c Config) GetPlatforms() ([]lib.Platform, error) {
+	if c.Platform != "" && len(c.Platforms) > 0 {
+		return nil, fmt.Errorf("%w - only one of 'platform' and 'platforms' can be set in the pipeline config", lib.BadUserInputError)
 	}
 
-	platform := s.config.Platform
-	if platform == "" {
-		platform = lib.PlatformLinuxAmd64
+	platforms := c.Platforms
+	if c.Platform != "" {
+		platforms = []lib.Platform{c.Platform}
 	}
+	if len(platforms) == 0 {
+		platforms = []lib.Platform{lib.PlatformLinuxAmd64}
+	}
+
 	allowedPlatforms := map[lib.Platform]struct{}{
 		lib.PlatformLinuxAmd64: {},
 		lib.PlatformLinuxArm64: {},
 	}
-	if _, ok := allowedPlatforms[platform]; !ok {
-		supported := make([]string, 0, len(allowedPlatforms))
-		for platform := range allowedPlatforms {
-			supported = append(supported, string(platform))
+	for i, platform := range platforms {
+		if _, ok := allowedPlatforms[platform]; !ok {
+			supported := make([]string, 0, len(allowedPlatforms))
+			for platform := range allowedPlatforms {
+				supported = append(supported, string(platform))
+			}
+			return nil, fmt.Errorf("%w - unsupported platform '%s' for pipeline builds, Supported are %s", lib.BadUserInputError, platform, strings.Join(supported, ", "))
 		}
-		return fmt.Errorf("%w - unsupported platform '%s' for pipeline builds, Supported are %s", lib.BadUserInputError, platform, strings.Join(supported, ", "))
+		if slices.Contains(platforms[:i], platform) {
+			return nil, fmt.Errorf("%w - platform '%s' is listed more than once in the pipeline config", lib.BadUserInputError, platform)
+		}
+	}
+
+	return platforms, nil
+}
+
+func (s *Service) ProcessPipeline(ctx context.Context, output Output) error {
+	l := slog.With("context", "pipeline_service")
+
+	if s.config.App == "" {
+		return fmt.Errorf("%w - no app specified in pipeline config", lib.BadUserInputError)
+	}
+
+	platforms, err := s.config.GetPlatforms()
+	if err != nil {
+		return err
+	}
+	if len(platforms) > 1 && output.TarballPath == "" {
+		// The docker daemon stores a single platform per image tag, the image index only survives in a tarball
+		return fmt.Errorf("%w - building several platforms requires the 'pipeline' image source", lib.BadUserInputError)
 	}
 
 	l.Info("building docker image from pipeline config",
 		"app", s.config.App,
 		"node_version", s.config.NodeVersion,
 		"pnpm_version", s.config.PnpmVersion,
-		"platform", platform,
+		"platforms", platforms,
 		"cmd", s.config.Cmd)
 
-	workspacePackages, err := s.monorepo.GetWorkspacePackages()
+	appPackage, workspacePackages, err := s.getAppPackage()
 	if err != nil {
-		return fmt.Errorf("failed to get workspace packages: %w", err)
-	}
-	l.Debug("retrieved workspace packages", "packages", workspacePackages)
-
-	appPackageIdx := slices.IndexFunc(workspacePackages, func(p WorkspacePackage) bool {
-		return p.Manifest.Name == s.config.App
-	})
-	if appPackageIdx < 0 {
-		return fmt.Errorf("%w - app package '%s' not found in monorepo workspace packages", lib.BadUserInputError, s.config.App)
+		return err
 	}
-
-	appPackage := workspacePackages[appPackageIdx]
 	l.Info("target workspace package found", "package", appPackage)
 
 	dependencies := s.monorepo.GetPackageDependencies(appPackage, workspacePackages, PackageDependencyTypeDependencies, PackageDependencyTypeDevDependencies)
 	l.Debug("app package dependencies", "dependencies", dependencies)
 
-	pipelinePlaceholderResolvers := PlaceholderResolvers{
-		"app.dir": func() (string, error) {
-			return appPackage.Path, nil
-		},
-		"app.package": func() (string, error) {
-			return appPackage.Manifest.Name, nil
-		},
-	}
+	pipelinePlaceholderResolvers := getPlaceholderResolvers(appPackage)
 
-	cmd := s.config.Cmd
+	// Resolved on a copy, the config is part of the build key
+	cmd := slices.Clone(s.config.Cmd)
 	if cmd == nil || len(cmd) == 0 {
 		return fmt.Errorf("%w - no 'cmd' specified for pipeline build", lib.BadUserInputError)
 	}
@@ -148,18 +180,6 @@ func (s *Service) ProcessPipeline(ctx context.Context, outputImage string) error
 	}
 	l.Info("resolved cmd", "cmd", cmd)
 
-	baseImage := fmt.Sprintf("node:%s-alpine", s.config.NodeVersion)
-	workdir := "/app"
-
-	client, err := dagger.Connect(
-		ctx,
-		dagger.WithLogOutput(os.Stdout),
-	)
-	if err != nil {
-		return fmt.Errorf("failed to connect to Dagger: %w", err)
-	}
-	defer client.Close()
-
 	filesForPackageInstallation := []string{
 		appPackage.ManifestPath,
 		"package.json",
@@ -182,27 +202,250 @@ func (s *Service) ProcessPipeline(ctx context.Context, outputImage string) error
 	}
 	l.Info("package installation files", "files", filesForPackageInstallation)
 
-	mandatoryFiles := []string{
-		appPackage.Path,
-		"package.json",
-		"pnpm-workspace.yaml",
-		"pnpm-lock.yaml",
-		"tsconfig.json",
-		".npmrc",
-		".gitignore",
+	includePaths := s.getIncludePaths(appPackage, dependencies)
+	l.Info("production build files", "paths", includePaths)
+
+	stepResults, err := s.processSteps(s.config.Steps, pipelinePlaceholderResolvers)
+	if err != nil {
+		return fmt.Errorf("processing pipeline steps: %w", err)
+	}
+
+	allowedRuntimeStageTasks := []TaskID{
+		TaskIDSetupPnpm,
+		TaskIDSetupBun,
+	}
+	runtimeStepsResult, err := s.processSteps(s.config.RuntimeSteps, pipelinePlaceholderResolvers, allowedRuntimeStageTasks...)
+	if err != nil {
+		return fmt.Errorf("processing runtime steps: %w", err)
 	}
-	includePaths := make([]string, 0, len(mandatoryFiles)+len(dependencies)+len(s.config.ExtraFiles))
+	if len(runtimeStepsResult.NpmPackages) > 0 {
+		return fmt.Errorf("%w - installing npm packages is not supported in runtime phase", lib.BadUserInputError)
+	}
+
+	build := pipelineBuild{
+		appPackage:                  appPackage,
+		dependencies:                dependencies,
+		cmd:                         cmd,
+		baseImage:                   fmt.Sprintf("node:%s-alpine", s.config.NodeVersion),
+		workdir:                     "/app",
+		filesForPackageInstallation: filesForPackageInstallation,
+		includePaths:                includePaths,
+		steps:                       stepResults,
+		runtimeSteps:                runtimeStepsResult,
+	}
+
+	client, err := dagger.Connect(
+		ctx,
+		dagger.WithLogOutput(os.Stdout),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Dagger: %w", err)
+	}
+	defer client.Close()
+
+	runtimes := make([]*dagger.Container, 0, len(platforms))
+	for _, platform := range platforms {
+		runtime, err := s.buildRuntimeContainer(client, platform, build)
+		if err != nil {
+			return fmt.Errorf("building %s image: %w", platform, err)
+		}
+		runtimes = append(runtimes, runtime)
+	}
+
+	if output.TarballPath != "" {
+		// Several platforms are exported as a single OCI image index
+		exportOpts := dagger.ContainerExportOpts{PlatformVariants: runtimes[1:]}
+		if _, err := runtimes[0].Export(ctx, output.TarballPath, exportOpts); err != nil {
+			return fmt.Errorf("exporting pipeline image to %s: %w", output.TarballPath, err)
+		}
+
+		l.Info("docker image built successfully via pipeline", "tarball", output.TarballPath, "platforms", platforms)
+		return nil
+	}
+
+	err = runtimes[0].ExportImage(ctx, output.Image) // TODO: ensure images compression when exported
+	if err != nil {
+		return fmt.Errorf("setting up pipeline container: %w", err)
+	}
+
+	l.Info("docker image built successfully via pipeline", "image", output.Image)
+	l.Info(fmt.Sprintf("run 'docker run --rm -it %s sh' to access the image", output.Image))
+
+	return nil
+}
+
+// getAppPackage returns the workspace package of the app and all the workspace packages of the monorepo.
+func (s *Service) getAppPackage() (WorkspacePackage, []WorkspacePackage, error) {
+	workspacePackages, err := s.monorepo.GetWorkspacePackages()
+	if err != nil {
+		return WorkspacePackage{}, nil, fmt.Errorf("failed to get workspace packages: %w", err)
+	}
+	slog.Debug("retrieved workspace packages", "context", "pipeline_service", "packages", workspacePackages)
+
+	appPackageIdx := slices.IndexFunc(workspacePackages, func(p WorkspacePackage) bool {
+		return p.Manifest.Name == s.config.App
+	})
+	if appPackageIdx < 0 {
+		return WorkspacePackage{}, nil, fmt.Errorf("%w - app package '%s' not found in monorepo workspace packages", lib.BadUserInputError, s.config.App)
+	}
+
+	return workspacePackages[appPackageIdx], workspacePackages, nil
+}
+
+// getPlaceholderResolvers returns the placeholders of the app that pipeline steps and cmd can use on top of the global ones.
+func getPlaceholderResolvers(appPackage WorkspacePackage) PlaceholderResolvers {
+	return PlaceholderResolvers{
+		"app.dir": func() (string, error) {
+			return appPackage.Path, nil
+		},
+		"app.package": func() (string, error) {
+			return appPackage.Manifest.Name, nil
+		},
+	}
+}
+
+// getIncludePaths returns the paths the runtime image gets from the builder: the app, its workspace dependencies,
+// the workspace root files and the extra files.
+func (s *Service) getIncludePaths(appPackage WorkspacePackage, dependencies []WorkspacePackage) []string {
+	includePaths := make([]string, 0, 1+len(dependencies)+len(workspaceRootFiles)+len(s.config.ExtraFiles))
 	for _, dep := range dependencies {
 		includePaths = append(includePaths, dep.Path)
 	}
-	includePaths = append(includePaths, mandatoryFiles...)
+	includePaths = append(includePaths, appPackage.Path)
+	includePaths = append(includePaths, workspaceRootFiles...)
 	includePaths = append(includePaths, s.config.ExtraFiles...)
-	l.Info("production build files", "paths", includePaths)
+	return includePaths
+}
 
-	stepResults, err := s.processSteps(s.config.Steps, pipelinePlaceholderResolvers)
+// GetAppDependencyGraph returns the app package and the workspace packages it depends on at runtime.
+func (s *Service) GetAppDependencyGraph() (WorkspacePackage, []WorkspacePackage, error) {
+	if s.config.App == "" {
+		return WorkspacePackage{}, nil, fmt.Errorf("%w - no app specified in pipeline config", lib.BadUserInputError)
+	}
+
+	appPackage, workspacePackages, err := s.getAppPackage()
 	if err != nil {
-		return fmt.Errorf("processing pipeline steps: %w", err)
+		return WorkspacePackage{}, nil, err
+	}
+
+	return appPackage, s.monorepo.GetPackageDependencies(appPackage, workspacePackages, PackageDependencyTypeDependencies), nil
+}
+
+// workspaceRootFiles are the files at the monorepo root every app build uses.
+var workspaceRootFiles = []string{
+	"package.json",
+	"pnpm-workspace.yaml",
+	"pnpm-lock.yaml",
+	"tsconfig.json",
+	".npmrc",
+	".gitignore",
+}
+
+// GetAffectingChanges returns what, among the changed files, affects the app image: the changed workspace packages the
+// app is or depends on, changed workspace root files and changed extra files. The changed files are absolute paths,
+// files outside the monorepo root are ignored.
+func (s *Service) GetAffectingChanges(changedFiles []string) ([]string, error) {
+	if s.config.App == "" {
+		return nil, fmt.Errorf("%w - no app specified in pipeline config", lib.BadUserInputError)
+	}
+
+	appPackage, workspacePackages, err := s.getAppPackage()
+	if err != nil {
+		return nil, err
+	}
+
+	repoRoot, err := filepath.Abs(s.repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("resolving repository root: %w", err)
+	}
+
+	var changes []string
+	var changedPackages []WorkspacePackage
+	for _, changedFile := range changedFiles {
+		relPath, err := filepath.Rel(repoRoot, changedFile)
+		if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			continue
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		extraFile, err := isIncludedPath(relPath, s.config.ExtraFiles)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(workspaceRootFiles, relPath) || extraFile {
+			changes = append(changes, relPath)
+			continue
+		}
+
+		pkg, ok := findOwningPackage(relPath, workspacePackages)
+		if ok && !slices.ContainsFunc(changedPackages, func(p WorkspacePackage) bool { return p.Path == pkg.Path }) {
+			changedPackages = append(changedPackages, pkg)
+		}
+	}
+
+	for _, changedPackage := range changedPackages {
+		if changedPackage.Path == appPackage.Path {
+			changes = append(changes, changedPackage.Manifest.Name)
+			continue
+		}
+
+		// Dev dependencies count, since the app build runs with them
+		dependents := s.monorepo.GetDependentPackages([]WorkspacePackage{changedPackage}, workspacePackages, PackageDependencyTypeDependencies, PackageDependencyTypeDevDependencies)
+		if slices.ContainsFunc(dependents, func(p WorkspacePackage) bool { return p.Path == appPackage.Path }) {
+			changes = append(changes, changedPackage.Manifest.Name)
+		}
+	}
+
+	return changes, nil
+}
+
+// isIncludedPath reports whether the path is one of the include paths, inside one of them or matching one as a pattern.
+func isIncludedPath(relPath string, includePaths []string) (bool, error) {
+	for _, includePath := range includePaths {
+		includePath = strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(includePath), "./"), "/")
+		if includePath == "." || includePath == "" || relPath == includePath || strings.HasPrefix(relPath, includePath+"/") {
+			return true, nil
+		}
+	}
+	return lib.PathMatchesOneOfPatterns(relPath, includePaths)
+}
+
+// findOwningPackage returns the innermost workspace package containing the path.
+func findOwningPackage(relPath string, workspacePackages []WorkspacePackage) (WorkspacePackage, bool) {
+	var owner WorkspacePackage
+	found := false
+	for _, pkg := range workspacePackages {
+		if pkg.Path != "." && relPath != pkg.Path && !strings.HasPrefix(relPath, pkg.Path+"/") {
+			continue
+		}
+		if !found || len(pkg.Path) > len(owner.Path) || owner.Path == "." {
+			owner, found = pkg, true
+		}
 	}
+	return owner, found
+}
+
+// GetApp returns the workspace package name of the app the pipeline builds.
+func (s *Service) GetApp() string {
+	return s.config.App
+}
+
+// GetRepoRoot returns the root of the monorepo the pipeline builds from.
+func (s *Service) GetRepoRoot() string {
+	return s.repoRoot
+}
+
+// buildRuntimeContainer defines the builder, production dependencies and runtime stages for one platform.
+func (s *Service) buildRuntimeContainer(client *dagger.Client, platform lib.Platform, build pipelineBuild) (*dagger.Container, error) {
+	l := slog.With("context", "pipeline_service", "platform", platform)
+
+	workdir := build.workdir
+	baseImage := build.baseImage
+	appPackage := build.appPackage
+	dependencies := build.dependencies
+	filesForPackageInstallation := build.filesForPackageInstallation
+	stepResults := build.steps
+	runtimeStepsResult := build.runtimeSteps
 
 	pnpmCacheVolume := client.CacheVolume(fmt.Sprintf("pnpm-cache-%s", s.config.PnpmVersion))
 
@@ -244,22 +487,14 @@ func (s *Service) ProcessPipeline(ctx context.Context, outputImage string) error
 		WithExec([]string{"pnpm", "install", "--prefer-offline", "--frozen-lockfile"}).
 		WithDirectory(workdir, hostRepoRootDir, dagger.ContainerWithDirectoryOpts{
 			//Include: []string{"**"}, // include all files for build
-			Exclude: append([]string{
-				"**/node_modules",
-				"**/dist",
-				"**/build",
-				"**/out",
-				"**/.next",
-				"**/.cache",
-				"**/.turbo",
-			}, s.config.ExcludeFiles...),
+			Exclude:   append(slices.Clone(buildExcludePatterns), s.config.ExcludeFiles...),
 			Gitignore: true,
 		})
 
 	for _, task := range stepResults.Tasks {
 		cmds, err := task.GetCmd()
 		if err != nil {
-			return fmt.Errorf("getting command for pipeline task: %w", err)
+			return nil, fmt.Errorf("getting command for pipeline task: %w", err)
 		}
 
 		for _, cmd := range cmds {
@@ -314,16 +549,7 @@ func (s *Service) ProcessPipeline(ctx context.Context, outputImage string) error
 			WithExec([]string{"/usr/local/bin/node-prune", "/app/node_modules"})
 	}
 
-	allowedRuntimeStageTasks := []TaskID{
-		TaskIDSetupPnpm,
-		TaskIDSetupBun,
-	}
-	runtimeStepsResult, err := s.processSteps(s.config.RuntimeSteps, pipelinePlaceholderResolvers, allowedRuntimeStageTasks...)
-	if err != nil {
-		return fmt.Errorf("processing runtime steps: %w", err)
-	}
-
-	runtimePathsToInclude := includePaths
+	runtimePathsToInclude := build.includePaths
 	runtime := client.Container(dagger.ContainerOpts{Platform: dagger.Platform(platform)}).
 		From(baseImage).
 		WithWorkdir(workdir)
@@ -336,10 +562,6 @@ func (s *Service) ProcessPipeline(ctx context.Context, outputImage string) error
 		runtime = runtime.WithExec(cmd)
 	}
 
-	if len(runtimeStepsResult.NpmPackages) > 0 {
-		return fmt.Errorf("%w - installing npm packages is not supported in runtime phase", lib.BadUserInputError)
-	}
-
 	// The key parts for runtime image construction:
 	// 1. Copy the pruned node_modules from the deps stage - it must utilize the layer caching so it is not uploaded every time the image is rebuilt
 	// 2. Copy other node_modules for the packages in the monorepo. The goal is the same - utilize layer caching for node_modules
@@ -362,7 +584,7 @@ func (s *Service) ProcessPipeline(ctx context.Context, outputImage string) error
 	for _, task := range runtimeStepsResult.Tasks {
 		cmds, err := task.GetCmd()
 		if err != nil {
-			return fmt.Errorf("getting command for runtime pipeline task: %w", err)
+			return nil, fmt.Errorf("getting command for runtime pipeline task: %w", err)
 		}
 
 		for _, cmd := range cmds {
@@ -370,19 +592,11 @@ func (s *Service) ProcessPipeline(ctx context.Context, outputImage string) error
 		}
 	}
 
-	err = runtime.
-		WithEntrypoint([]string{cmd[0]}).
-		WithDefaultArgs(cmd[1:]).
-		ExportImage(ctx, outputImage) // TODO: ensure images compression when exported
-
-	if err != nil {
-		return fmt.Errorf("setting up pipeline container: %w", err)
-	}
-
-	l.Info("docker image built successfully via pipeline", "image", outputImage)
-	l.Info(fmt.Sprintf("run 'docker run --rm -it %s sh' to access the image", outputImage))
+	runtime = runtime.
+		WithEntrypoint([]string{build.cmd[0]}).
+		WithDefaultArgs(build.cmd[1:])
 
-	return nil
+	return runtime, nil
 }
 
 func (s *Service) processSteps(steps []Step, placeholderResolvers PlaceholderResolvers, allowedSteps ...TaskID) (processStepsResult, error) {
@@ -0,0 +1,119 @@
+package pipeline
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_GetPlatforms(t *testing.T) {
+	t.Parallel()
+
+	t.Run("defaults to linux/amd64", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		platforms, err := Config{}.GetPlatforms()
+		r.NoError(err)
+		r.Equal([]lib.Platform{lib.PlatformLinuxAmd64}, platforms)
+	})
+
+	t.Run("accepts a single platform", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		platforms, err := Config{Platform: lib.PlatformLinuxArm64}.GetPlatforms()
+		r.NoError(err)
+		r.Equal([]lib.Platform{lib.PlatformLinuxArm64}, platforms)
+	})
+
+	t.Run("accepts a list of platforms", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		platforms, err := Config{Platforms: []lib.Platform{lib.PlatformLinuxAmd64, lib.PlatformLinuxArm64}}.GetPlatforms()
+		r.NoError(err)
+		r.Equal([]lib.Platform{lib.PlatformLinuxAmd64, lib.PlatformLinuxArm64}, platforms)
+	})
+
+	t.Run("rejects invalid configs", func(t *testing.T) {
+		t.Parallel()
+
+		for name, config := range map[string]Config{
+			"both fields":          {Platform: lib.PlatformLinuxAmd64, Platforms: []lib.Platform{lib.PlatformLinuxArm64}},
+			"unsupported platform": {Platforms: []lib.Platform{"windows/amd64"}},
+			"duplicate platform":   {Platforms: []lib.Platform{lib.PlatformLinuxArm64, lib.PlatformLinuxArm64}},
+		} {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				_, err := config.GetPlatforms()
+				require.ErrorIs(t, err, lib.BadUserInputError)
+			})
+		}
+	})
+}
+
+func TestService_GetAffectingChanges(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	DirectorySpec{
+		".": {
+			{Name: "pnpm-workspace.yaml", Content: `
+packages:
+  - "apps/*"
+  - "packages/*"
+`},
+			{Name: "README.md", Content: `docs`},
+		},
+		"packages/lib-a": {
+			{Name: "package.json", Content: `{ "name": "lib-a" }`},
+		},
+		"packages/lib-b": {
+			{Name: "package.json", Content: `{"name": "lib-b", "dependencies": {"lib-a": "workspace:*"}}`},
+		},
+		"apps/api": {
+			{Name: "package.json", Content: `{"name": "api", "dependencies": {"lib-b": "workspace:*"}}`},
+		},
+		"apps/web": {
+			{Name: "package.json", Content: `{ "name": "web" }`},
+		},
+		"proto": {
+			{Name: "api.proto", Content: `syntax = "proto3";`},
+		},
+	}.Build(t, root)
+
+	svc := NewService(Config{App: "api", ExtraFiles: []string{"proto"}}, root, NewPnpmMonorepo(root), nil)
+	changed := func(paths ...string) []string {
+		files := make([]string, 0, len(paths))
+		for _, path := range paths {
+			files = append(files, filepath.Join(root, filepath.FromSlash(path)))
+		}
+		return files
+	}
+
+	for name, tc := range map[string]struct {
+		files    []string
+		expected []string
+	}{
+		"app":                   {changed("apps/api/src/index.ts"), []string{"api"}},
+		"transitive dependency": {changed("packages/lib-a/src/index.ts", "packages/lib-a/README.md"), []string{"lib-a"}},
+		"unrelated package":     {changed("apps/web/src/index.ts"), nil},
+		"root file":             {changed("pnpm-lock.yaml", "README.md"), []string{"pnpm-lock.yaml"}},
+		"extra file":            {changed("proto/api.proto"), []string{"proto/api.proto"}},
+		"gitignore":             {changed(".gitignore"), []string{".gitignore"}},
+		"outside the monorepo":  {[]string{filepath.Join(filepath.Dir(root), "other", "package.json")}, nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			changes, err := svc.GetAffectingChanges(tc.files)
+			r.NoError(err)
+			r.Equal(tc.expected, changes)
+		})
+	}
+}
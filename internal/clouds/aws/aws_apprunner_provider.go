@@ -8,6 +8,7 @@ import (
 
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	aws_sdk "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	aws_config "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/apprunner"
@@ -150,3 +151,75 @@ waiterLoop:
 
 	return nil
 }
+
+func (p *AppRunnerProvider) PlanDeployServiceFromImage(ctx context.Context, registry clouds.ImageRegistry) (clouds.DeployPlan, error) {
+	var plan clouds.DeployPlan
+
+	imageRef, err := registry.GetImageRef()
+	if err != nil {
+		return plan, fmt.Errorf("getting image reference for service %s: %w", p.config.ARN, err)
+	}
+	if imageRef == "" {
+		return plan, fmt.Errorf("image reference is empty for service %s", p.config.ARN)
+	}
+
+	describeOutput, err := p.apprunner.DescribeService(ctx, &apprunner.DescribeServiceInput{
+		ServiceArn: &p.config.ARN,
+	})
+	if err != nil {
+		return plan, fmt.Errorf("describing App Runner service: %w", err)
+	}
+
+	service := describeOutput.Service
+	if service == nil {
+		return plan, fmt.Errorf("App Runner service with ARN %s not found", p.config.ARN)
+	}
+	sourceConfig := service.SourceConfiguration
+	if sourceConfig == nil || sourceConfig.ImageRepository == nil {
+		return plan, fmt.Errorf("%w - App Runner service %s is not configured with an image repository", lib.BadUserInputError, p.config.ARN)
+	}
+
+	plan.Add(fmt.Sprintf("service %s image", aws_sdk.ToString(service.ServiceName)), aws_sdk.ToString(sourceConfig.ImageRepository.ImageIdentifier), imageRef)
+	plan.Add("service update operation started", "", string(types.OperationTypeUpdateService))
+
+	return plan, nil
+}
+
+func (p *AppRunnerProvider) GetServiceStatus(ctx context.Context) (clouds.ServiceStatus, error) {
+	describeOutput, err := p.apprunner.DescribeService(ctx, &apprunner.DescribeServiceInput{
+		ServiceArn: &p.config.ARN,
+	})
+	if err != nil {
+		return clouds.ServiceStatus{}, fmt.Errorf("describing App Runner service: %w", err)
+	}
+
+	service := describeOutput.Service
+	if service == nil {
+		return clouds.ServiceStatus{}, fmt.Errorf("App Runner service with ARN %s not found", p.config.ARN)
+	}
+
+	status := clouds.ServiceStatus{
+		Status:       string(service.Status),
+		LastDeployAt: aws_sdk.ToTime(service.UpdatedAt),
+	}
+	if service.ServiceUrl != nil {
+		status.URL = fmt.Sprintf("https://%s", *service.ServiceUrl)
+	}
+	if service.SourceConfiguration != nil && service.SourceConfiguration.ImageRepository != nil {
+		status.ImageRef = aws_sdk.ToString(service.SourceConfiguration.ImageRepository.ImageIdentifier)
+	}
+
+	return status, nil
+}
+
+// GetRuntimePlatform reports the platform the service runs on. App Runner only runs linux/amd64 images.
+func (p *AppRunnerProvider) GetRuntimePlatform(ctx context.Context) (lib.Platform, error) {
+	return lib.PlatformLinuxAmd64, nil
+}
+
+// GetPreviousImageRef always fails with PreviousImageNotFoundError. The App Runner operations only hold their type,
+// status and timestamps, not the source configuration, so the service keeps no image history. Rollbacks of App Runner
+// services go back to the previous image recorded in the lockfile instead.
+func (p *AppRunnerProvider) GetPreviousImageRef(ctx context.Context) (string, error) {
+	return "", fmt.Errorf("%w - App Runner keeps no image history for service %s, the previous image is taken from the lockfile", clouds.PreviousImageNotFoundError, p.config.ARN)
+}
@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"log/slog"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	aws_sdk "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	aws_config "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
@@ -43,38 +45,10 @@ func (p *EcsProvider) DeployServiceFromImage(ctx context.Context, registry cloud
 	// get default task definition
 	// create new task definition revision with updated image
 	// update service to use new task definition revision
-	serviceArn, err := arn.Parse(p.config.ARN)
+	service, err := p.describeService(ctx)
 	if err != nil {
-		return fmt.Errorf("parsing ECS service ARN: %w", err)
+		return err
 	}
-	serviceResourceParts := strings.Split(serviceArn.Resource, "/")
-	if len(serviceResourceParts) != 3 {
-		slog.ErrorContext(ctx, "invalid ECS service ARN",
-			"arn", p.config.ARN,
-			"resource", serviceArn.Resource)
-		return fmt.Errorf("%w - invalid ECS service ARN: %s", lib.BadUserInputError, p.config.ARN)
-	}
-	slog.DebugContext(ctx, "parsed ECS service ARN",
-		"arn", p.config.ARN,
-		"cluster", serviceResourceParts[1],
-		"service", serviceResourceParts[2])
-
-	cluster := serviceResourceParts[1]
-	serviceName := serviceResourceParts[2]
-	services, err := p.ecs.DescribeServices(ctx, &ecs.DescribeServicesInput{
-		Services: []string{serviceName},
-		Cluster:  &cluster,
-	})
-	if err != nil {
-		return fmt.Errorf("describing ECS services: %s", err)
-	}
-	serviceIdx := slices.IndexFunc(services.Services, func(s types.Service) bool {
-		return *s.ServiceArn == p.config.ARN
-	})
-	if serviceIdx == -1 {
-		return fmt.Errorf("ECS service with ARN %s not found", p.config.ARN)
-	}
-	service := services.Services[serviceIdx]
 
 	taskDefOutput, err := p.ecs.DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{
 		TaskDefinition: service.TaskDefinition,
@@ -96,16 +70,9 @@ func (p *EcsProvider) DeployServiceFromImage(ctx context.Context, registry cloud
 		return fmt.Errorf("image reference is empty for service %s", p.config.ARN)
 	}
 
-	containerName := "default"
-	if p.config.ContainerName != nil {
-		containerName = *p.config.ContainerName
-	}
-
-	containerIdx := slices.IndexFunc(newContainerDefs, func(c types.ContainerDefinition) bool {
-		return *c.Name == containerName
-	})
-	if containerIdx < 0 {
-		return fmt.Errorf("%w - container %s not found in task definition", lib.BadUserInputError, containerName)
+	containerIdx, err := p.findContainerIndex(newContainerDefs)
+	if err != nil {
+		return err
 	}
 	newContainerDefs[containerIdx].Image = &imageRef
 
@@ -175,3 +142,281 @@ func (p *EcsProvider) DeployServiceFromImage(ctx context.Context, registry cloud
 
 	return nil
 }
+
+func (p *EcsProvider) PlanDeployServiceFromImage(ctx context.Context, registry clouds.ImageRegistry) (clouds.DeployPlan, error) {
+	var plan clouds.DeployPlan
+
+	imageRef, err := registry.GetImageRef()
+	if err != nil {
+		return plan, fmt.Errorf("getting image reference for service %s: %w", p.config.ARN, err)
+	}
+	if imageRef == "" {
+		return plan, fmt.Errorf("image reference is empty for service %s", p.config.ARN)
+	}
+
+	service, err := p.describeService(ctx)
+	if err != nil {
+		return plan, err
+	}
+
+	taskDefOutput, err := p.ecs.DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: service.TaskDefinition,
+	})
+	if err != nil {
+		return plan, fmt.Errorf("error describing ECS task definition: %s", err)
+	}
+	taskDef := taskDefOutput.TaskDefinition
+
+	containerIdx, err := p.findContainerIndex(taskDef.ContainerDefinitions)
+	if err != nil {
+		return plan, err
+	}
+	container := taskDef.ContainerDefinitions[containerIdx]
+
+	latestRevision, err := p.getLatestTaskDefinitionRevision(ctx, aws_sdk.ToString(taskDef.Family))
+	if err != nil {
+		return plan, err
+	}
+	currentTaskDef := fmt.Sprintf("%s:%d", aws_sdk.ToString(taskDef.Family), taskDef.Revision)
+	newTaskDef := fmt.Sprintf("%s:%d", aws_sdk.ToString(taskDef.Family), latestRevision+1)
+
+	plan.Add(fmt.Sprintf("container %s image", aws_sdk.ToString(container.Name)), aws_sdk.ToString(container.Image), imageRef)
+	plan.Add("task definition revision registered", "", newTaskDef)
+	plan.Add(fmt.Sprintf("service %s task definition", aws_sdk.ToString(service.ServiceName)), currentTaskDef, newTaskDef)
+
+	return plan, nil
+}
+
+func (p *EcsProvider) GetServiceStatus(ctx context.Context) (clouds.ServiceStatus, error) {
+	service, err := p.describeService(ctx)
+	if err != nil {
+		return clouds.ServiceStatus{}, err
+	}
+
+	taskDefOutput, err := p.ecs.DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: service.TaskDefinition,
+	})
+	if err != nil {
+		return clouds.ServiceStatus{}, fmt.Errorf("error describing ECS task definition: %s", err)
+	}
+	containerIdx, err := p.findContainerIndex(taskDefOutput.TaskDefinition.ContainerDefinitions)
+	if err != nil {
+		return clouds.ServiceStatus{}, err
+	}
+
+	status := clouds.ServiceStatus{
+		ImageRef: aws_sdk.ToString(taskDefOutput.TaskDefinition.ContainerDefinitions[containerIdx].Image),
+		Status:   aws_sdk.ToString(service.Status),
+	}
+
+	primaryIdx := slices.IndexFunc(service.Deployments, func(d types.Deployment) bool {
+		return aws_sdk.ToString(d.Status) == "PRIMARY"
+	})
+	if primaryIdx >= 0 {
+		primary := service.Deployments[primaryIdx]
+		status.Status = fmt.Sprintf("%s (rollout %s, %d/%d running)", status.Status, primary.RolloutState, primary.RunningCount, primary.DesiredCount)
+		status.LastDeployAt = aws_sdk.ToTime(primary.CreatedAt)
+	}
+
+	return status, nil
+}
+
+// GetRuntimePlatform reads the runtime platform of the task definition the service runs.
+// Task definitions without a runtime platform run on linux/amd64.
+func (p *EcsProvider) GetRuntimePlatform(ctx context.Context) (lib.Platform, error) {
+	service, err := p.describeService(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	taskDefOutput, err := p.ecs.DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: service.TaskDefinition,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error describing ECS task definition: %s", err)
+	}
+
+	return runtimePlatform(taskDefOutput.TaskDefinition.RuntimePlatform), nil
+}
+
+func runtimePlatform(platform *types.RuntimePlatform) lib.Platform {
+	if platform == nil {
+		return lib.PlatformLinuxAmd64
+	}
+
+	operatingSystem := "linux"
+	if platform.OperatingSystemFamily != "" && platform.OperatingSystemFamily != types.OSFamilyLinux {
+		operatingSystem = "windows"
+	}
+	architecture := "amd64"
+	if platform.CpuArchitecture == types.CPUArchitectureArm64 {
+		architecture = "arm64"
+	}
+	return lib.Platform(operatingSystem + "/" + architecture)
+}
+
+// GetPreviousImageRef walks back through the task definition family revisions
+// and returns the first image that differs from the one currently deployed.
+func (p *EcsProvider) GetPreviousImageRef(ctx context.Context) (string, error) {
+	service, err := p.describeService(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	taskDefOutput, err := p.ecs.DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: service.TaskDefinition,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error describing ECS task definition: %s", err)
+	}
+	currentTaskDef := taskDefOutput.TaskDefinition
+	containerIdx, err := p.findContainerIndex(currentTaskDef.ContainerDefinitions)
+	if err != nil {
+		return "", err
+	}
+	currentImage := aws_sdk.ToString(currentTaskDef.ContainerDefinitions[containerIdx].Image)
+
+	paginator := ecs.NewListTaskDefinitionsPaginator(p.ecs, &ecs.ListTaskDefinitionsInput{
+		FamilyPrefix: currentTaskDef.Family,
+		Sort:         types.SortOrderDesc,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", fmt.Errorf("listing ECS task definitions: %w", err)
+		}
+
+		for _, taskDefArn := range page.TaskDefinitionArns {
+			family, revision, err := parseTaskDefinitionArn(taskDefArn)
+			if err != nil {
+				return "", err
+			}
+			// Family prefix also matches families like "<family>-worker", so the exact family must be checked
+			if family != aws_sdk.ToString(currentTaskDef.Family) || revision >= currentTaskDef.Revision {
+				continue
+			}
+
+			revisionOutput, err := p.ecs.DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{
+				TaskDefinition: &taskDefArn,
+			})
+			if err != nil {
+				return "", fmt.Errorf("error describing ECS task definition %s: %s", taskDefArn, err)
+			}
+			revisionContainerIdx, err := p.findContainerIndex(revisionOutput.TaskDefinition.ContainerDefinitions)
+			if err != nil {
+				slog.DebugContext(ctx, "skipping task definition revision without target container",
+					"task_definition", taskDefArn,
+					"error", err)
+				continue
+			}
+
+			revisionImage := aws_sdk.ToString(revisionOutput.TaskDefinition.ContainerDefinitions[revisionContainerIdx].Image)
+			if revisionImage != "" && revisionImage != currentImage {
+				slog.DebugContext(ctx, "found previous ECS task definition revision",
+					"task_definition", taskDefArn,
+					"image", revisionImage)
+				return revisionImage, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("%w - no earlier revision of task definition family %s runs an image other than %s", clouds.PreviousImageNotFoundError, aws_sdk.ToString(currentTaskDef.Family), currentImage)
+}
+
+func (p *EcsProvider) getLatestTaskDefinitionRevision(ctx context.Context, family string) (int32, error) {
+	paginator := ecs.NewListTaskDefinitionsPaginator(p.ecs, &ecs.ListTaskDefinitionsInput{
+		FamilyPrefix: &family,
+		Sort:         types.SortOrderDesc,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("listing ECS task definitions: %w", err)
+		}
+
+		for _, taskDefArn := range page.TaskDefinitionArns {
+			taskDefFamily, revision, err := parseTaskDefinitionArn(taskDefArn)
+			if err != nil {
+				return 0, err
+			}
+			if taskDefFamily == family {
+				return revision, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("no revisions found for ECS task definition family %s", family)
+}
+
+func (p *EcsProvider) describeService(ctx context.Context) (types.Service, error) {
+	serviceArn, err := arn.Parse(p.config.ARN)
+	if err != nil {
+		return types.Service{}, fmt.Errorf("parsing ECS service ARN: %w", err)
+	}
+	serviceResourceParts := strings.Split(serviceArn.Resource, "/")
+	if len(serviceResourceParts) != 3 {
+		slog.ErrorContext(ctx, "invalid ECS service ARN",
+			"arn", p.config.ARN,
+			"resource", serviceArn.Resource)
+		return types.Service{}, fmt.Errorf("%w - invalid ECS service ARN: %s", lib.BadUserInputError, p.config.ARN)
+	}
+	slog.DebugContext(ctx, "parsed ECS service ARN",
+		"arn", p.config.ARN,
+		"cluster", serviceResourceParts[1],
+		"service", serviceResourceParts[2])
+
+	cluster := serviceResourceParts[1]
+	serviceName := serviceResourceParts[2]
+	services, err := p.ecs.DescribeServices(ctx, &ecs.DescribeServicesInput{
+		Services: []string{serviceName},
+		Cluster:  &cluster,
+	})
+	if err != nil {
+		return types.Service{}, fmt.Errorf("describing ECS services: %s", err)
+	}
+	serviceIdx := slices.IndexFunc(services.Services, func(s types.Service) bool {
+		return *s.ServiceArn == p.config.ARN
+	})
+	if serviceIdx == -1 {
+		return types.Service{}, fmt.Errorf("ECS service with ARN %s not found", p.config.ARN)
+	}
+
+	return services.Services[serviceIdx], nil
+}
+
+func (p *EcsProvider) findContainerIndex(containerDefs []types.ContainerDefinition) (int, error) {
+	containerName := "default"
+	if p.config.ContainerName != nil {
+		containerName = *p.config.ContainerName
+	}
+
+	containerIdx := slices.IndexFunc(containerDefs, func(c types.ContainerDefinition) bool {
+		return *c.Name == containerName
+	})
+	if containerIdx < 0 {
+		return -1, fmt.Errorf("%w - container %s not found in task definition", lib.BadUserInputError, containerName)
+	}
+
+	return containerIdx, nil
+}
+
+// parseTaskDefinitionArn splits arn:aws:ecs:<region>:<account>:task-definition/<family>:<revision>
+func parseTaskDefinitionArn(taskDefArn string) (string, int32, error) {
+	parsedArn, err := arn.Parse(taskDefArn)
+	if err != nil {
+		return "", 0, fmt.Errorf("parsing ECS task definition ARN %s: %w", taskDefArn, err)
+	}
+
+	familyAndRevision := strings.TrimPrefix(parsedArn.Resource, "task-definition/")
+	family, revision, ok := strings.Cut(familyAndRevision, ":")
+	if !ok {
+		return "", 0, fmt.Errorf("invalid ECS task definition ARN: %s", taskDefArn)
+	}
+
+	parsedRevision, err := strconv.ParseInt(revision, 10, 32)
+	if err != nil {
+		return "", 0, fmt.Errorf("parsing revision of ECS task definition ARN %s: %w", taskDefArn, err)
+	}
+
+	return family, int32(parsedRevision), nil
+}
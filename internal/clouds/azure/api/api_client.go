@@ -0,0 +1,24 @@
+package api
+
+import (
+	"net/url"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+)
+
+const containerAppsApiVersion = "2024-03-01"
+
+type Client struct {
+	*lib.ApiClient
+}
+
+func MustNewClient(baseURL, accessToken string) *Client {
+	client := lib.MustNewProtectedApiClient(baseURL, accessToken)
+	return &Client{ApiClient: client}
+}
+
+func apiVersionQuery() url.Values {
+	query := url.Values{}
+	query.Set("api-version", containerAppsApiVersion)
+	return query
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/azure/api/containerapps"
+)
+
+func containerAppPath(id containerapps.ContainerAppID) string {
+	return "/subscriptions/" + id.SubscriptionID + "/resourceGroups/" + id.ResourceGroup + "/providers/Microsoft.App/containerApps/" + id.Name
+}
+
+func (c *Client) RetrieveContainerApp(ctx context.Context, id containerapps.ContainerAppID) (containerapps.ContainerApp, error) {
+	var app containerapps.ContainerApp
+	resp, err := c.NewGetRequest(ctx, c.URLWithQuery(apiVersionQuery(), containerAppPath(id))).WriteBodyTo(&app).Do()
+	return app, MapResponseToError(resp, err)
+}
+
+// UpdateContainerApp starts the update of the container app. Azure applies it asynchronously,
+// the provisioning state of the app reports when it is done.
+func (c *Client) UpdateContainerApp(ctx context.Context, id containerapps.ContainerAppID, input containerapps.UpdateContainerAppInput) error {
+	resp, err := c.NewPatchRequest(ctx, c.URLWithQuery(apiVersionQuery(), containerAppPath(id)), input).Do()
+	if resp != nil && resp.Body != nil {
+		defer resp.Body.Close()
+	}
+	return MapResponseToError(resp, err)
+}
+
+func (c *Client) RetrieveRevision(ctx context.Context, id containerapps.ContainerAppID, revisionName string) (containerapps.Revision, error) {
+	var revision containerapps.Revision
+	resp, err := c.NewGetRequest(ctx, c.URLWithQueryf(apiVersionQuery(), "%s/revisions/%s", containerAppPath(id), revisionName)).WriteBodyTo(&revision).Do()
+	return revision, MapResponseToError(resp, err)
+}
+
+// ListRevisions returns every revision of the container app following the result pages.
+func (c *Client) ListRevisions(ctx context.Context, id containerapps.ContainerAppID) ([]containerapps.Revision, error) {
+	var revisions []containerapps.Revision
+
+	pageURL := c.URLWithQueryf(apiVersionQuery(), "%s/revisions", containerAppPath(id))
+	for pageURL != "" {
+		var page containerapps.ListRevisionsResponse
+		resp, err := c.NewGetRequest(ctx, pageURL).WriteBodyTo(&page).Do()
+		if err := MapResponseToError(resp, err); err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, page.Value...)
+		pageURL = page.NextLink
+	}
+
+	return revisions, nil
+}
@@ -0,0 +1,168 @@
+package containerapps
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type ContainerAppID struct {
+	SubscriptionID string
+	ResourceGroup  string
+	Name           string
+}
+
+type ProvisioningState string
+
+const (
+	ProvisioningStateInProgress ProvisioningState = "InProgress"
+	ProvisioningStateSucceeded  ProvisioningState = "Succeeded"
+	ProvisioningStateFailed     ProvisioningState = "Failed"
+	ProvisioningStateCanceled   ProvisioningState = "Canceled"
+	ProvisioningStateDeleting   ProvisioningState = "Deleting"
+)
+
+func (s ProvisioningState) IsTerminal() bool {
+	switch s {
+	case ProvisioningStateSucceeded, ProvisioningStateFailed, ProvisioningStateCanceled:
+		return true
+	}
+	return false
+}
+
+type RevisionProvisioningState string
+
+const (
+	RevisionProvisioningStateProvisioning   RevisionProvisioningState = "Provisioning"
+	RevisionProvisioningStateProvisioned    RevisionProvisioningState = "Provisioned"
+	RevisionProvisioningStateFailed         RevisionProvisioningState = "Failed"
+	RevisionProvisioningStateDeprovisioning RevisionProvisioningState = "Deprovisioning"
+	RevisionProvisioningStateDeprovisioned  RevisionProvisioningState = "Deprovisioned"
+)
+
+type Container struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+}
+
+// Template is kept as raw JSON because an update replaces the whole template -
+// every field the app already has (env, resources, probes, scale) has to be sent back as is.
+type Template json.RawMessage
+
+func (t Template) MarshalJSON() ([]byte, error) {
+	if len(t) == 0 {
+		return []byte("null"), nil
+	}
+	return t, nil
+}
+
+func (t *Template) UnmarshalJSON(data []byte) error {
+	*t = append((*t)[:0], data...)
+	return nil
+}
+
+func (t Template) Containers() ([]Container, error) {
+	var template struct {
+		Containers []Container `json:"containers"`
+	}
+	if len(t) == 0 {
+		return nil, nil
+	}
+	if err := json.Unmarshal(t, &template); err != nil {
+		return nil, fmt.Errorf("decoding template containers: %w", err)
+	}
+	return template.Containers, nil
+}
+
+// WithContainerImage returns a copy of the template where the image of the named container is replaced
+// and the revision is named with the suffix.
+func (t Template) WithContainerImage(containerName, image, revisionSuffix string) (Template, error) {
+	var template map[string]any
+	if err := json.Unmarshal(t, &template); err != nil {
+		return nil, fmt.Errorf("decoding template: %w", err)
+	}
+
+	containers, _ := template["containers"].([]any)
+	found := false
+	for _, c := range containers {
+		container, ok := c.(map[string]any)
+		if !ok || container["name"] != containerName {
+			continue
+		}
+		container["image"] = image
+		found = true
+	}
+	if !found {
+		return nil, fmt.Errorf("container %s not found in template", containerName)
+	}
+	// The suffix names the revision, reusing the previous one would make the update fail,
+	// a new one makes Azure create a revision even when the template is otherwise unchanged
+	template["revisionSuffix"] = revisionSuffix
+
+	updated, err := json.Marshal(template)
+	if err != nil {
+		return nil, fmt.Errorf("encoding template: %w", err)
+	}
+	return updated, nil
+}
+
+type Ingress struct {
+	Fqdn string `json:"fqdn"`
+}
+
+type Configuration struct {
+	ActiveRevisionsMode string   `json:"activeRevisionsMode"`
+	Ingress             *Ingress `json:"ingress"`
+}
+
+type ContainerAppProperties struct {
+	ProvisioningState       ProvisioningState `json:"provisioningState"`
+	RunningStatus           string            `json:"runningStatus"`
+	LatestRevisionName      string            `json:"latestRevisionName"`
+	LatestReadyRevisionName string            `json:"latestReadyRevisionName"`
+	LatestRevisionFqdn      string            `json:"latestRevisionFqdn"`
+	Configuration           Configuration     `json:"configuration"`
+	Template                Template          `json:"template"`
+}
+
+type SystemData struct {
+	CreatedAt      time.Time `json:"createdAt"`
+	LastModifiedAt time.Time `json:"lastModifiedAt"`
+}
+
+type ContainerApp struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	Location   string                 `json:"location"`
+	Properties ContainerAppProperties `json:"properties"`
+	SystemData SystemData             `json:"systemData"`
+}
+
+type UpdateContainerAppProperties struct {
+	Template Template `json:"template"`
+}
+
+type UpdateContainerAppInput struct {
+	Properties UpdateContainerAppProperties `json:"properties"`
+}
+
+type RevisionProperties struct {
+	CreatedTime       time.Time                 `json:"createdTime"`
+	Active            bool                      `json:"active"`
+	ProvisioningState RevisionProvisioningState `json:"provisioningState"`
+	ProvisioningError string                    `json:"provisioningError"`
+	HealthState       string                    `json:"healthState"`
+	RunningState      string                    `json:"runningState"`
+	Template          Template                  `json:"template"`
+}
+
+type Revision struct {
+	ID         string             `json:"id"`
+	Name       string             `json:"name"`
+	Properties RevisionProperties `json:"properties"`
+}
+
+type ListRevisionsResponse struct {
+	Value    []Revision `json:"value"`
+	NextLink string     `json:"nextLink"`
+}
@@ -0,0 +1,8 @@
+package azure
+
+type ContainerAppsConfig struct {
+	SubscriptionID string  `mapstructure:"subscription_id"`
+	ResourceGroup  string  `mapstructure:"resource_group"`
+	Name           string  `mapstructure:"name"`
+	ContainerName  *string `mapstructure:"container_name"`
+}
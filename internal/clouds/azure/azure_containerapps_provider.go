@@ -0,0 +1,351 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/azure/api"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/azure/api/containerapps"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/azure/identity"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+)
+
+const azureManagementURL = "https://management.azure.com"
+
+type ContainerAppsProvider struct {
+	config               ContainerAppsConfig
+	appID                containerapps.ContainerAppID
+	api                  *api.Client
+	storage              lib.CredentialsStorage
+	revisionPollInterval time.Duration
+	revisionTimeout      time.Duration
+}
+
+func NewContainerAppsProvider(config ContainerAppsConfig, storage lib.CredentialsStorage, envKeys identity.EnvKeys) (*ContainerAppsProvider, error) {
+	if err := validateContainerAppsConfig(config); err != nil {
+		return nil, err
+	}
+
+	sp, err := identity.GetServicePrincipal(storage, envKeys)
+	if err != nil {
+		return nil, fmt.Errorf("getting Azure credentials: %w", err)
+	}
+
+	accessToken, err := identity.GetToken(context.Background(), identity.DefaultAuthorityURL, sp, identity.ManagementScope)
+	if err != nil {
+		if errors.Is(err, identity.UnauthorizedError) {
+			identity.ResetServicePrincipal(storage)
+		}
+		return nil, fmt.Errorf("getting Azure access token: %w", err)
+	}
+
+	return newContainerAppsProvider(config, api.MustNewClient(azureManagementURL, accessToken), storage), nil
+}
+
+func newContainerAppsProvider(config ContainerAppsConfig, apiClient *api.Client, storage lib.CredentialsStorage) *ContainerAppsProvider {
+	return &ContainerAppsProvider{
+		config: config,
+		appID: containerapps.ContainerAppID{
+			SubscriptionID: config.SubscriptionID,
+			ResourceGroup:  config.ResourceGroup,
+			Name:           config.Name,
+		},
+		api:                  apiClient,
+		storage:              storage,
+		revisionPollInterval: 5 * time.Second,
+		revisionTimeout:      15 * time.Minute,
+	}
+}
+
+func validateContainerAppsConfig(config ContainerAppsConfig) error {
+	if config.SubscriptionID == "" {
+		return fmt.Errorf("%w - Azure subscription ID is required", lib.BadUserInputError)
+	}
+	if config.ResourceGroup == "" {
+		return fmt.Errorf("%w - Azure resource group is required", lib.BadUserInputError)
+	}
+	if config.Name == "" {
+		return fmt.Errorf("%w - Azure Container App name is required", lib.BadUserInputError)
+	}
+	return nil
+}
+
+func (p *ContainerAppsProvider) DeployServiceFromImage(ctx context.Context, registry clouds.ImageRegistry) error {
+	imageRef, err := registry.GetImageRef()
+	if err != nil {
+		return fmt.Errorf("getting image reference for container app %s: %w", p.config.Name, err)
+	}
+	if imageRef == "" {
+		return fmt.Errorf("image reference is empty for container app %s", p.config.Name)
+	}
+
+	app, err := p.retrieveContainerApp(ctx)
+	if err != nil {
+		return err
+	}
+
+	container, err := p.findContainer(app.Properties.Template)
+	if err != nil {
+		return err
+	}
+	if container.Image == imageRef {
+		// The tag may point to new content, a new revision pulls it again like a deploy of another image does
+		slog.InfoContext(ctx, "container app already runs the image reference, creating a new revision",
+			"app", p.config.Name,
+			"image", imageRef)
+	}
+
+	template, err := app.Properties.Template.WithContainerImage(container.Name, imageRef, newRevisionSuffix())
+	if err != nil {
+		return fmt.Errorf("updating template of container app %s: %w", p.config.Name, err)
+	}
+
+	slog.InfoContext(ctx, "updating container app image",
+		"app", p.config.Name,
+		"container", container.Name,
+		"from", container.Image,
+		"to", imageRef)
+
+	err = p.api.UpdateContainerApp(ctx, p.appID, containerapps.UpdateContainerAppInput{
+		Properties: containerapps.UpdateContainerAppProperties{Template: template},
+	})
+	if err != nil {
+		p.resetCredentialsOnUnauthorized(err)
+		return fmt.Errorf("updating container app %s: %w", p.config.Name, err)
+	}
+
+	return p.waitForRevision(ctx, app.Properties.LatestRevisionName)
+}
+
+// newRevisionSuffix returns a suffix unique to the deploy. Suffixes are lower case alphanumerics starting with a letter.
+func newRevisionSuffix() string {
+	return "r" + strconv.FormatInt(time.Now().UnixMilli(), 36)
+}
+
+// waitForRevision polls the container app until it reports a revision newer than previousRevision
+// and then waits for that revision to be provisioned.
+func (p *ContainerAppsProvider) waitForRevision(ctx context.Context, previousRevision string) error {
+	slog.InfoContext(ctx, "waiting for container app revision to be provisioned",
+		"app", p.config.Name,
+		"previous_revision", previousRevision)
+
+	waitCtx, cancel := context.WithTimeout(ctx, p.revisionTimeout)
+	defer cancel()
+
+	waitTicker := time.NewTicker(p.revisionPollInterval)
+	defer waitTicker.Stop()
+
+	var lastStatus string
+	for {
+		app, err := p.api.RetrieveContainerApp(waitCtx, p.appID)
+		if err != nil {
+			p.resetCredentialsOnUnauthorized(err)
+			return fmt.Errorf("retrieving container app %s: %w", p.config.Name, err)
+		}
+
+		appState := app.Properties.ProvisioningState
+		if appState == containerapps.ProvisioningStateFailed || appState == containerapps.ProvisioningStateCanceled {
+			return fmt.Errorf("container app %s update finished with provisioning state %s", p.config.Name, appState)
+		}
+
+		revisionName := app.Properties.LatestRevisionName
+		status := string(appState)
+		if revisionName != "" && revisionName != previousRevision {
+			revision, err := p.api.RetrieveRevision(waitCtx, p.appID, revisionName)
+			if err != nil {
+				p.resetCredentialsOnUnauthorized(err)
+				return fmt.Errorf("retrieving revision %s of container app %s: %w", revisionName, p.config.Name, err)
+			}
+			status = fmt.Sprintf("%s/%s", revisionName, revision.Properties.ProvisioningState)
+
+			if status != lastStatus {
+				slog.InfoContext(ctx, "container app revision status changed",
+					"app", p.config.Name,
+					"revision", revisionName,
+					"from", lastStatus,
+					"to", status)
+				lastStatus = status
+			}
+
+			switch revision.Properties.ProvisioningState {
+			case containerapps.RevisionProvisioningStateProvisioned:
+				slog.InfoContext(ctx, "container app revision provisioned",
+					"app", p.config.Name,
+					"revision", revisionName,
+					"fqdn", app.Properties.LatestRevisionFqdn)
+				return nil
+			case containerapps.RevisionProvisioningStateFailed:
+				return fmt.Errorf("revision %s of container app %s failed to provision: %s", revisionName, p.config.Name, revision.Properties.ProvisioningError)
+			}
+		} else if status != lastStatus {
+			slog.InfoContext(ctx, "container app status changed",
+				"app", p.config.Name,
+				"from", lastStatus,
+				"to", status)
+			lastStatus = status
+		}
+
+		select {
+		case <-waitCtx.Done():
+			return fmt.Errorf("waiting for container app %s revision to be provisioned: %w", p.config.Name, waitCtx.Err())
+		case <-waitTicker.C:
+		}
+	}
+}
+
+func (p *ContainerAppsProvider) PlanDeployServiceFromImage(ctx context.Context, registry clouds.ImageRegistry) (clouds.DeployPlan, error) {
+	var plan clouds.DeployPlan
+
+	imageRef, err := registry.GetImageRef()
+	if err != nil {
+		return plan, fmt.Errorf("getting image reference for container app %s: %w", p.config.Name, err)
+	}
+	if imageRef == "" {
+		return plan, fmt.Errorf("image reference is empty for container app %s", p.config.Name)
+	}
+
+	app, err := p.retrieveContainerApp(ctx)
+	if err != nil {
+		return plan, err
+	}
+
+	container, err := p.findContainer(app.Properties.Template)
+	if err != nil {
+		return plan, err
+	}
+
+	plan.Add(fmt.Sprintf("container %s image", container.Name), container.Image, imageRef)
+	plan.Add("latest revision", app.Properties.LatestRevisionName, "new revision")
+
+	return plan, nil
+}
+
+func (p *ContainerAppsProvider) GetServiceStatus(ctx context.Context) (clouds.ServiceStatus, error) {
+	app, err := p.retrieveContainerApp(ctx)
+	if err != nil {
+		return clouds.ServiceStatus{}, err
+	}
+
+	status := clouds.ServiceStatus{
+		Status:       string(app.Properties.ProvisioningState),
+		LastDeployAt: app.SystemData.LastModifiedAt,
+	}
+	if app.Properties.ProvisioningState == containerapps.ProvisioningStateSucceeded && app.Properties.RunningStatus != "" {
+		status.Status = app.Properties.RunningStatus
+	}
+	if ingress := app.Properties.Configuration.Ingress; ingress != nil && ingress.Fqdn != "" {
+		status.URL = fmt.Sprintf("https://%s", ingress.Fqdn)
+	}
+	if container, err := p.findContainer(app.Properties.Template); err == nil {
+		status.ImageRef = container.Image
+	}
+
+	return status, nil
+}
+
+// GetRuntimePlatform reports the platform the service runs on. Container Apps only run linux/amd64 images.
+func (p *ContainerAppsProvider) GetRuntimePlatform(ctx context.Context) (lib.Platform, error) {
+	return lib.PlatformLinuxAmd64, nil
+}
+
+// GetPreviousImageRef looks through the provisioned revisions created before the latest ready one
+// and returns the newest image that differs from the currently deployed image.
+func (p *ContainerAppsProvider) GetPreviousImageRef(ctx context.Context) (string, error) {
+	app, err := p.retrieveContainerApp(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	revisions, err := p.api.ListRevisions(ctx, p.appID)
+	if err != nil {
+		p.resetCredentialsOnUnauthorized(err)
+		return "", fmt.Errorf("listing revisions of container app %s: %w", p.config.Name, err)
+	}
+
+	currentIndex := slices.IndexFunc(revisions, func(revision containerapps.Revision) bool {
+		return revision.Name == app.Properties.LatestReadyRevisionName
+	})
+	if currentIndex < 0 {
+		return "", fmt.Errorf("%w - latest ready revision of container app %s not found", clouds.PreviousImageNotFoundError, p.config.Name)
+	}
+	currentRevision := revisions[currentIndex]
+
+	currentContainer, err := p.findContainer(currentRevision.Properties.Template)
+	if err != nil {
+		return "", err
+	}
+
+	slices.SortFunc(revisions, func(a, b containerapps.Revision) int {
+		return b.Properties.CreatedTime.Compare(a.Properties.CreatedTime)
+	})
+
+	for _, revision := range revisions {
+		if !revision.Properties.CreatedTime.Before(currentRevision.Properties.CreatedTime) {
+			continue
+		}
+		if revision.Properties.ProvisioningState == containerapps.RevisionProvisioningStateFailed {
+			continue
+		}
+
+		container, err := p.findContainer(revision.Properties.Template)
+		if err != nil {
+			slog.DebugContext(ctx, "skipping revision without the container", "revision", revision.Name, "error", err)
+			continue
+		}
+		if container.Image != "" && container.Image != currentContainer.Image {
+			slog.DebugContext(ctx, "found previous container app revision",
+				"revision", revision.Name,
+				"image", container.Image)
+			return container.Image, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w - no earlier revision of container app %s runs an image other than %s", clouds.PreviousImageNotFoundError, p.config.Name, currentContainer.Image)
+}
+
+func (p *ContainerAppsProvider) retrieveContainerApp(ctx context.Context) (containerapps.ContainerApp, error) {
+	app, err := p.api.RetrieveContainerApp(ctx, p.appID)
+	if err != nil {
+		p.resetCredentialsOnUnauthorized(err)
+		return app, fmt.Errorf("retrieving container app %s in resource group %s: %w", p.config.Name, p.config.ResourceGroup, err)
+	}
+	return app, nil
+}
+
+// findContainer returns the configured container, or the only one when the template has a single container.
+func (p *ContainerAppsProvider) findContainer(template containerapps.Template) (containerapps.Container, error) {
+	containers, err := template.Containers()
+	if err != nil {
+		return containerapps.Container{}, fmt.Errorf("reading containers of container app %s: %w", p.config.Name, err)
+	}
+	if len(containers) == 0 {
+		return containerapps.Container{}, fmt.Errorf("%w - container app %s has no containers configured", lib.BadUserInputError, p.config.Name)
+	}
+
+	if p.config.ContainerName == nil {
+		if len(containers) > 1 {
+			return containerapps.Container{}, fmt.Errorf("%w - container app %s has %d containers, container_name is required", lib.BadUserInputError, p.config.Name, len(containers))
+		}
+		return containers[0], nil
+	}
+
+	for _, container := range containers {
+		if container.Name == *p.config.ContainerName {
+			return container, nil
+		}
+	}
+
+	return containerapps.Container{}, fmt.Errorf("%w - container %s not found in container app %s", lib.BadUserInputError, *p.config.ContainerName, p.config.Name)
+}
+
+func (p *ContainerAppsProvider) resetCredentialsOnUnauthorized(err error) {
+	if errors.Is(err, api.UnauthorizedError) {
+		identity.ResetServicePrincipal(p.storage)
+	}
+}
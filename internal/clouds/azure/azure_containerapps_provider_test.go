@@ -0,0 +1,268 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/azure/api"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const testAppPath = "/subscriptions/sub-1/resourceGroups/rg-1/providers/Microsoft.App/containerApps/api"
+
+type standInRevision struct {
+	name      string
+	image     string
+	createdAt string
+	state     string
+}
+
+// armStandIn is a minimal stand-in of the Azure Resource Manager Container Apps API.
+type armStandIn struct {
+	mu                 sync.Mutex
+	image              string
+	latestRevision     string
+	revisionStates     []string
+	revisions          []standInRevision
+	patchedTemplates   []map[string]any
+	provisioningStates []string
+}
+
+func (s *armStandIn) template(image string) map[string]any {
+	return map[string]any{
+		"revisionSuffix": "v1",
+		"containers": []map[string]any{
+			{"name": "api", "image": image, "env": []map[string]any{{"name": "PORT", "value": "8080"}}},
+		},
+		"scale": map[string]any{"minReplicas": 1, "maxReplicas": 3},
+	}
+}
+
+func (s *armStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Query().Get("api-version") == "" {
+		http.Error(w, "missing api-version", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case r.URL.Path == testAppPath && r.Method == http.MethodGet:
+		state := "Succeeded"
+		if len(s.provisioningStates) > 0 {
+			state = s.provisioningStates[0]
+			s.provisioningStates = s.provisioningStates[1:]
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":   testAppPath,
+			"name": "api",
+			"properties": map[string]any{
+				"provisioningState":       state,
+				"runningStatus":           "Running",
+				"latestRevisionName":      s.latestRevision,
+				"latestReadyRevisionName": s.latestRevision,
+				"configuration":           map[string]any{"ingress": map[string]any{"fqdn": "api.example.azurecontainerapps.io"}},
+				"template":                s.template(s.image),
+			},
+			"systemData": map[string]any{"lastModifiedAt": "2025-01-02T10:00:00Z"},
+		})
+	case r.URL.Path == testAppPath && r.Method == http.MethodPatch:
+		var input struct {
+			Properties struct {
+				Template map[string]any `json:"template"`
+			} `json:"properties"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.patchedTemplates = append(s.patchedTemplates, input.Properties.Template)
+		s.image = input.Properties.Template["containers"].([]any)[0].(map[string]any)["image"].(string)
+		s.latestRevision = "api--new"
+		s.provisioningStates = []string{"InProgress", "Succeeded"}
+		w.WriteHeader(http.StatusAccepted)
+	case r.URL.Path == testAppPath+"/revisions" && r.Method == http.MethodGet:
+		values := make([]map[string]any, 0, len(s.revisions))
+		for _, revision := range s.revisions {
+			values = append(values, map[string]any{
+				"name": revision.name,
+				"properties": map[string]any{
+					"createdTime":       revision.createdAt,
+					"provisioningState": revision.state,
+					"template":          s.template(revision.image),
+				},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"value": values})
+	case strings.HasPrefix(r.URL.Path, testAppPath+"/revisions/") && r.Method == http.MethodGet:
+		state := s.revisionStates[0]
+		if len(s.revisionStates) > 1 {
+			s.revisionStates = s.revisionStates[1:]
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"name": strings.TrimPrefix(r.URL.Path, testAppPath+"/revisions/"),
+			"properties": map[string]any{
+				"provisioningState": state,
+				"provisioningError": "container crashed",
+			},
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestProvider(t *testing.T, handler http.Handler) *ContainerAppsProvider {
+	server := testutil.NewServer(t, handler)
+
+	p := newContainerAppsProvider(ContainerAppsConfig{
+		SubscriptionID: "sub-1",
+		ResourceGroup:  "rg-1",
+		Name:           "api",
+	}, api.MustNewClient(server.URL, "token"), testutil.NewMemoryCredentialsStorage(map[string]string{"azure_client_secret": "secret"}))
+	p.revisionPollInterval = time.Millisecond
+
+	return p
+}
+
+func TestContainerAppsProvider_DeployServiceFromImage(t *testing.T) {
+	t.Parallel()
+
+	t.Run("updates the template and waits for the new revision", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		standIn := &armStandIn{
+			image:          "acr.azurecr.io/api:v1",
+			latestRevision: "api--v1",
+			revisionStates: []string{"Provisioning", "Provisioned"},
+		}
+		p := newTestProvider(t, standIn)
+
+		r.NoError(p.DeployServiceFromImage(context.Background(), clouds.ImageRef("acr.azurecr.io/api:v2")))
+		r.Len(standIn.patchedTemplates, 1)
+
+		template := standIn.patchedTemplates[0]
+		r.NotEmpty(template["revisionSuffix"])
+		r.NotEqual("v1", template["revisionSuffix"])
+		r.Equal(map[string]any{"minReplicas": float64(1), "maxReplicas": float64(3)}, template["scale"])
+		container := template["containers"].([]any)[0].(map[string]any)
+		r.Equal("acr.azurecr.io/api:v2", container["image"])
+		r.NotEmpty(container["env"])
+	})
+
+	t.Run("creates a new revision when the image did not change", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		standIn := &armStandIn{
+			image:          "acr.azurecr.io/api:v1",
+			latestRevision: "api--v1",
+			revisionStates: []string{"Provisioning", "Provisioned"},
+		}
+		p := newTestProvider(t, standIn)
+
+		r.NoError(p.DeployServiceFromImage(context.Background(), clouds.ImageRef("acr.azurecr.io/api:v1")))
+		r.Len(standIn.patchedTemplates, 1)
+
+		template := standIn.patchedTemplates[0]
+		r.NotEqual("v1", template["revisionSuffix"])
+		container := template["containers"].([]any)[0].(map[string]any)
+		r.Equal("acr.azurecr.io/api:v1", container["image"])
+	})
+
+	t.Run("fails when the revision fails to provision", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		standIn := &armStandIn{
+			image:          "acr.azurecr.io/api:v1",
+			latestRevision: "api--v1",
+			revisionStates: []string{"Provisioning", "Failed"},
+		}
+		p := newTestProvider(t, standIn)
+
+		err := p.DeployServiceFromImage(context.Background(), clouds.ImageRef("acr.azurecr.io/api:v2"))
+		r.ErrorContains(err, "failed to provision: container crashed")
+	})
+
+	t.Run("resets stored credentials when not authorized", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		p := newTestProvider(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+
+		err := p.DeployServiceFromImage(context.Background(), clouds.ImageRef("acr.azurecr.io/api:v2"))
+		r.ErrorIs(err, api.UnauthorizedError)
+
+		secret, err := p.storage.Get("azure_client_secret")
+		r.NoError(err)
+		r.Empty(secret)
+	})
+}
+
+func TestContainerAppsProvider_GetServiceStatus(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	standIn := &armStandIn{image: "acr.azurecr.io/api:v2", latestRevision: "api--v2"}
+	p := newTestProvider(t, standIn)
+
+	status, err := p.GetServiceStatus(context.Background())
+	r.NoError(err)
+	r.Equal("acr.azurecr.io/api:v2", status.ImageRef)
+	r.Equal("Running", status.Status)
+	r.Equal("https://api.example.azurecontainerapps.io", status.URL)
+	r.Equal(time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC), status.LastDeployAt)
+}
+
+func TestContainerAppsProvider_GetPreviousImageRef(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns the image of the newest older revision with a different image", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		standIn := &armStandIn{
+			image:          "acr.azurecr.io/api:v3",
+			latestRevision: "api--v3",
+			revisions: []standInRevision{
+				{name: "api--v1", image: "acr.azurecr.io/api:v1", createdAt: "2025-01-01T10:00:00Z", state: "Provisioned"},
+				{name: "api--v3", image: "acr.azurecr.io/api:v3", createdAt: "2025-01-03T10:00:00Z", state: "Provisioned"},
+				{name: "api--v2", image: "acr.azurecr.io/api:v2", createdAt: "2025-01-02T10:00:00Z", state: "Failed"},
+				{name: "api--v4", image: "acr.azurecr.io/api:v4", createdAt: "2025-01-04T10:00:00Z", state: "Failed"},
+			},
+		}
+		p := newTestProvider(t, standIn)
+
+		imageRef, err := p.GetPreviousImageRef(context.Background())
+		r.NoError(err)
+		r.Equal("acr.azurecr.io/api:v1", imageRef)
+	})
+
+	t.Run("reports missing history", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		standIn := &armStandIn{
+			image:          "acr.azurecr.io/api:v1",
+			latestRevision: "api--v1",
+			revisions: []standInRevision{
+				{name: "api--v1", image: "acr.azurecr.io/api:v1", createdAt: "2025-01-01T10:00:00Z", state: "Provisioned"},
+			},
+		}
+		p := newTestProvider(t, standIn)
+
+		_, err := p.GetPreviousImageRef(context.Background())
+		r.ErrorIs(err, clouds.PreviousImageNotFoundError)
+	})
+}
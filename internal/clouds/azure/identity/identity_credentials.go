@@ -0,0 +1,130 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/AnotherFullstackDev/httpreqx"
+)
+
+const (
+	DefaultAuthorityURL = "https://login.microsoftonline.com"
+	ManagementScope     = "https://management.azure.com/.default"
+
+	tenantIDStorageKey       = "azure_tenant_id"
+	tenantIDStorageLabel     = "Azure Tenant ID"
+	clientIDStorageKey       = "azure_client_id"
+	clientIDStorageLabel     = "Azure Client ID"
+	clientSecretStorageKey   = "azure_client_secret"
+	clientSecretStorageLabel = "Azure Client Secret"
+)
+
+var (
+	UnauthorizedError = errors.New("unauthorized")
+)
+
+// EnvKeys lists the environment variables each part of the service principal is looked up in.
+type EnvKeys struct {
+	TenantID     []string
+	ClientID     []string
+	ClientSecret []string
+}
+
+// ServicePrincipal holds the Microsoft Entra ID application credentials used for the client credentials flow.
+type ServicePrincipal struct {
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// GetServicePrincipal reads the service principal from the storage, the environment or the terminal, in that order.
+func GetServicePrincipal(storage lib.CredentialsStorage, envKeys EnvKeys) (ServicePrincipal, error) {
+	var sp ServicePrincipal
+	var err error
+
+	sp.TenantID, err = lib.GetSecretFromEnvOrInput(storage, tenantIDStorageKey, tenantIDStorageLabel, envKeys.TenantID, os.Stdin, os.Stdout, "Please provide Azure Tenant ID")
+	if err != nil {
+		return sp, fmt.Errorf("requesting azure tenant id: %w", err)
+	}
+	sp.ClientID, err = lib.GetSecretFromEnvOrInput(storage, clientIDStorageKey, clientIDStorageLabel, envKeys.ClientID, os.Stdin, os.Stdout, "Please provide Azure Client ID")
+	if err != nil {
+		return sp, fmt.Errorf("requesting azure client id: %w", err)
+	}
+	sp.ClientSecret, err = lib.GetSecretFromEnvOrInput(storage, clientSecretStorageKey, clientSecretStorageLabel, envKeys.ClientSecret, os.Stdin, os.Stdout, "Please provide Azure Client Secret")
+	if err != nil {
+		return sp, fmt.Errorf("requesting azure client secret: %w", err)
+	}
+
+	return sp, nil
+}
+
+// ResetServicePrincipal removes the stored service principal so the next GetServicePrincipal asks for it again.
+func ResetServicePrincipal(storage lib.CredentialsStorage) error {
+	for _, key := range []string{tenantIDStorageKey, clientIDStorageKey, clientSecretStorageKey} {
+		if err := storage.Remove(key); err != nil {
+			return fmt.Errorf("resetting %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// GetToken requests an access token for the scope with the client credentials flow.
+func GetToken(ctx context.Context, authorityURL string, sp ServicePrincipal, scope string) (string, error) {
+	tokenURL, err := url.JoinPath(authorityURL, sp.TenantID, "oauth2/v2.0/token")
+	if err != nil {
+		return "", fmt.Errorf("building token url: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", sp.ClientID)
+	form.Set("client_secret", sp.ClientSecret)
+	form.Set("scope", scope)
+
+	var token tokenResponse
+	resp, err := PostForm(ctx, tokenURL, form, &token)
+	if err != nil {
+		return "", fmt.Errorf("requesting azure access token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("azure token endpoint returned no access token (status %d)", resp.StatusCode)
+	}
+
+	return token.AccessToken, nil
+}
+
+// PostForm sends an url encoded form and decodes the JSON response into result.
+// 400 and 401 responses are reported as UnauthorizedError since both token endpoints use them for rejected credentials.
+func PostForm(ctx context.Context, endpoint string, form url.Values, result any) (*http.Response, error) {
+	resp, err := httpreqx.NewHttpClient().
+		SetBodyMarshaler(httpreqx.NewNoopBodyMarshaler()).
+		SetBodyUnmarshaler(httpreqx.NewJSONBodyUnmarshaler()).
+		SetHeaders(map[string]string{
+			"Content-Type": "application/x-www-form-urlencoded",
+			"Accept":       "application/json",
+		}).
+		SetStackTraceEnabled(false).
+		NewPostRequest(ctx, endpoint, strings.NewReader(form.Encode())).
+		WriteBodyTo(result).
+		Do()
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized) {
+			return resp, errors.Join(UnauthorizedError, err)
+		}
+		return resp, err
+	}
+
+	return resp, nil
+}
@@ -2,12 +2,58 @@ package clouds
 
 import (
 	"context"
+	"errors"
+	"time"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+)
+
+var (
+	PreviousImageNotFoundError = errors.New("previous image not found")
 )
 
 type ImageRegistry interface {
 	GetImageRef() (string, error)
 }
 
+// ImageRef is an ImageRegistry for an image that is already present in a registry (e.g. the one a provider ran before).
+type ImageRef string
+
+func (r ImageRef) GetImageRef() (string, error) {
+	return string(r), nil
+}
+
+type ServiceStatus struct {
+	ImageRef     string    `json:"image_ref"`
+	Status       string    `json:"status"`
+	URL          string    `json:"url"`
+	LastDeployAt time.Time `json:"last_deploy_at"`
+}
+
+// PlanChange describes a single change a deploy would make. From is empty for things that would be created.
+type PlanChange struct {
+	Subject string `json:"subject"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to"`
+}
+
+type DeployPlan struct {
+	Changes []PlanChange `json:"changes"`
+}
+
+func (p *DeployPlan) Add(subject, from, to string) {
+	p.Changes = append(p.Changes, PlanChange{Subject: subject, From: from, To: to})
+}
+
 type CloudProvider interface {
 	DeployServiceFromImage(ctx context.Context, registry ImageRegistry) error
+	// PlanDeployServiceFromImage reports what DeployServiceFromImage would change without modifying anything.
+	PlanDeployServiceFromImage(ctx context.Context, registry ImageRegistry) (DeployPlan, error)
+	// GetServiceStatus reports the live state of the service as seen by the provider.
+	GetServiceStatus(ctx context.Context) (ServiceStatus, error)
+	// GetPreviousImageRef returns the image that was running before the current one.
+	// PreviousImageNotFoundError is returned when the provider has no such image in its history.
+	GetPreviousImageRef(ctx context.Context) (string, error)
+	// GetRuntimePlatform reports the OS and CPU architecture the service containers run on.
+	GetRuntimePlatform(ctx context.Context) (lib.Platform, error)
 }
@@ -2,8 +2,10 @@ package gcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"time"
 
 	run "cloud.google.com/go/run/apiv2"
@@ -13,8 +15,9 @@ import (
 )
 
 type CloudRunProvider struct {
-	config CloudRunConfig
-	client *run.ServicesClient
+	config          CloudRunConfig
+	client          *run.ServicesClient
+	revisionsClient *run.RevisionsClient
 }
 
 func NewCloudRunProvider(config CloudRunConfig) (*CloudRunProvider, error) {
@@ -34,9 +37,16 @@ func NewCloudRunProvider(config CloudRunConfig) (*CloudRunProvider, error) {
 		return nil, fmt.Errorf("creating Cloud Run services client: %w", err)
 	}
 
+	revisionsClient, err := run.NewRevisionsClient(context.Background())
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("creating Cloud Run revisions client: %w", err)
+	}
+
 	return &CloudRunProvider{
-		config: config,
-		client: client,
+		config:          config,
+		client:          client,
+		revisionsClient: revisionsClient,
 	}, nil
 }
 
@@ -49,9 +59,7 @@ func (p *CloudRunProvider) DeployServiceFromImage(ctx context.Context, registry
 		return fmt.Errorf("image reference is empty for service %s", p.config.ServiceName)
 	}
 
-	// Build the full service name in the format: projects/{project}/locations/{location}/services/{service}
-	serviceName := fmt.Sprintf("projects/%s/locations/%s/services/%s",
-		p.config.ProjectID, p.config.Region, p.config.ServiceName)
+	serviceName := p.fullServiceName()
 
 	slog.DebugContext(ctx, "fetching current Cloud Run service configuration",
 		"service", serviceName)
@@ -113,10 +121,138 @@ func (p *CloudRunProvider) DeployServiceFromImage(ctx context.Context, registry
 	return nil
 }
 
-// Close closes the Cloud Run client connection
+func (p *CloudRunProvider) PlanDeployServiceFromImage(ctx context.Context, registry clouds.ImageRegistry) (clouds.DeployPlan, error) {
+	var plan clouds.DeployPlan
+
+	imageRef, err := registry.GetImageRef()
+	if err != nil {
+		return plan, fmt.Errorf("getting image reference for service %s: %w", p.config.ServiceName, err)
+	}
+	if imageRef == "" {
+		return plan, fmt.Errorf("image reference is empty for service %s", p.config.ServiceName)
+	}
+
+	serviceName := p.fullServiceName()
+
+	service, err := p.client.GetService(ctx, &runpb.GetServiceRequest{
+		Name: serviceName,
+	})
+	if err != nil {
+		return plan, fmt.Errorf("getting Cloud Run service %s: %w", serviceName, err)
+	}
+	if len(service.GetTemplate().GetContainers()) == 0 {
+		return plan, fmt.Errorf("%w - Cloud Run service %s has no containers configured", lib.BadUserInputError, serviceName)
+	}
+
+	plan.Add(fmt.Sprintf("service %s image", p.config.ServiceName), service.Template.Containers[0].Image, imageRef)
+	plan.Add("serving revision", service.GetLatestReadyRevision(), "new revision")
+
+	return plan, nil
+}
+
+func (p *CloudRunProvider) GetServiceStatus(ctx context.Context) (clouds.ServiceStatus, error) {
+	serviceName := p.fullServiceName()
+
+	service, err := p.client.GetService(ctx, &runpb.GetServiceRequest{
+		Name: serviceName,
+	})
+	if err != nil {
+		return clouds.ServiceStatus{}, fmt.Errorf("getting Cloud Run service %s: %w", serviceName, err)
+	}
+
+	status := clouds.ServiceStatus{
+		URL:    service.GetUri(),
+		Status: service.GetTerminalCondition().GetState().String(),
+	}
+	if service.GetReconciling() {
+		status.Status = "RECONCILING"
+	}
+	if service.GetUpdateTime() != nil {
+		status.LastDeployAt = service.GetUpdateTime().AsTime()
+	}
+	if containers := service.GetTemplate().GetContainers(); len(containers) > 0 {
+		status.ImageRef = containers[0].GetImage()
+	}
+
+	return status, nil
+}
+
+// GetRuntimePlatform reports the platform the service runs on. Cloud Run only runs linux/amd64 images.
+func (p *CloudRunProvider) GetRuntimePlatform(ctx context.Context) (lib.Platform, error) {
+	return lib.PlatformLinuxAmd64, nil
+}
+
+// GetPreviousImageRef looks through the service revisions older than the latest ready one
+// and returns the newest image that differs from the currently deployed image.
+func (p *CloudRunProvider) GetPreviousImageRef(ctx context.Context) (string, error) {
+	serviceName := p.fullServiceName()
+
+	service, err := p.client.GetService(ctx, &runpb.GetServiceRequest{
+		Name: serviceName,
+	})
+	if err != nil {
+		return "", fmt.Errorf("getting Cloud Run service %s: %w", serviceName, err)
+	}
+
+	var currentRevision *runpb.Revision
+	revisions := make([]*runpb.Revision, 0, 10)
+	for revision, err := range p.revisionsClient.ListRevisions(ctx, &runpb.ListRevisionsRequest{Parent: serviceName}).All() {
+		if err != nil {
+			return "", fmt.Errorf("listing Cloud Run revisions of %s: %w", serviceName, err)
+		}
+		if revision.GetName() == service.GetLatestReadyRevision() {
+			currentRevision = revision
+		}
+		revisions = append(revisions, revision)
+	}
+	if currentRevision == nil {
+		return "", fmt.Errorf("%w - latest ready revision of Cloud Run service %s not found", clouds.PreviousImageNotFoundError, serviceName)
+	}
+
+	slices.SortFunc(revisions, func(a, b *runpb.Revision) int {
+		return b.GetCreateTime().AsTime().Compare(a.GetCreateTime().AsTime())
+	})
+
+	currentImage := revisionImage(currentRevision)
+	for _, revision := range revisions {
+		if !revision.GetCreateTime().AsTime().Before(currentRevision.GetCreateTime().AsTime()) {
+			continue
+		}
+
+		image := revisionImage(revision)
+		if image != "" && image != currentImage {
+			slog.DebugContext(ctx, "found previous Cloud Run revision",
+				"revision", revision.GetName(),
+				"image", image)
+			return image, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w - no earlier revision of Cloud Run service %s runs an image other than %s", clouds.PreviousImageNotFoundError, serviceName, currentImage)
+}
+
+// fullServiceName builds the service name in the format: projects/{project}/locations/{location}/services/{service}
+func (p *CloudRunProvider) fullServiceName() string {
+	return fmt.Sprintf("projects/%s/locations/%s/services/%s",
+		p.config.ProjectID, p.config.Region, p.config.ServiceName)
+}
+
+func revisionImage(revision *runpb.Revision) string {
+	containers := revision.GetContainers()
+	if len(containers) == 0 {
+		return ""
+	}
+	return containers[0].GetImage()
+}
+
+// Close closes the Cloud Run client connections
 func (p *CloudRunProvider) Close() error {
+	var errs []error
 	if p.client != nil {
-		return p.client.Close()
+		errs = append(errs, p.client.Close())
 	}
-	return nil
+	if p.revisionsClient != nil {
+		errs = append(errs, p.revisionsClient.Close())
+	}
+	return errors.Join(errs...)
 }
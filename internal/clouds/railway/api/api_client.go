@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+)
+
+type Client struct {
+	*lib.ApiClient
+}
+
+type graphqlRequest struct {
+	OperationName string         `json:"operationName"`
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []GraphqlError  `json:"errors"`
+}
+
+func MustNewClient(baseURL, apiKey string) *Client {
+	client := lib.MustNewProtectedApiClient(baseURL, apiKey)
+	return &Client{ApiClient: client}
+}
+
+// execute sends a GraphQL operation and decodes its data into result.
+func (c *Client) execute(ctx context.Context, operationName, query string, variables map[string]any, result any) error {
+	var response graphqlResponse
+	resp, err := c.NewPostRequest(ctx, c.URL(""), graphqlRequest{
+		OperationName: operationName,
+		Query:         query,
+		Variables:     variables,
+	}).WriteBodyTo(&response).Do()
+	if err := MapResponseToError(resp, response.Errors, err); err != nil {
+		return fmt.Errorf("executing %s: %w", operationName, err)
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(response.Data, result); err != nil {
+		return fmt.Errorf("decoding %s response: %w", operationName, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/railway/api/deployments"
+)
+
+const deploymentQuery = `query Deployment($id: String!) {
+  deployment(id: $id) {
+    id
+    status
+    createdAt
+    staticUrl
+    meta
+  }
+}`
+
+const deploymentsQuery = `query Deployments($first: Int!, $input: DeploymentListInput!) {
+  deployments(first: $first, input: $input) {
+    edges {
+      node {
+        id
+        status
+        createdAt
+        staticUrl
+        meta
+      }
+    }
+  }
+}`
+
+func (c *Client) RetrieveDeployment(ctx context.Context, deploymentID string) (deployments.Deployment, error) {
+	var result struct {
+		Deployment deployments.Deployment `json:"deployment"`
+	}
+	err := c.execute(ctx, "Deployment", deploymentQuery, map[string]any{
+		"id": deploymentID,
+	}, &result)
+	return result.Deployment, err
+}
+
+// ListDeployments returns up to first deployments ordered from the newest to the oldest.
+func (c *Client) ListDeployments(ctx context.Context, first int, input deployments.ListDeploymentsInput) ([]deployments.Deployment, error) {
+	var result struct {
+		Deployments struct {
+			Edges []struct {
+				Node deployments.Deployment `json:"node"`
+			} `json:"edges"`
+		} `json:"deployments"`
+	}
+	err := c.execute(ctx, "Deployments", deploymentsQuery, map[string]any{
+		"first": first,
+		"input": input,
+	}, &result)
+
+	deploymentsList := make([]deployments.Deployment, 0, len(result.Deployments.Edges))
+	for _, edge := range result.Deployments.Edges {
+		deploymentsList = append(deploymentsList, edge.Node)
+	}
+	return deploymentsList, err
+}
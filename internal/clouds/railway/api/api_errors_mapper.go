@@ -0,0 +1,43 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	UnauthorizedError = errors.New("unauthorized")
+)
+
+type GraphqlError struct {
+	Message string `json:"message"`
+}
+
+func (e GraphqlError) Error() string {
+	return e.Message
+}
+
+// MapResponseToError maps both transport and GraphQL errors - Railway reports most failures with a 200 status and an "errors" list.
+func MapResponseToError(response *http.Response, graphqlErrors []GraphqlError, err error) error {
+	if response == nil {
+		return errors.Join(errors.New("response is nil"), err)
+	}
+
+	var mappedErr error
+	switch response.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		mappedErr = UnauthorizedError
+	}
+
+	errs := []error{mappedErr, err}
+	for _, graphqlErr := range graphqlErrors {
+		if mappedErr == nil && strings.Contains(strings.ToLower(graphqlErr.Message), "not authorized") {
+			mappedErr = UnauthorizedError
+			errs[0] = mappedErr
+		}
+		errs = append(errs, graphqlErr)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/railway/api/services"
+)
+
+const serviceInstanceQuery = `query ServiceInstance($serviceId: String!, $environmentId: String!) {
+  serviceInstance(serviceId: $serviceId, environmentId: $environmentId) {
+    id
+    serviceName
+    source {
+      image
+      repo
+    }
+    domains {
+      serviceDomains {
+        domain
+      }
+      customDomains {
+        domain
+      }
+    }
+  }
+}`
+
+const serviceInstanceUpdateMutation = `mutation ServiceInstanceUpdate($serviceId: String!, $environmentId: String!, $input: ServiceInstanceUpdateInput!) {
+  serviceInstanceUpdate(serviceId: $serviceId, environmentId: $environmentId, input: $input)
+}`
+
+const serviceInstanceDeployMutation = `mutation ServiceInstanceDeploy($serviceId: String!, $environmentId: String!) {
+  serviceInstanceDeployV2(serviceId: $serviceId, environmentId: $environmentId)
+}`
+
+func (c *Client) RetrieveServiceInstance(ctx context.Context, serviceID, environmentID string) (services.ServiceInstance, error) {
+	var result struct {
+		ServiceInstance services.ServiceInstance `json:"serviceInstance"`
+	}
+	err := c.execute(ctx, "ServiceInstance", serviceInstanceQuery, map[string]any{
+		"serviceId":     serviceID,
+		"environmentId": environmentID,
+	}, &result)
+	return result.ServiceInstance, err
+}
+
+func (c *Client) UpdateServiceInstance(ctx context.Context, serviceID, environmentID string, input services.ServiceInstanceUpdateInput) error {
+	return c.execute(ctx, "ServiceInstanceUpdate", serviceInstanceUpdateMutation, map[string]any{
+		"serviceId":     serviceID,
+		"environmentId": environmentID,
+		"input":         input,
+	}, nil)
+}
+
+// DeployServiceInstance triggers a deployment of the service instance and returns the deployment ID.
+func (c *Client) DeployServiceInstance(ctx context.Context, serviceID, environmentID string) (string, error) {
+	var result struct {
+		DeploymentID string `json:"serviceInstanceDeployV2"`
+	}
+	err := c.execute(ctx, "ServiceInstanceDeploy", serviceInstanceDeployMutation, map[string]any{
+		"serviceId":     serviceID,
+		"environmentId": environmentID,
+	}, &result)
+	return result.DeploymentID, err
+}
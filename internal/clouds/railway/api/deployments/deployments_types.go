@@ -0,0 +1,72 @@
+package deployments
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type DeploymentStatus string
+
+// BUILDING CRASHED DEPLOYING FAILED INITIALIZING NEEDS_APPROVAL QUEUED REMOVED REMOVING SKIPPED SLEEPING SUCCESS WAITING
+const (
+	DeploymentStatusBuilding      DeploymentStatus = "BUILDING"
+	DeploymentStatusCrashed       DeploymentStatus = "CRASHED"
+	DeploymentStatusDeploying     DeploymentStatus = "DEPLOYING"
+	DeploymentStatusFailed        DeploymentStatus = "FAILED"
+	DeploymentStatusInitializing  DeploymentStatus = "INITIALIZING"
+	DeploymentStatusNeedsApproval DeploymentStatus = "NEEDS_APPROVAL"
+	DeploymentStatusQueued        DeploymentStatus = "QUEUED"
+	DeploymentStatusRemoved       DeploymentStatus = "REMOVED"
+	DeploymentStatusRemoving      DeploymentStatus = "REMOVING"
+	DeploymentStatusSkipped       DeploymentStatus = "SKIPPED"
+	DeploymentStatusSleeping      DeploymentStatus = "SLEEPING"
+	DeploymentStatusSuccess       DeploymentStatus = "SUCCESS"
+	DeploymentStatusWaiting       DeploymentStatus = "WAITING"
+)
+
+// IsTerminal reports whether the deployment reached a status it will not move on from by itself.
+func (s DeploymentStatus) IsTerminal() bool {
+	switch s {
+	case DeploymentStatusSuccess,
+		DeploymentStatusSleeping,
+		DeploymentStatusCrashed,
+		DeploymentStatusFailed,
+		DeploymentStatusRemoved,
+		DeploymentStatusRemoving,
+		DeploymentStatusSkipped:
+		return true
+	}
+	return false
+}
+
+// IsSuccessful reports whether the deployment is running - a sleeping deployment is a successful one scaled to zero.
+func (s DeploymentStatus) IsSuccessful() bool {
+	return s == DeploymentStatusSuccess || s == DeploymentStatusSleeping
+}
+
+type DeploymentMeta struct {
+	Image string `json:"image"`
+}
+
+type Deployment struct {
+	ID        string           `json:"id"`
+	Status    DeploymentStatus `json:"status"`
+	CreatedAt time.Time        `json:"createdAt"`
+	StaticUrl string           `json:"staticUrl"`
+	Meta      json.RawMessage  `json:"meta"`
+}
+
+// GetMeta decodes the deployment meta, which holds the deployed image for image based services.
+func (d Deployment) GetMeta() (DeploymentMeta, error) {
+	var meta DeploymentMeta
+	if len(d.Meta) == 0 || string(d.Meta) == "null" {
+		return meta, nil
+	}
+	err := json.Unmarshal(d.Meta, &meta)
+	return meta, err
+}
+
+type ListDeploymentsInput struct {
+	ServiceID     string `json:"serviceId"`
+	EnvironmentID string `json:"environmentId"`
+}
@@ -0,0 +1,30 @@
+package services
+
+type ServiceInstanceSource struct {
+	Image string `json:"image"`
+	Repo  string `json:"repo"`
+}
+
+type ServiceDomain struct {
+	Domain string `json:"domain"`
+}
+
+type ServiceInstanceDomains struct {
+	ServiceDomains []ServiceDomain `json:"serviceDomains"`
+	CustomDomains  []ServiceDomain `json:"customDomains"`
+}
+
+type ServiceInstance struct {
+	ID          string                 `json:"id"`
+	ServiceName string                 `json:"serviceName"`
+	Source      *ServiceInstanceSource `json:"source"`
+	Domains     ServiceInstanceDomains `json:"domains"`
+}
+
+type ServiceInstanceUpdateSource struct {
+	Image string `json:"image"`
+}
+
+type ServiceInstanceUpdateInput struct {
+	Source *ServiceInstanceUpdateSource `json:"source,omitempty"`
+}
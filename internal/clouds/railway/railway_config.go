@@ -0,0 +1,23 @@
+package railway
+
+import (
+	"fmt"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+)
+
+type Config struct {
+	ServiceID     string `mapstructure:"service_id"`
+	EnvironmentID string `mapstructure:"environment_id"`
+}
+
+// Validate reports a lib.BadUserInputError when the IDs of the Railway service are missing.
+func (c Config) Validate() error {
+	if c.ServiceID == "" {
+		return fmt.Errorf("%w - Railway service ID is required", lib.BadUserInputError)
+	}
+	if c.EnvironmentID == "" {
+		return fmt.Errorf("%w - Railway environment ID is required", lib.BadUserInputError)
+	}
+	return nil
+}
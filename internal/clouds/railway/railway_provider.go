@@ -0,0 +1,273 @@
+package railway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/railway/api"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/railway/api/deployments"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/railway/api/services"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+)
+
+const (
+	railwayApiURL          = "https://backboard.railway.com/graphql/v2"
+	railwayApiSecretKey    = "railway_api_token"
+	railwayApiSecretLabel  = "Railway API Token"
+	railwayDeploymentsPage = 50
+)
+
+type Provider struct {
+	serviceID              string
+	config                 Config
+	api                    *api.Client
+	storage                lib.CredentialsStorage
+	deploymentPollInterval time.Duration
+	deploymentTimeout      time.Duration
+}
+
+// NewProviderFromStorage reads the API token from the storage, the environment or the terminal, in this order.
+func NewProviderFromStorage(serviceID string, cfg Config, storage lib.CredentialsStorage, authEnvKeys []string) (*Provider, error) {
+	apiToken, err := lib.GetSecretFromEnvOrInput(storage, railwayApiSecretKey, railwayApiSecretLabel, authEnvKeys, os.Stdin, os.Stdout, "Please provide Railway API Token")
+	if err != nil {
+		return nil, fmt.Errorf("getting railway_api_token: %w", err)
+	}
+
+	return NewProvider(serviceID, cfg, api.MustNewClient(railwayApiURL, apiToken), storage), nil
+}
+
+func NewProvider(serviceID string, cfg Config, apiClient *api.Client, storage lib.CredentialsStorage) *Provider {
+	return &Provider{
+		serviceID:              serviceID,
+		config:                 cfg,
+		api:                    apiClient,
+		storage:                storage,
+		deploymentPollInterval: 5 * time.Second,
+		deploymentTimeout:      15 * time.Minute,
+	}
+}
+
+func (p *Provider) DeployServiceFromImage(ctx context.Context, registry clouds.ImageRegistry) error {
+	imageRef, err := registry.GetImageRef()
+	if err != nil {
+		return fmt.Errorf("getting image reference for service %s: %w", p.config.ServiceID, err)
+	}
+	if imageRef == "" {
+		return fmt.Errorf("image reference is empty for service %s", p.config.ServiceID)
+	}
+
+	instance, err := p.retrieveServiceInstance(ctx)
+	if err != nil {
+		return err
+	}
+	slog.DebugContext(ctx, "retrieved service instance", "service_id", p.config.ServiceID, "instance", instance)
+
+	currentImage := ""
+	if instance.Source != nil {
+		currentImage = instance.Source.Image
+		if instance.Source.Repo != "" {
+			return fmt.Errorf("%w - Railway service %s is deployed from repository %s - not supported for container image deployment", lib.BadUserInputError, p.config.ServiceID, instance.Source.Repo)
+		}
+	}
+
+	if currentImage != imageRef {
+		slog.InfoContext(ctx, "updating service image", "service_id", p.config.ServiceID, "from", currentImage, "to", imageRef)
+
+		err = p.api.UpdateServiceInstance(ctx, p.config.ServiceID, p.config.EnvironmentID, services.ServiceInstanceUpdateInput{
+			Source: &services.ServiceInstanceUpdateSource{Image: imageRef},
+		})
+		if err != nil {
+			p.resetCredentialsOnUnauthorized(err)
+			return fmt.Errorf("updating service %s image from %s to %s: %w", p.config.ServiceID, currentImage, imageRef, err)
+		}
+	}
+
+	slog.InfoContext(ctx, "deploying image to service", "service_id", p.config.ServiceID, "image", imageRef)
+
+	deploymentID, err := p.api.DeployServiceInstance(ctx, p.config.ServiceID, p.config.EnvironmentID)
+	if err != nil {
+		p.resetCredentialsOnUnauthorized(err)
+		return fmt.Errorf("deploying service %s: %w", p.config.ServiceID, err)
+	}
+	if deploymentID == "" {
+		return fmt.Errorf("Railway returned no deployment for service %s", p.config.ServiceID)
+	}
+
+	return p.waitForDeployment(ctx, deploymentID)
+}
+
+// waitForDeployment polls the deployment until it reaches a terminal status and reports status changes along the way.
+func (p *Provider) waitForDeployment(ctx context.Context, deploymentID string) error {
+	slog.InfoContext(ctx, "waiting for Railway deployment to complete",
+		"service_id", p.config.ServiceID,
+		"deployment_id", deploymentID)
+
+	waitCtx, cancel := context.WithTimeout(ctx, p.deploymentTimeout)
+	defer cancel()
+
+	waitTicker := time.NewTicker(p.deploymentPollInterval)
+	defer waitTicker.Stop()
+
+	var lastStatus deployments.DeploymentStatus
+	for {
+		deployment, err := p.api.RetrieveDeployment(waitCtx, deploymentID)
+		if err != nil {
+			p.resetCredentialsOnUnauthorized(err)
+			return fmt.Errorf("retrieving deployment %s of service %s: %w", deploymentID, p.config.ServiceID, err)
+		}
+
+		if deployment.Status != lastStatus {
+			slog.InfoContext(ctx, "Railway deployment status changed",
+				"service_id", p.config.ServiceID,
+				"deployment_id", deploymentID,
+				"from", lastStatus,
+				"to", deployment.Status)
+			lastStatus = deployment.Status
+		}
+
+		if deployment.Status.IsTerminal() {
+			if !deployment.Status.IsSuccessful() {
+				return fmt.Errorf("Railway deployment %s of service %s finished with status %s", deploymentID, p.config.ServiceID, deployment.Status)
+			}
+			break
+		}
+
+		select {
+		case <-waitCtx.Done():
+			return fmt.Errorf("waiting for Railway deployment %s of service %s to complete: %w", deploymentID, p.config.ServiceID, waitCtx.Err())
+		case <-waitTicker.C:
+		}
+	}
+
+	slog.InfoContext(ctx, "Railway deployment completed",
+		"service_id", p.config.ServiceID,
+		"deployment_id", deploymentID)
+
+	return nil
+}
+
+func (p *Provider) PlanDeployServiceFromImage(ctx context.Context, registry clouds.ImageRegistry) (clouds.DeployPlan, error) {
+	var plan clouds.DeployPlan
+
+	imageRef, err := registry.GetImageRef()
+	if err != nil {
+		return plan, fmt.Errorf("getting image reference for service %s: %w", p.config.ServiceID, err)
+	}
+	if imageRef == "" {
+		return plan, fmt.Errorf("image reference is empty for service %s", p.config.ServiceID)
+	}
+
+	instance, err := p.retrieveServiceInstance(ctx)
+	if err != nil {
+		return plan, err
+	}
+
+	currentImage := ""
+	if instance.Source != nil {
+		currentImage = instance.Source.Image
+	}
+
+	plan.Add(fmt.Sprintf("service %s image", instance.ServiceName), currentImage, imageRef)
+	plan.Add("deployment triggered", "", imageRef)
+
+	return plan, nil
+}
+
+func (p *Provider) GetServiceStatus(ctx context.Context) (clouds.ServiceStatus, error) {
+	instance, err := p.retrieveServiceInstance(ctx)
+	if err != nil {
+		return clouds.ServiceStatus{}, err
+	}
+
+	var status clouds.ServiceStatus
+	if instance.Source != nil {
+		status.ImageRef = instance.Source.Image
+	}
+	switch {
+	case len(instance.Domains.CustomDomains) > 0:
+		status.URL = fmt.Sprintf("https://%s", instance.Domains.CustomDomains[0].Domain)
+	case len(instance.Domains.ServiceDomains) > 0:
+		status.URL = fmt.Sprintf("https://%s", instance.Domains.ServiceDomains[0].Domain)
+	}
+
+	deploymentsList, err := p.api.ListDeployments(ctx, 1, deployments.ListDeploymentsInput{
+		ServiceID:     p.config.ServiceID,
+		EnvironmentID: p.config.EnvironmentID,
+	})
+	if err != nil {
+		p.resetCredentialsOnUnauthorized(err)
+		return clouds.ServiceStatus{}, fmt.Errorf("listing deployments of service %s: %w", p.config.ServiceID, err)
+	}
+	if len(deploymentsList) > 0 {
+		status.Status = string(deploymentsList[0].Status)
+		status.LastDeployAt = deploymentsList[0].CreatedAt
+	}
+
+	return status, nil
+}
+
+// GetRuntimePlatform reports the platform the service runs on. Railway only runs linux/amd64 images.
+func (p *Provider) GetRuntimePlatform(ctx context.Context) (lib.Platform, error) {
+	return lib.PlatformLinuxAmd64, nil
+}
+
+// GetPreviousImageRef returns the image of the newest successful deployment older than the current successful one.
+func (p *Provider) GetPreviousImageRef(ctx context.Context) (string, error) {
+	deploymentsList, err := p.api.ListDeployments(ctx, railwayDeploymentsPage, deployments.ListDeploymentsInput{
+		ServiceID:     p.config.ServiceID,
+		EnvironmentID: p.config.EnvironmentID,
+	})
+	if err != nil {
+		p.resetCredentialsOnUnauthorized(err)
+		return "", fmt.Errorf("listing deployments of service %s: %w", p.config.ServiceID, err)
+	}
+
+	currentImage := ""
+	for _, deployment := range deploymentsList {
+		// Removed deployments were successful ones replaced by newer deployments
+		if !deployment.Status.IsSuccessful() && deployment.Status != deployments.DeploymentStatusRemoved {
+			continue
+		}
+
+		meta, err := deployment.GetMeta()
+		if err != nil {
+			return "", fmt.Errorf("decoding meta of deployment %s: %w", deployment.ID, err)
+		}
+		if meta.Image == "" {
+			continue
+		}
+
+		switch {
+		case currentImage == "":
+			currentImage = meta.Image
+		case meta.Image != currentImage:
+			slog.DebugContext(ctx, "found previous deployment", "service_id", p.config.ServiceID, "deployment_id", deployment.ID, "image", meta.Image)
+			return meta.Image, nil
+		}
+	}
+
+	if currentImage == "" {
+		return "", fmt.Errorf("%w - service %s has no image deployment", clouds.PreviousImageNotFoundError, p.config.ServiceID)
+	}
+	return "", fmt.Errorf("%w - no earlier deployment of service %s runs an image other than %s", clouds.PreviousImageNotFoundError, p.config.ServiceID, currentImage)
+}
+
+func (p *Provider) retrieveServiceInstance(ctx context.Context) (services.ServiceInstance, error) {
+	instance, err := p.api.RetrieveServiceInstance(ctx, p.config.ServiceID, p.config.EnvironmentID)
+	if err != nil {
+		p.resetCredentialsOnUnauthorized(err)
+		return instance, fmt.Errorf("retrieving service %s in environment %s: %w", p.config.ServiceID, p.config.EnvironmentID, err)
+	}
+	return instance, nil
+}
+
+func (p *Provider) resetCredentialsOnUnauthorized(err error) {
+	if errors.Is(err, api.UnauthorizedError) {
+		p.storage.Remove(railwayApiSecretKey)
+	}
+}
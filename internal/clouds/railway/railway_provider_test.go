@@ -0,0 +1,241 @@
+package railway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/railway/api"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type standInDeployment struct {
+	ID        string `json:"id"`
+	Status    string `json:"status"`
+	CreatedAt string `json:"createdAt"`
+	Meta      any    `json:"meta"`
+}
+
+// graphqlStandIn is a minimal stand-in of the Railway GraphQL API dispatching on the operation name.
+type graphqlStandIn struct {
+	mu                 sync.Mutex
+	image              string
+	domain             string
+	deploymentStatuses []string
+	deployments        []standInDeployment
+	updatedImages      []string
+	deploysTriggered   int
+}
+
+func (s *graphqlStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var request struct {
+		OperationName string         `json:"operationName"`
+		Variables     map[string]any `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var data any
+	switch request.OperationName {
+	case "ServiceInstance":
+		data = map[string]any{
+			"serviceInstance": map[string]any{
+				"id":          "instance-1",
+				"serviceName": "api",
+				"source":      map[string]any{"image": s.image},
+				"domains": map[string]any{
+					"serviceDomains": []map[string]any{{"domain": s.domain}},
+					"customDomains":  []map[string]any{},
+				},
+			},
+		}
+	case "ServiceInstanceUpdate":
+		input := request.Variables["input"].(map[string]any)
+		s.image = input["source"].(map[string]any)["image"].(string)
+		s.updatedImages = append(s.updatedImages, s.image)
+		data = map[string]any{"serviceInstanceUpdate": true}
+	case "ServiceInstanceDeploy":
+		s.deploysTriggered++
+		data = map[string]any{"serviceInstanceDeployV2": "deployment-new"}
+	case "Deployment":
+		status := s.deploymentStatuses[0]
+		if len(s.deploymentStatuses) > 1 {
+			s.deploymentStatuses = s.deploymentStatuses[1:]
+		}
+		data = map[string]any{
+			"deployment": map[string]any{"id": request.Variables["id"], "status": status},
+		}
+	case "Deployments":
+		edges := make([]map[string]any, 0, len(s.deployments))
+		for _, deployment := range s.deployments {
+			edges = append(edges, map[string]any{"node": deployment})
+		}
+		data = map[string]any{
+			"deployments": map[string]any{"edges": edges},
+		}
+	default:
+		json.NewEncoder(w).Encode(map[string]any{
+			"data":   nil,
+			"errors": []map[string]any{{"message": "unknown operation " + request.OperationName}},
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"data": data})
+}
+
+func newTestProvider(t *testing.T, handler http.Handler) *Provider {
+	server := testutil.NewServer(t, handler)
+
+	storage := testutil.NewMemoryCredentialsStorage(map[string]string{railwayApiSecretKey: "token"})
+	p := NewProvider("api", Config{ServiceID: "service-1", EnvironmentID: "env-1"}, api.MustNewClient(server.URL, "token"), storage)
+	p.deploymentPollInterval = time.Millisecond
+
+	return p
+}
+
+func TestProvider_DeployServiceFromImage(t *testing.T) {
+	t.Parallel()
+
+	t.Run("updates the image and waits for the deployment", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		standIn := &graphqlStandIn{
+			image:              "ghcr.io/owner/api:v1",
+			deploymentStatuses: []string{"QUEUED", "BUILDING", "DEPLOYING", "SUCCESS"},
+		}
+		p := newTestProvider(t, standIn)
+
+		r.NoError(p.DeployServiceFromImage(context.Background(), clouds.ImageRef("ghcr.io/owner/api:v2")))
+		r.Equal([]string{"ghcr.io/owner/api:v2"}, standIn.updatedImages)
+		r.Equal(1, standIn.deploysTriggered)
+	})
+
+	t.Run("skips the image update when the image did not change", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		standIn := &graphqlStandIn{
+			image:              "ghcr.io/owner/api:v1",
+			deploymentStatuses: []string{"SUCCESS"},
+		}
+		p := newTestProvider(t, standIn)
+
+		r.NoError(p.DeployServiceFromImage(context.Background(), clouds.ImageRef("ghcr.io/owner/api:v1")))
+		r.Empty(standIn.updatedImages)
+		r.Equal(1, standIn.deploysTriggered)
+	})
+
+	t.Run("fails when the deployment fails", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		standIn := &graphqlStandIn{
+			image:              "ghcr.io/owner/api:v1",
+			deploymentStatuses: []string{"BUILDING", "FAILED"},
+		}
+		p := newTestProvider(t, standIn)
+
+		err := p.DeployServiceFromImage(context.Background(), clouds.ImageRef("ghcr.io/owner/api:v2"))
+		r.ErrorContains(err, "FAILED")
+	})
+
+	t.Run("resets stored token when not authorized", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		p := newTestProvider(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]any{
+				"data":   nil,
+				"errors": []map[string]any{{"message": "Not Authorized"}},
+			})
+		}))
+
+		err := p.DeployServiceFromImage(context.Background(), clouds.ImageRef("ghcr.io/owner/api:v2"))
+		r.ErrorIs(err, api.UnauthorizedError)
+
+		storedToken, err := p.storage.Get(railwayApiSecretKey)
+		r.NoError(err)
+		r.Empty(storedToken)
+	})
+}
+
+func TestProvider_GetServiceStatus(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	standIn := &graphqlStandIn{
+		image:  "ghcr.io/owner/api:v2",
+		domain: "api-production.up.railway.app",
+		deployments: []standInDeployment{
+			{ID: "deployment-2", Status: "SUCCESS", CreatedAt: "2025-01-02T10:00:00Z"},
+		},
+	}
+	p := newTestProvider(t, standIn)
+
+	status, err := p.GetServiceStatus(context.Background())
+	r.NoError(err)
+	r.Equal("ghcr.io/owner/api:v2", status.ImageRef)
+	r.Equal("SUCCESS", status.Status)
+	r.Equal("https://api-production.up.railway.app", status.URL)
+	r.Equal(time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC), status.LastDeployAt)
+}
+
+func TestProvider_GetPreviousImageRef(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns the image of the previous successful deployment", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		standIn := &graphqlStandIn{
+			deployments: []standInDeployment{
+				{ID: "deployment-4", CreatedAt: "2025-01-04T10:00:00Z", Status: "FAILED", Meta: map[string]any{"image": "ghcr.io/owner/api:v4"}},
+				{ID: "deployment-3", CreatedAt: "2025-01-03T10:00:00Z", Status: "SUCCESS", Meta: map[string]any{"image": "ghcr.io/owner/api:v3"}},
+				{ID: "deployment-2", CreatedAt: "2025-01-02T10:00:00Z", Status: "REMOVED", Meta: map[string]any{"image": "ghcr.io/owner/api:v3"}},
+				{ID: "deployment-1", CreatedAt: "2025-01-01T10:00:00Z", Status: "REMOVED", Meta: map[string]any{"image": "ghcr.io/owner/api:v1"}},
+			},
+		}
+		p := newTestProvider(t, standIn)
+
+		imageRef, err := p.GetPreviousImageRef(context.Background())
+		r.NoError(err)
+		r.Equal("ghcr.io/owner/api:v1", imageRef)
+	})
+
+	t.Run("reports missing history", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		standIn := &graphqlStandIn{
+			deployments: []standInDeployment{
+				{ID: "deployment-1", CreatedAt: "2025-01-01T10:00:00Z", Status: "SUCCESS", Meta: map[string]any{"image": "ghcr.io/owner/api:v1"}},
+			},
+		}
+		p := newTestProvider(t, standIn)
+
+		_, err := p.GetPreviousImageRef(context.Background())
+		r.ErrorIs(err, clouds.PreviousImageNotFoundError)
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	r.NoError(Config{ServiceID: "service-1", EnvironmentID: "env-1"}.Validate())
+	r.ErrorIs(Config{EnvironmentID: "env-1"}.Validate(), lib.BadUserInputError)
+	r.ErrorIs(Config{ServiceID: "service-1"}.Validate(), lib.BadUserInputError)
+}
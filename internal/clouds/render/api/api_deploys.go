@@ -1,12 +1,57 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
 
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/render/api/deploys"
 )
 
-func (c *Client) TriggerDeploy(ctx context.Context, serviceID string, input deploys.TriggerDeployInput) error {
+// TriggerDeploy starts a deploy and returns it. The API may only queue the deploy without returning it,
+// in which case the returned deploy has an empty ID.
+func (c *Client) TriggerDeploy(ctx context.Context, serviceID string, input deploys.TriggerDeployInput) (deploys.Deploy, error) {
+	var deploy deploys.Deploy
 	resp, err := c.NewPostRequest(ctx, c.URLf("/services/%s/deploys", serviceID), input).Do()
-	return MapResponseToError(resp, err)
+	if resp != nil && resp.Body != nil {
+		defer resp.Body.Close()
+	}
+	if err := MapResponseToError(resp, err); err != nil {
+		return deploy, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return deploy, fmt.Errorf("reading deploy response: %w", err)
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return deploy, nil
+	}
+	if err := json.Unmarshal(body, &deploy); err != nil {
+		return deploy, fmt.Errorf("decoding deploy response: %w", err)
+	}
+
+	return deploy, nil
+}
+
+func (c *Client) RetrieveDeploy(ctx context.Context, serviceID, deployID string) (deploys.Deploy, error) {
+	var deploy deploys.Deploy
+	resp, err := c.NewGetRequest(ctx, c.URLf("/services/%s/deploys/%s", serviceID, deployID)).WriteBodyTo(&deploy).Do()
+	return deploy, MapResponseToError(resp, err)
+}
+
+// ListDeploys returns the service deploys ordered from the newest to the oldest.
+func (c *Client) ListDeploys(ctx context.Context, serviceID string, input deploys.ListDeploysInput) (deploys.ListDeploysResponse, error) {
+	query := url.Values{}
+	if input.Limit > 0 {
+		query.Set("limit", strconv.Itoa(input.Limit))
+	}
+
+	var deploysList deploys.ListDeploysResponse
+	resp, err := c.NewGetRequest(ctx, c.URLWithQueryf(query, "/services/%s/deploys", serviceID)).WriteBodyTo(&deploysList).Do()
+	return deploysList, MapResponseToError(resp, err)
 }
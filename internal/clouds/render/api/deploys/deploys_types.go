@@ -1,5 +1,7 @@
 package deploys
 
+import "time"
+
 type ClearCache string
 
 // clear do_not_clear
@@ -13,3 +15,68 @@ type TriggerDeployInput struct {
 	CommitID   string     `json:"commitId,omitempty"`
 	ImageID    string     `json:"imageId,omitempty"`
 }
+
+type DeployStatus string
+
+// created queued build_in_progress update_in_progress live deactivated build_failed update_failed canceled pre_deploy_in_progress pre_deploy_failed
+const (
+	DeployStatusCreated             DeployStatus = "created"
+	DeployStatusQueued              DeployStatus = "queued"
+	DeployStatusBuildInProgress     DeployStatus = "build_in_progress"
+	DeployStatusUpdateInProgress    DeployStatus = "update_in_progress"
+	DeployStatusLive                DeployStatus = "live"
+	DeployStatusDeactivated         DeployStatus = "deactivated"
+	DeployStatusBuildFailed         DeployStatus = "build_failed"
+	DeployStatusUpdateFailed        DeployStatus = "update_failed"
+	DeployStatusCanceled            DeployStatus = "canceled"
+	DeployStatusPreDeployInProgress DeployStatus = "pre_deploy_in_progress"
+	DeployStatusPreDeployFailed     DeployStatus = "pre_deploy_failed"
+)
+
+// IsTerminal reports whether the deploy reached a status it will not move on from.
+func (s DeployStatus) IsTerminal() bool {
+	switch s {
+	case DeployStatusLive,
+		DeployStatusDeactivated,
+		DeployStatusBuildFailed,
+		DeployStatusUpdateFailed,
+		DeployStatusCanceled,
+		DeployStatusPreDeployFailed:
+		return true
+	}
+	return false
+}
+
+type DeployCommit struct {
+	ID        string    `json:"id"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type DeployImage struct {
+	Ref                string `json:"ref"`
+	Sha                string `json:"sha"`
+	RegistryCredential string `json:"registryCredential"`
+}
+
+type Deploy struct {
+	ID         string        `json:"id"`
+	Commit     *DeployCommit `json:"commit"`
+	Image      *DeployImage  `json:"image"`
+	Status     DeployStatus  `json:"status"`
+	Trigger    string        `json:"trigger"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	UpdatedAt  time.Time     `json:"updatedAt"`
+	FinishedAt *time.Time    `json:"finishedAt"`
+}
+
+type ListDeploysInput struct {
+	Limit int
+}
+
+type ListDeploysItem struct {
+	Deploy Deploy `json:"deploy"`
+	Cursor string `json:"cursor"`
+}
+
+type ListDeploysResponse []ListDeploysItem
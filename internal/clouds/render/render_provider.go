@@ -4,9 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds"
 	api2 "github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/render/api"
@@ -18,28 +18,36 @@ import (
 const (
 	renderApiSecretKey   = "render_api_key"
 	renderApiSecretLabel = "Render API Key"
+
+	// triggeredDeploySearchLimit is how many of the latest deploys are searched for a deploy the API only queued
+	triggeredDeploySearchLimit = 10
 )
 
 type Provider struct {
-	serviceID string
-	config    Config
-	api       *api2.Client
-	storage   lib.CredentialsStorage
+	serviceID          string
+	config             Config
+	api                *api2.Client
+	storage            lib.CredentialsStorage
+	deployPollInterval time.Duration
+	deployTimeout      time.Duration
 }
 
-func MustNewProvider(serviceID string, cfg Config, storage lib.CredentialsStorage, authEnvKeys []string) *Provider {
+// NewProvider reads the API key from the storage, the environment or the terminal, in this order.
+func NewProvider(serviceID string, cfg Config, storage lib.CredentialsStorage, authEnvKeys []string) (*Provider, error) {
 	apiKey, err := lib.GetSecretFromEnvOrInput(storage, renderApiSecretKey, renderApiSecretLabel, authEnvKeys, os.Stdin, os.Stdout, "Please provide Render API Key")
 	if err != nil {
-		log.Fatalf("Error getting render_api_key: %v", err)
+		return nil, fmt.Errorf("getting render_api_key: %w", err)
 	}
 	api := api2.MustNewClient("https://api.render.com/v1", apiKey)
 
 	return &Provider{
-		serviceID: serviceID,
-		config:    cfg,
-		api:       api,
-		storage:   storage,
-	}
+		serviceID:          serviceID,
+		config:             cfg,
+		api:                api,
+		storage:            storage,
+		deployPollInterval: 10 * time.Second,
+		deployTimeout:      15 * time.Minute,
+	}, nil
 }
 
 func (p *Provider) DeployServiceFromImage(ctx context.Context, registry clouds.ImageRegistry) error {
@@ -85,12 +93,206 @@ func (p *Provider) DeployServiceFromImage(ctx context.Context, registry clouds.I
 
 	slog.InfoContext(ctx, "deploying image to service", "service_id", p.config.ServiceID, "image", imageRef)
 
-	err = p.api.TriggerDeploy(ctx, p.config.ServiceID, deploys.TriggerDeployInput{ImageID: imageRef})
+	// Render reports the creation time in whole seconds
+	triggeredAt := time.Now().Truncate(time.Second)
+	deploy, err := p.api.TriggerDeploy(ctx, p.config.ServiceID, deploys.TriggerDeployInput{ImageID: imageRef})
 	if err != nil {
 		if errors.Is(err, api2.UnauthorizedError) {
 			p.storage.Remove(renderApiSecretKey)
 		}
 		return fmt.Errorf("deploying service %s: %w", p.config.ServiceID, err)
 	}
+
+	if deploy.ID == "" {
+		deploy, err = p.findTriggeredDeploy(ctx, imageRef, triggeredAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.waitForDeploy(ctx, deploy)
+}
+
+// findTriggeredDeploy returns the deploy of the image created since the trigger, for deploys the API only queued.
+// Deploys started meanwhile from the dashboard or by auto-deploy run another image or were created before.
+func (p *Provider) findTriggeredDeploy(ctx context.Context, imageRef string, triggeredAt time.Time) (deploys.Deploy, error) {
+	deploysList, err := p.api.ListDeploys(ctx, p.config.ServiceID, deploys.ListDeploysInput{Limit: triggeredDeploySearchLimit})
+	if err != nil {
+		if errors.Is(err, api2.UnauthorizedError) {
+			p.storage.Remove(renderApiSecretKey)
+		}
+		return deploys.Deploy{}, fmt.Errorf("listing deploys of service %s: %w", p.config.ServiceID, err)
+	}
+
+	for _, item := range deploysList {
+		deploy := item.Deploy
+		if deploy.Image != nil && deploy.Image.Ref == imageRef && !deploy.CreatedAt.Before(triggeredAt) {
+			return deploy, nil
+		}
+	}
+	return deploys.Deploy{}, fmt.Errorf("triggered deploy of image %s not found among the latest deploys of service %s", imageRef, p.config.ServiceID)
+}
+
+// waitForDeploy polls the deploy until it reaches a terminal status and reports status changes along the way.
+func (p *Provider) waitForDeploy(ctx context.Context, deploy deploys.Deploy) error {
+	slog.InfoContext(ctx, "waiting for Render deploy to complete",
+		"service_id", p.config.ServiceID,
+		"deploy_id", deploy.ID,
+		"status", deploy.Status)
+
+	waitCtx, cancel := context.WithTimeout(ctx, p.deployTimeout)
+	defer cancel()
+
+	waitTicker := time.NewTicker(p.deployPollInterval)
+	defer waitTicker.Stop()
+
+	lastStatus := deploy.Status
+	for !deploy.Status.IsTerminal() {
+		select {
+		case <-waitCtx.Done():
+			return fmt.Errorf("waiting for Render deploy %s of service %s to complete: %w", deploy.ID, p.config.ServiceID, waitCtx.Err())
+		case <-waitTicker.C:
+		}
+
+		retrievedDeploy, err := p.api.RetrieveDeploy(waitCtx, p.config.ServiceID, deploy.ID)
+		if err != nil {
+			if errors.Is(err, api2.UnauthorizedError) {
+				p.storage.Remove(renderApiSecretKey)
+			}
+			return fmt.Errorf("retrieving deploy %s of service %s: %w", deploy.ID, p.config.ServiceID, err)
+		}
+		deploy = retrievedDeploy
+
+		if deploy.Status != lastStatus {
+			slog.InfoContext(ctx, "Render deploy status changed",
+				"service_id", p.config.ServiceID,
+				"deploy_id", deploy.ID,
+				"from", lastStatus,
+				"to", deploy.Status)
+			lastStatus = deploy.Status
+		}
+	}
+
+	if deploy.Status != deploys.DeployStatusLive {
+		return fmt.Errorf("Render deploy %s of service %s finished with status %s", deploy.ID, p.config.ServiceID, deploy.Status)
+	}
+
+	slog.InfoContext(ctx, "Render deploy completed",
+		"service_id", p.config.ServiceID,
+		"deploy_id", deploy.ID)
+
 	return nil
 }
+
+func (p *Provider) PlanDeployServiceFromImage(ctx context.Context, registry clouds.ImageRegistry) (clouds.DeployPlan, error) {
+	var plan clouds.DeployPlan
+
+	imageRef, err := registry.GetImageRef()
+	if err != nil {
+		return plan, fmt.Errorf("getting image reference for service %s: %w", p.config.ServiceID, err)
+	}
+	if imageRef == "" {
+		return plan, fmt.Errorf("image reference is empty for service %s", p.config.ServiceID)
+	}
+
+	service, err := p.api.RetrieveService(ctx, p.config.ServiceID)
+	if err != nil {
+		if errors.Is(err, api2.UnauthorizedError) {
+			p.storage.Remove(renderApiSecretKey)
+		}
+		return plan, fmt.Errorf("retrieving service %s: %w", p.config.ServiceID, err)
+	}
+	if service.Type == services.ServiceTypeStaticSite {
+		return plan, fmt.Errorf("service %s is static site - not supported for container image deployment", p.config.ServiceID)
+	}
+
+	plan.Add(fmt.Sprintf("service %s image", service.Name), service.ImagePath, imageRef)
+	plan.Add("deploy triggered", "", imageRef)
+
+	return plan, nil
+}
+
+func (p *Provider) GetServiceStatus(ctx context.Context) (clouds.ServiceStatus, error) {
+	service, err := p.api.RetrieveService(ctx, p.config.ServiceID)
+	if err != nil {
+		if errors.Is(err, api2.UnauthorizedError) {
+			p.storage.Remove(renderApiSecretKey)
+		}
+		return clouds.ServiceStatus{}, fmt.Errorf("retrieving service %s: %w", p.config.ServiceID, err)
+	}
+
+	status := clouds.ServiceStatus{
+		ImageRef:     service.ImagePath,
+		LastDeployAt: service.UpdatedAt,
+	}
+	if service.Suspended == services.ServiceSuspendedSuspended {
+		status.Status = string(service.Suspended)
+	}
+
+	var details struct {
+		Url string `json:"url"`
+	}
+	if len(service.ServiceDetails) > 0 {
+		if err := service.ServiceDetails.DecodeInto(&details); err != nil {
+			return clouds.ServiceStatus{}, fmt.Errorf("decoding details of service %s: %w", p.config.ServiceID, err)
+		}
+	}
+	status.URL = details.Url
+
+	deploysList, err := p.api.ListDeploys(ctx, p.config.ServiceID, deploys.ListDeploysInput{Limit: 1})
+	if err != nil {
+		if errors.Is(err, api2.UnauthorizedError) {
+			p.storage.Remove(renderApiSecretKey)
+		}
+		return clouds.ServiceStatus{}, fmt.Errorf("listing deploys of service %s: %w", p.config.ServiceID, err)
+	}
+	if len(deploysList) > 0 {
+		latestDeploy := deploysList[0].Deploy
+		if service.Suspended != services.ServiceSuspendedSuspended {
+			status.Status = string(latestDeploy.Status)
+		}
+		status.LastDeployAt = latestDeploy.CreatedAt
+		if latestDeploy.FinishedAt != nil {
+			status.LastDeployAt = *latestDeploy.FinishedAt
+		}
+	}
+
+	return status, nil
+}
+
+// GetRuntimePlatform reports the platform the service runs on. Render only runs linux/amd64 images.
+func (p *Provider) GetRuntimePlatform(ctx context.Context) (lib.Platform, error) {
+	return lib.PlatformLinuxAmd64, nil
+}
+
+// GetPreviousImageRef returns the image of the newest deploy that was live before the current live deploy.
+func (p *Provider) GetPreviousImageRef(ctx context.Context) (string, error) {
+	deploysList, err := p.api.ListDeploys(ctx, p.config.ServiceID, deploys.ListDeploysInput{Limit: 50})
+	if err != nil {
+		if errors.Is(err, api2.UnauthorizedError) {
+			p.storage.Remove(renderApiSecretKey)
+		}
+		return "", fmt.Errorf("listing deploys of service %s: %w", p.config.ServiceID, err)
+	}
+
+	currentImage := ""
+	for _, item := range deploysList {
+		deploy := item.Deploy
+		if deploy.Image == nil || deploy.Image.Ref == "" {
+			continue
+		}
+
+		switch {
+		case currentImage == "" && deploy.Status == deploys.DeployStatusLive:
+			currentImage = deploy.Image.Ref
+		case currentImage != "" && deploy.Status == deploys.DeployStatusDeactivated && deploy.Image.Ref != currentImage:
+			slog.DebugContext(ctx, "found previous deploy", "service_id", p.config.ServiceID, "deploy_id", deploy.ID, "image", deploy.Image.Ref)
+			return deploy.Image.Ref, nil
+		}
+	}
+
+	if currentImage == "" {
+		return "", fmt.Errorf("%w - service %s has no live image deploy", clouds.PreviousImageNotFoundError, p.config.ServiceID)
+	}
+	return "", fmt.Errorf("%w - no earlier deploy of service %s runs an image other than %s", clouds.PreviousImageNotFoundError, p.config.ServiceID, currentImage)
+}
@@ -0,0 +1,142 @@
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	api2 "github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/render/api"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/render/api/deploys"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// newDeployServer serves the given deploy statuses one by one on every deploy retrieval, repeating the last one.
+func newDeployServer(t *testing.T, triggerStatus deploys.DeployStatus, statuses ...deploys.DeployStatus) *httptest.Server {
+	var mu sync.Mutex
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /services/srv-1/deploys", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(deploys.Deploy{ID: "dep-1", Status: triggerStatus})
+	})
+	mux.HandleFunc("GET /services/srv-1/deploys/dep-1", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		status := statuses[0]
+		if len(statuses) > 1 {
+			statuses = statuses[1:]
+		}
+		json.NewEncoder(w).Encode(deploys.Deploy{ID: "dep-1", Status: status})
+	})
+
+	server := testutil.NewServer(t, mux)
+	return server
+}
+
+func newTestProvider(baseURL string, timeout time.Duration) *Provider {
+	return &Provider{
+		serviceID:          "api",
+		config:             Config{ServiceID: "srv-1"},
+		api:                api2.MustNewClient(baseURL, "test-key"),
+		storage:            testutil.NewMemoryCredentialsStorage(nil),
+		deployPollInterval: time.Millisecond,
+		deployTimeout:      timeout,
+	}
+}
+
+func TestProvider_WaitForDeploy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("succeeds when the deploy becomes live", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		server := newDeployServer(t, deploys.DeployStatusCreated,
+			deploys.DeployStatusBuildInProgress,
+			deploys.DeployStatusUpdateInProgress,
+			deploys.DeployStatusLive)
+		p := newTestProvider(server.URL, time.Minute)
+
+		deploy, err := p.api.TriggerDeploy(context.Background(), "srv-1", deploys.TriggerDeployInput{ImageID: "ghcr.io/owner/repo:v1"})
+		r.NoError(err)
+		r.Equal("dep-1", deploy.ID)
+
+		r.NoError(p.waitForDeploy(context.Background(), deploy))
+	})
+
+	t.Run("fails when the deploy fails", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		server := newDeployServer(t, deploys.DeployStatusCreated,
+			deploys.DeployStatusUpdateInProgress,
+			deploys.DeployStatusUpdateFailed)
+		p := newTestProvider(server.URL, time.Minute)
+
+		err := p.waitForDeploy(context.Background(), deploys.Deploy{ID: "dep-1", Status: deploys.DeployStatusCreated})
+		r.ErrorContains(err, string(deploys.DeployStatusUpdateFailed))
+	})
+
+	t.Run("fails when the deploy is canceled", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		server := newDeployServer(t, deploys.DeployStatusCreated, deploys.DeployStatusCanceled)
+		p := newTestProvider(server.URL, time.Minute)
+
+		err := p.waitForDeploy(context.Background(), deploys.Deploy{ID: "dep-1", Status: deploys.DeployStatusCreated})
+		r.ErrorContains(err, string(deploys.DeployStatusCanceled))
+	})
+
+	t.Run("times out when the deploy never finishes", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		server := newDeployServer(t, deploys.DeployStatusCreated, deploys.DeployStatusBuildInProgress)
+		p := newTestProvider(server.URL, 50*time.Millisecond)
+
+		err := p.waitForDeploy(context.Background(), deploys.Deploy{ID: "dep-1", Status: deploys.DeployStatusCreated})
+		r.ErrorIs(err, context.DeadlineExceeded)
+	})
+}
+
+func TestProvider_findTriggeredDeploy(t *testing.T) {
+	t.Parallel()
+
+	triggeredAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	imageRef := "ghcr.io/owner/repo:v2"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /services/srv-1/deploys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(deploys.ListDeploysResponse{
+			// Started from the dashboard at the same moment, but with another image
+			{Deploy: deploys.Deploy{ID: "dep-dashboard", Image: &deploys.DeployImage{Ref: "ghcr.io/owner/repo:v1"}, CreatedAt: triggeredAt.Add(time.Second)}},
+			{Deploy: deploys.Deploy{ID: "dep-triggered", Image: &deploys.DeployImage{Ref: imageRef}, CreatedAt: triggeredAt}},
+			// The same image deployed before
+			{Deploy: deploys.Deploy{ID: "dep-earlier", Image: &deploys.DeployImage{Ref: imageRef}, CreatedAt: triggeredAt.Add(-time.Hour)}},
+		})
+	})
+	server := testutil.NewServer(t, mux)
+	p := newTestProvider(server.URL, time.Minute)
+
+	t.Run("returns the deploy of the image created since the trigger", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		deploy, err := p.findTriggeredDeploy(context.Background(), imageRef, triggeredAt)
+		r.NoError(err)
+		r.Equal("dep-triggered", deploy.ID)
+	})
+
+	t.Run("fails when no deploy matches", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		_, err := p.findTriggeredDeploy(context.Background(), imageRef, triggeredAt.Add(time.Minute))
+		r.ErrorContains(err, "not found")
+	})
+}
@@ -14,6 +14,8 @@ type Config struct {
 }
 
 type ServiceConfig struct {
+	// DependsOn lists the services which must be deployed before this one when deploying several services at once
+	DependsOn    []string                     `mapstructure:"depends_on"`
 	Environments map[string]EnvironmentConfig `mapstructure:"environments"`
 	Extras       map[string]any               `mapstructure:",remain"`
 }
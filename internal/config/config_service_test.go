@@ -27,6 +27,12 @@ services:
         some_other_key: 100
         some_other_nested_key:
           another_nested_value: false
+  service2:
+    depends_on:
+      - service1
+    environments:
+      dev:
+        env_key: 'dev_value'
 `
 
 func TestConfig(t *testing.T) {
@@ -40,6 +46,17 @@ func TestConfig(t *testing.T) {
 		r.Equal(cfg.Services["service1"].Environments["dev"].Extras["env_key"], "dev_value")
 	})
 
+	t.Run("must parse service dependencies", func(t *testing.T) {
+		cfg, err := NewConfigFromReader(configToReader(configYAML))
+		r.NoError(err)
+		r.Equal([]string{"service1"}, cfg.Services["service2"].DependsOn)
+		r.NotContains(cfg.Services["service2"].Extras, "depends_on")
+
+		cfgWithEnv, err := cfg.WithEnvironment("dev")
+		r.NoError(err)
+		r.Equal([]string{"service1"}, cfgWithEnv.Services["service2"].DependsOn)
+	})
+
 	t.Run("must parse config with environment", func(t *testing.T) {
 		cfg, err := NewConfigFromReader(configToReader(configYAML))
 		r.NoError(err)
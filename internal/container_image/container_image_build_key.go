@@ -0,0 +1,107 @@
+package container_image
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// buildKeyTagPrefix marks the tags of images pushed for a pipeline build key.
+const buildKeyTagPrefix = "build-"
+
+// isReuseUnchangedEnabled reports whether pipeline builds are skipped when the registry has the image of the build key.
+func (s *Service) isReuseUnchangedEnabled() bool {
+	build := s.config.Build
+	return build != nil && build.Pipeline != nil && (build.ReuseUnchanged == nil || *build.ReuseUnchanged)
+}
+
+// getBuildKeyTag returns the tag of the pipeline build key in the destination repository. The labels and compression
+// applied while pushing are hashed with the key, so images pushed with other ones are not reused.
+func (s *Service) getBuildKeyTag() (name.Tag, error) {
+	pipelineKey, err := s.pipelineService.GetBuildKey()
+	if err != nil {
+		return name.Tag{}, fmt.Errorf("computing pipeline build key: %w", err)
+	}
+
+	pushConfig := struct {
+		PipelineKey string            `json:"pipeline_key"`
+		Labels      map[string]string `json:"labels,omitempty"`
+		OciLabels   *bool             `json:"oci_labels,omitempty"`
+		Compression string            `json:"compression,omitempty"`
+		Level       int               `json:"level,omitempty"`
+	}{PipelineKey: pipelineKey, Labels: s.config.Labels, OciLabels: s.config.OciLabels}
+	if s.config.Compression != nil {
+		pushConfig.Compression = string(s.config.Compression.Algorithm)
+		pushConfig.Level = s.config.Compression.Level
+	}
+	encoded, err := json.Marshal(pushConfig)
+	if err != nil {
+		return name.Tag{}, fmt.Errorf("encoding build key: %w", err)
+	}
+	key := sha256.Sum256(encoded)
+
+	destRef, err := s.registry.GetImageRef()
+	if err != nil {
+		return name.Tag{}, fmt.Errorf("getting image reference from registry: %w", err)
+	}
+	destTag, err := name.NewTag(destRef, s.nameOptions()...)
+	if err != nil {
+		return name.Tag{}, fmt.Errorf("parsing destination image tag: %w", err)
+	}
+
+	return destTag.Context().Tag(buildKeyTagPrefix + hex.EncodeToString(key[:])), nil
+}
+
+// reuseUnchangedBuild looks for an image pushed before under the build key tag and reports whether it is reused.
+// When the registry has none, the key tag is pushed with the image built next. A registry that can not be checked
+// does not prevent the build.
+func (s *Service) reuseUnchangedBuild(ctx context.Context) (bool, error) {
+	s.reusedBuild, s.buildKeyTag = nil, nil
+	if !s.isReuseUnchangedEnabled() {
+		return false, nil
+	}
+
+	keyTag, err := s.getBuildKeyTag()
+	if err != nil {
+		return false, err
+	}
+
+	options, err := s.registryOptions(ctx)
+	if err != nil {
+		return false, err
+	}
+	desc, err := remote.Head(keyTag, options...)
+	switch {
+	case err == nil:
+		slog.InfoContext(ctx, "pipeline inputs unchanged, reusing the image pushed for the build key",
+			"tag", keyTag,
+			"digest", desc.Digest)
+		s.reusedBuild = &keyTag
+		return true, nil
+	case isNotFoundError(err):
+		slog.DebugContext(ctx, "no image pushed for the build key", "tag", keyTag)
+	default:
+		slog.WarnContext(ctx, "checking the registry for the build key failed, building the image", "tag", keyTag, "error", err)
+	}
+
+	s.buildKeyTag = &keyTag
+	return false, nil
+}
+
+// pushReusedBuild points the destination tags to the image of the build key tag, nothing is uploaded.
+func (s *Service) pushReusedBuild(ctx context.Context, keyTag name.Tag, pushOptions PushOptions) (v1.Hash, error) {
+	digest, err := s.PromoteImage(ctx, s, keyTag.String(), pushOptions)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("tagging reused image %s: %w", keyTag, err)
+	}
+
+	slog.InfoContext(ctx, "reused image tagged", "source", keyTag, "digest", digest)
+	return digest, nil
+}
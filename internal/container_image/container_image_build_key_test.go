@@ -0,0 +1,102 @@
+package container_image
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/build/pipeline"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/testutil"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_reuseUnchangedBuild(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	root := t.TempDir()
+	for path, content := range map[string]string{
+		"pnpm-workspace.yaml":   "packages:\n  - \"apps/*\"\n",
+		"apps/api/package.json": `{ "name": "api" }`,
+		"apps/api/index.ts":     `console.log("api")`,
+	} {
+		fullPath := filepath.Join(root, filepath.FromSlash(path))
+		r.NoError(os.MkdirAll(filepath.Dir(fullPath), 0o755))
+		r.NoError(os.WriteFile(fullPath, []byte(content), 0o644))
+	}
+	pipelineConfig := &pipeline.Config{App: "api", Cmd: []string{"node", "index.js"}}
+	pipelineSvc := pipeline.NewService(*pipelineConfig, root, pipeline.NewPnpmMonorepo(root), nil)
+
+	requests := &registryRequests{handler: ggcrregistry.New(ggcrregistry.Logger(log.New(io.Discard, "", 0)))}
+	server := testutil.NewServer(t, requests)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	image, err := random.Image(1024, 2)
+	r.NoError(err)
+	layoutDir := t.TempDir()
+	writeOciLayout(t, layoutDir, image)
+
+	newService := func(imageTag string) *Service {
+		return NewService(Config{
+			Build:  &BuildConfig{Pipeline: pipelineConfig},
+			Source: &ImageSourceConfig{Type: ImageSourceOciLayout, Path: layoutDir},
+		}, testRegistry{imageRef: host + "/team/app:" + imageTag}, nil, pipelineSvc, nil, nil)
+	}
+
+	// Nothing to reuse yet, the built image is pushed under the build key tag as well
+	svc := newService("v1")
+	reused, err := svc.reuseUnchangedBuild(context.Background())
+	r.NoError(err)
+	r.False(reused)
+	r.NotNil(svc.buildKeyTag)
+	r.True(strings.HasPrefix(svc.buildKeyTag.TagStr(), buildKeyTagPrefix))
+
+	digest, err := svc.PushImage(context.Background(), PushOptions{})
+	r.NoError(err)
+	r.ElementsMatch([]string{"v1", svc.buildKeyTag.TagStr()}, requests.manifestPuts)
+
+	// The same inputs reuse the pushed image, only the new tag is added
+	requests.reset()
+	svc = newService("v2")
+	reused, err = svc.reuseUnchangedBuild(context.Background())
+	r.NoError(err)
+	r.True(reused)
+	r.NoError(svc.BuildImage(context.Background()))
+
+	reusedDigest, err := svc.PushImage(context.Background(), PushOptions{})
+	r.NoError(err)
+	r.Equal(digest, reusedDigest)
+	r.Equal([]string{"v2"}, requests.manifestPuts)
+	r.Zero(requests.blobUploads)
+
+	v2, err := name.NewTag(host+"/team/app:v2", name.Insecure)
+	r.NoError(err)
+	desc, err := remote.Head(v2)
+	r.NoError(err)
+	r.Equal(digest, desc.Digest)
+
+	// Changed inputs get another build key
+	r.NoError(os.WriteFile(filepath.Join(root, "apps", "api", "index.ts"), []byte(`console.log("v3")`), 0o644))
+	svc = newService("v3")
+	reused, err = svc.reuseUnchangedBuild(context.Background())
+	r.NoError(err)
+	r.False(reused)
+
+	// Reuse can be turned off
+	disabled := false
+	svc = NewService(Config{
+		Build: &BuildConfig{Pipeline: pipelineConfig, ReuseUnchanged: &disabled},
+	}, testRegistry{imageRef: host + "/team/app:v4"}, nil, pipelineSvc, nil, nil)
+	reused, err = svc.reuseUnchangedBuild(context.Background())
+	r.NoError(err)
+	r.False(reused)
+	r.Nil(svc.buildKeyTag)
+}
@@ -3,6 +3,8 @@ package container_image
 import (
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/build/pipeline"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/registry"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/sbom"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/signing"
 )
 
 type BuildConfig struct {
@@ -13,6 +15,9 @@ type BuildConfig struct {
 
 	// pipeline build definition
 	Pipeline *pipeline.Config `mapstructure:"pipeline"`
+	// ReuseUnchanged skips the pipeline build and push when the registry has an image tagged with the build key
+	// of the current pipeline inputs, the image is deployed instead (default true)
+	ReuseUnchanged *bool `mapstructure:"reuse_unchanged"`
 }
 
 type CompressionAlgorithm string
@@ -25,19 +30,76 @@ const (
 
 type CompressionConfig struct {
 	Algorithm CompressionAlgorithm `mapstructure:"algorithm"`
-	Level     int                  `mapstructure:"level"` // For gzip: 1-9 (default 6), for zstd: 1-22 (default 3)
+	Level     int                  `mapstructure:"level"`   // For gzip: 1-9 (default 6), for zstd: 1-22 (default 3)
+	Workers   int                  `mapstructure:"workers"` // Layers recompressed in parallel (default: number of CPUs, at most 4)
+	Cache     *LayerCacheConfig    `mapstructure:"cache"`
+}
+
+// LayerCacheConfig configures the on-disk cache of recompressed layers shared by all pushes.
+type LayerCacheConfig struct {
+	Disabled bool   `mapstructure:"disabled"`
+	Dir      string `mapstructure:"dir"`      // default: <user cache dir>/cloudctl/layers
+	MaxSize  string `mapstructure:"max_size"` // e.g. 10GB (default 5GB), the least recently used blobs are pruned after a push
+}
+
+type SignatureMode string
+
+const (
+	// SignatureModeTag stores signatures under the sha256-<digest>.sig tag like cosign does by default
+	SignatureModeTag SignatureMode = "tag"
+	// SignatureModeReferrer attaches signatures as OCI 1.1 referrers of the image manifest
+	SignatureModeReferrer SignatureMode = "referrer"
+)
+
+// SigningConfig signs pushed images with cosign compatible signatures.
+type SigningConfig struct {
+	// Key signs the image after the push, signing is skipped when it is not set
+	Key *signing.KeyConfig `mapstructure:"key"`
+	// PublicKey is the PEM file signatures are verified with, the public part of Key is used when empty
+	PublicKey string        `mapstructure:"public_key"`
+	Mode      SignatureMode `mapstructure:"mode"` // tag (default) or referrer
+	// Required makes the deploy fail unless the image has a valid signature
+	Required bool `mapstructure:"required"`
+}
+
+// SbomConfig generates an SBOM of the pushed image and attaches it to the image as an OCI referrer.
+type SbomConfig struct {
+	Format sbom.Format `mapstructure:"format"` // spdx (default) or cyclonedx
+	// Output also writes the SBOM to the file, images of a multi-platform index get an -<os>-<arch> suffix
+	Output string `mapstructure:"output"`
+}
+
+// ProvenanceConfig attaches a SLSA provenance attestation to the pushed image.
+type ProvenanceConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// BuilderID identifies who built the image, the CI run or cloudctl://<user>@<host> when empty
+	BuilderID string `mapstructure:"builder_id"`
 }
 
 type Config struct {
 	Image       string             `mapstructure:"image"`
 	Build       *BuildConfig       `mapstructure:"build"`
+	Source      *ImageSourceConfig `mapstructure:"source"`
 	Registry    RegistryConfig     `mapstructure:"registry"`
 	Compression *CompressionConfig `mapstructure:"compression"`
+	Signing     *SigningConfig     `mapstructure:"signing"`
+	Sbom        *SbomConfig        `mapstructure:"sbom"`
+	Provenance  *ProvenanceConfig  `mapstructure:"provenance"`
+	// Labels are added to the pushed image as config labels and manifest annotations, values support placeholders.
+	// Keys are lowercased by the config loader.
+	Labels map[string]string `mapstructure:"labels"`
+	// OciLabels adds the org.opencontainers.image.* revision, source, version and created labels (default true)
+	OciLabels *bool `mapstructure:"oci_labels"`
+	// DeployByTag makes providers deploy the pushed tag instead of the immutable digest reference
+	DeployByTag bool `mapstructure:"deploy_by_tag"`
 }
 
 type RegistryConfig struct {
-	Ghcr   *registry.GithubContainerRegistryConfig `mapstructure:"ghcr"`
-	AWSEcr *registry.AwsECRConfig                  `mapstructure:"aws_ecr"`
-	GcpAr  *registry.GcpArtifactRegistryConfig     `mapstructure:"gcp_ar"`
-	Tags   []string                                `mapstructure:"tags"`
+	Ghcr      *registry.GithubContainerRegistryConfig `mapstructure:"ghcr"`
+	AWSEcr    *registry.AwsECRConfig                  `mapstructure:"aws_ecr"`
+	GcpAr     *registry.GcpArtifactRegistryConfig     `mapstructure:"gcp_ar"`
+	AzACR     *registry.AzureContainerRegistryConfig  `mapstructure:"azure_acr"`
+	DockerHub *registry.DockerHubConfig               `mapstructure:"docker_hub"`
+	Oci       *registry.OciRegistryConfig             `mapstructure:"oci"`
+	Tags      []string                                `mapstructure:"tags"`
 }
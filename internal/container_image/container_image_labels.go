@@ -0,0 +1,292 @@
+package container_image
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// Standard labels of the OCI image spec, set both as image config labels and as manifest annotations.
+const (
+	LabelRevision = "org.opencontainers.image.revision"
+	LabelSource   = "org.opencontainers.image.source"
+	LabelVersion  = "org.opencontainers.image.version"
+	LabelCreated  = "org.opencontainers.image.created"
+)
+
+func (s *Service) isOciLabelsEnabled() bool {
+	return s.config.OciLabels == nil || *s.config.OciLabels
+}
+
+// getImageLabels returns the standard labels from the git repository and the source image, merged with the configured ones.
+// The configured labels win over the standard ones.
+func (s *Service) getImageLabels(ctx context.Context, source sourceImage) (map[string]string, error) {
+	labels := make(map[string]string, len(s.config.Labels)+4)
+
+	if s.isOciLabelsEnabled() {
+		var commitTime time.Time
+		if s.gitRepoInfo != nil {
+			commitTime = s.addGitLabels(ctx, labels)
+		}
+		if created := getCreatedTime(ctx, source, commitTime); !created.IsZero() {
+			labels[LabelCreated] = created.UTC().Format(time.RFC3339)
+		}
+	}
+
+	for key, value := range s.config.Labels {
+		resolved := value
+		if s.placeholdersResolver != nil {
+			var err error
+			resolved, err = s.placeholdersResolver.ResolvePlaceholders(value)
+			if err != nil {
+				return nil, fmt.Errorf("resolving placeholders in label '%s'='%s': %w", key, value, err)
+			}
+		}
+		labels[key] = resolved
+	}
+
+	return labels, nil
+}
+
+// addGitLabels adds the revision, version and source labels, a repository without commits, tags or remote just lacks them.
+// The commit time is returned for the created label, zero without a commit.
+func (s *Service) addGitLabels(ctx context.Context, labels map[string]string) time.Time {
+	commit, err := s.gitRepoInfo.CurrentCommit()
+	if err != nil {
+		slog.WarnContext(ctx, "no git commit for the image revision label", "error", err)
+		return time.Time{}
+	}
+	labels[LabelRevision] = commit.Hash.String()
+
+	tag, err := s.gitRepoInfo.CurrentTag()
+	if err != nil {
+		slog.WarnContext(ctx, "no git tag for the image version label", "error", err)
+	} else if tag != nil {
+		labels[LabelVersion] = tag.Name().Short()
+	}
+
+	remoteURL, err := s.gitRepoInfo.RemoteURL("origin")
+	if err != nil {
+		slog.WarnContext(ctx, "no git remote for the image source label", "error", err)
+	} else if remoteURL != "" {
+		labels[LabelSource] = sourceURL(remoteURL)
+	}
+
+	return commit.Committer.When
+}
+
+// getCreatedTime returns the time for the created label. It never comes from the clock, so pushing the same build
+// again gives the same digest: SOURCE_DATE_EPOCH wins, then the commit time, then the created time of the source image.
+func getCreatedTime(ctx context.Context, source sourceImage, commitTime time.Time) time.Time {
+	if epoch := os.Getenv(lib.SourceDateEpochEnv); epoch != "" {
+		seconds, err := strconv.ParseInt(epoch, 10, 64)
+		if err == nil {
+			return time.Unix(seconds, 0)
+		}
+		slog.WarnContext(ctx, "ignoring invalid SOURCE_DATE_EPOCH", "value", epoch, "error", err)
+	}
+	if !commitTime.IsZero() {
+		return commitTime
+	}
+
+	image := source.image
+	if source.index != nil {
+		manifest, err := source.index.IndexManifest()
+		if err != nil || len(manifest.Manifests) == 0 {
+			return time.Time{}
+		}
+		if image, err = source.index.Image(manifest.Manifests[0].Digest); err != nil {
+			return time.Time{}
+		}
+	}
+	if image == nil {
+		return time.Time{}
+	}
+	configFile, err := image.ConfigFile()
+	if err != nil {
+		return time.Time{}
+	}
+	return configFile.Created.Time
+}
+
+// sourceURL turns the git remote into the browsable https URL of the repository, without credentials.
+// git@github.com:org/api.git becomes https://github.com/org/api.
+func sourceURL(remote string) string {
+	remote = strings.TrimSuffix(remote, ".git")
+
+	if !strings.Contains(remote, "://") {
+		if _, hostPath, ok := strings.Cut(remote, "@"); ok {
+			host, path, _ := strings.Cut(hostPath, ":")
+			return "https://" + host + "/" + strings.TrimPrefix(path, "/")
+		}
+		return remote
+	}
+
+	parsed, err := url.Parse(remote)
+	if err != nil {
+		return remote
+	}
+	parsed.User = nil
+	if parsed.Scheme == "ssh" || parsed.Scheme == "git" {
+		parsed.Scheme = "https"
+		parsed.Host = parsed.Hostname()
+	}
+	return parsed.String()
+}
+
+// labelSource adds the labels to the image, or to every image of the index and to the index itself.
+// Images of an unknown platform, like build attestations, are left as they are.
+func labelSource(source sourceImage, labels map[string]string) (sourceImage, error) {
+	if len(labels) == 0 {
+		return source, nil
+	}
+
+	if source.index == nil {
+		image, err := labelImage(source.image, labels)
+		if err != nil {
+			return sourceImage{}, err
+		}
+		return sourceImage{image: image}, nil
+	}
+
+	indexManifest, err := source.index.IndexManifest()
+	if err != nil {
+		return sourceImage{}, fmt.Errorf("reading index manifest: %w", err)
+	}
+
+	mediaType := indexManifest.MediaType
+	if mediaType == "" {
+		mediaType = types.OCIImageIndex
+	}
+	result := mutate.IndexMediaType(empty.Index, mediaType)
+	annotations := maps.Clone(indexManifest.Annotations)
+	if annotations == nil {
+		annotations = make(map[string]string, len(labels))
+	}
+	maps.Copy(annotations, labels)
+	result = mutate.Annotations(result, annotations).(v1.ImageIndex)
+
+	for _, desc := range indexManifest.Manifests {
+		descriptor := v1.Descriptor{Platform: desc.Platform, Annotations: desc.Annotations}
+
+		if !desc.MediaType.IsImage() {
+			child, err := source.index.ImageIndex(desc.Digest)
+			if err != nil {
+				return sourceImage{}, fmt.Errorf("reading nested index %s: %w", desc.Digest, err)
+			}
+			result = mutate.AppendManifests(result, mutate.IndexAddendum{Add: child, Descriptor: descriptor})
+			continue
+		}
+
+		image, err := source.index.Image(desc.Digest)
+		if err != nil {
+			return sourceImage{}, fmt.Errorf("reading image %s: %w", desc.Digest, err)
+		}
+		if desc.Platform != nil && desc.Platform.OS != "unknown" {
+			image, err = labelImage(image, labels)
+			if err != nil {
+				return sourceImage{}, fmt.Errorf("labeling image %s: %w", desc.Digest, err)
+			}
+		}
+		result = mutate.AppendManifests(result, mutate.IndexAddendum{Add: image, Descriptor: descriptor})
+	}
+
+	return sourceImage{index: result}, nil
+}
+
+// labelImage sets the labels in the image config and, for OCI manifests, as manifest annotations.
+// The image is returned unchanged when it already has every label, so its digest stays the same.
+func labelImage(image v1.Image, labels map[string]string) (v1.Image, error) {
+	configFile, err := image.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("getting image config: %w", err)
+	}
+
+	changed := false
+	for key, value := range labels {
+		if configFile.Config.Labels[key] != value {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return image, nil
+	}
+
+	newConfig := configFile.DeepCopy()
+	if newConfig.Config.Labels == nil {
+		newConfig.Config.Labels = make(map[string]string, len(labels))
+	}
+	maps.Copy(newConfig.Config.Labels, labels)
+
+	result, err := mutate.ConfigFile(image, newConfig)
+	if err != nil {
+		return nil, fmt.Errorf("setting image labels: %w", err)
+	}
+
+	// Docker schema 2 manifests have no annotations
+	mediaType, err := image.MediaType()
+	if err != nil {
+		return nil, fmt.Errorf("getting image media type: %w", err)
+	}
+	if mediaType == types.OCIManifestSchema1 {
+		result = mutate.Annotations(result, labels).(v1.Image)
+	}
+
+	return result, nil
+}
+
+// GetImageLabels reads the labels of the image from the registry, for an index the annotations of the index.
+// Images in other registries than the service registry are read with the docker credentials.
+func (s *Service) GetImageLabels(ctx context.Context, ref string) (map[string]string, error) {
+	parsed, err := name.ParseReference(ref, s.nameOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing image reference '%s': %w", ref, err)
+	}
+
+	options, err := s.referenceOptions(ctx, parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := remote.Get(parsed, options...)
+	if err != nil {
+		return nil, fmt.Errorf("getting image %s: %w", parsed, err)
+	}
+
+	if desc.MediaType.IsIndex() {
+		index, err := desc.ImageIndex()
+		if err != nil {
+			return nil, fmt.Errorf("reading image index %s: %w", parsed, err)
+		}
+		indexManifest, err := index.IndexManifest()
+		if err != nil {
+			return nil, fmt.Errorf("reading image index %s: %w", parsed, err)
+		}
+		return indexManifest.Annotations, nil
+	}
+
+	image, err := desc.Image()
+	if err != nil {
+		return nil, fmt.Errorf("reading image %s: %w", parsed, err)
+	}
+	configFile, err := image.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("reading image config %s: %w", parsed, err)
+	}
+	return configFile.Config.Labels, nil
+}
@@ -0,0 +1,103 @@
+package container_image
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"golang.org/x/term"
+)
+
+// PromoteImage copies the image ref of the source service to every destination tag of this service and returns its digest.
+// The manifest is copied as is, so the promoted image has the same digest as the source one, even when the registries differ.
+// Blobs the destination registry already has are not uploaded again.
+func (s *Service) PromoteImage(ctx context.Context, from *Service, ref string, pushOptions PushOptions) (v1.Hash, error) {
+	sourceRef, err := name.ParseReference(ref, from.nameOptions()...)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("%w - parsing source image reference '%s': %s", lib.BadUserInputError, ref, err)
+	}
+
+	sourceOptions, err := from.referenceOptions(ctx, sourceRef)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	sourceOptions = append(sourceOptions, from.remoteOptions()...)
+
+	desc, err := remote.Get(sourceRef, sourceOptions...)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("getting source image %s: %w", sourceRef, err)
+	}
+
+	var source sourceImage
+	if desc.MediaType.IsIndex() {
+		source.index, err = desc.ImageIndex()
+	} else {
+		source.image, err = desc.Image()
+	}
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("reading source image %s: %w", sourceRef, err)
+	}
+
+	if pushOptions.Platform != "" {
+		if err := checkImagePlatform(ctx, source, pushOptions.Platform); err != nil {
+			return v1.Hash{}, err
+		}
+	}
+
+	destRef, err := s.registry.GetImageRef()
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("getting image reference from registry: %w", err)
+	}
+	destTags, err := s.getDestinationTags(ctx, destRef)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+
+	authOption, err := s.getAuthOption()
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	options := append([]remote.Option{remote.WithContext(ctx), authOption}, s.remoteOptions()...)
+
+	tagsState, err := s.getDestinationTagsState(destTags, desc.Digest, options)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+
+	startTime := time.Now()
+	switch {
+	case len(tagsState.missing) == 0:
+		slog.InfoContext(ctx, "destination tags already up to date",
+			"tags", tagsState.upToDate,
+			"digest", desc.Digest)
+	case tagsState.manifestExists:
+		slog.InfoContext(ctx, "tagging manifest already present in the registry",
+			"tags", tagsState.missing,
+			"digest", desc.Digest)
+		err = s.copyManifestToTags(destTags[0].Context().Digest(desc.Digest.String()), tagsState.missing, options)
+	default:
+		slog.InfoContext(ctx, "copying image to remote registry",
+			"source", sourceRef,
+			"tags", tagsState.missing,
+			"digest", desc.Digest)
+		tty := term.IsTerminal(int(os.Stdout.Fd()))
+		err = s.writeImage(ctx, source.taggable(), tagsState.missing, authOption, tty, os.Stdout, os.Stderr)
+	}
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("copying image %s to %s: %w", sourceRef, destTags[0], err)
+	}
+
+	slog.InfoContext(ctx, "image promoted successfully",
+		"source", sourceRef,
+		"destination", destRef,
+		"digest", desc.Digest,
+		"duration", fmt.Sprintf("%f seconds", time.Since(startTime).Seconds()))
+
+	return desc.Digest, nil
+}
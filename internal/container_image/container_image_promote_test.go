@@ -0,0 +1,71 @@
+package container_image
+
+import (
+	"context"
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/testutil"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_PromoteImage(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	sourceRef, sourceDigest := pushTestImage(t)
+	from := NewService(Config{}, testRegistry{imageRef: sourceRef}, nil, nil, nil, nil)
+
+	requests := &registryRequests{handler: ggcrregistry.New(ggcrregistry.Logger(log.New(io.Discard, "", 0)))}
+	server := testutil.NewServer(t, requests)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	to := NewService(Config{Registry: RegistryConfig{Tags: []string{"prod"}}}, testRegistry{imageRef: host + "/prod/app:v1"}, nil, nil, nil, nil)
+
+	digest, err := to.PromoteImage(context.Background(), from, sourceRef, PushOptions{})
+	r.NoError(err)
+	r.Equal(sourceDigest.DigestStr(), digest.String())
+	r.ElementsMatch([]string{"v1", "prod"}, requests.manifestPuts)
+	r.Positive(requests.blobUploads)
+
+	prod, err := name.NewTag(host+"/prod/app:prod", name.Insecure)
+	r.NoError(err)
+	desc, err := remote.Head(prod)
+	r.NoError(err)
+	r.Equal(digest, desc.Digest)
+
+	// Promoting the same image again changes nothing
+	requests.reset()
+	_, err = to.PromoteImage(context.Background(), from, sourceDigest.String(), PushOptions{})
+	r.NoError(err)
+	r.Empty(requests.manifestPuts)
+	r.Zero(requests.blobUploads)
+}
+
+func TestService_PromoteImage_platformMismatch(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	sourceRef, _ := pushTestImage(t)
+	sourceTag, err := name.NewTag(sourceRef, name.Insecure)
+	r.NoError(err)
+	r.NoError(remote.WriteIndex(sourceTag, mutate.AppendManifests(empty.Index, platformAddendum(t, "arm64"))))
+	from := NewService(Config{}, testRegistry{imageRef: sourceRef}, nil, nil, nil, nil)
+
+	requests := &registryRequests{handler: ggcrregistry.New(ggcrregistry.Logger(log.New(io.Discard, "", 0)))}
+	server := testutil.NewServer(t, requests)
+	to := NewService(Config{}, testRegistry{imageRef: strings.TrimPrefix(server.URL, "http://") + "/prod/app:v1"}, nil, nil, nil, nil)
+
+	_, err = to.PromoteImage(context.Background(), from, sourceRef, PushOptions{Platform: lib.PlatformLinuxAmd64})
+	r.ErrorIs(err, ImagePlatformMismatchError)
+	r.Empty(requests.manifestPuts)
+	r.Zero(requests.blobUploads)
+}
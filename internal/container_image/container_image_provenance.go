@@ -0,0 +1,219 @@
+package container_image
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/provenance"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/signing"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// IsProvenanceEnabled reports whether pushed images get a provenance attestation.
+func (s *Service) IsProvenanceEnabled() bool {
+	return s.config.Provenance != nil && s.config.Provenance.Enabled
+}
+
+// AttachProvenance attaches a SLSA provenance attestation of the last build to the image as an OCI referrer.
+// The attestation is signed with the signing key when signing is enabled. Images already having one are skipped,
+// since the attestation of the build that produced the digest is the one to keep.
+func (s *Service) AttachProvenance(ctx context.Context, image name.Digest) error {
+	options, err := s.registryOptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	existing, err := listReferrers(image, string(provenance.EnvelopeMediaType), options)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		slog.InfoContext(ctx, "image already has a provenance attestation", "image", image)
+		return nil
+	}
+
+	digest, err := v1.NewHash(image.DigestStr())
+	if err != nil {
+		return fmt.Errorf("parsing image digest: %w", err)
+	}
+
+	source, err := s.getProvenanceSource(ctx)
+	if err != nil {
+		return err
+	}
+	statement := provenance.NewStatement(image.Context().Name(), digest, s.getProvenanceBuild(), source, s.getProvenanceBuilder())
+
+	var key crypto.Signer
+	if s.IsSigningEnabled() {
+		key, err = signing.LoadPrivateKey(*s.config.Signing.Key, s.signingKeys)
+		if err != nil {
+			return fmt.Errorf("loading signing key: %w", err)
+		}
+	}
+	envelope, err := provenance.NewEnvelope(statement, key)
+	if err != nil {
+		return err
+	}
+
+	layer := artifactLayer{MediaType: provenance.EnvelopeMediaType, Content: envelope}
+	annotations := map[string]string{provenance.PredicateTypeAnnotation: provenance.PredicateType}
+	attestation, err := pushReferrer(image, string(provenance.EnvelopeMediaType), []artifactLayer{layer}, annotations, options)
+	if err != nil {
+		return fmt.Errorf("pushing provenance attestation of %s: %w", image, err)
+	}
+
+	slog.InfoContext(ctx, "provenance attestation attached to image",
+		"image", image,
+		"attestation", attestation,
+		"signed", key != nil)
+	return nil
+}
+
+// GetImageProvenance returns the provenance statements attached to the image. When a public key is given,
+// only the statements with a valid signature are returned and provenance.UnsignedEnvelopeError when there are none.
+func (s *Service) GetImageProvenance(ctx context.Context, image name.Digest, publicKeyPath string) ([]provenance.Statement, error) {
+	var publicKey crypto.PublicKey
+	if publicKeyPath != "" {
+		key, err := s.getSigningPublicKey(publicKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		publicKey = key
+	}
+
+	options, err := s.registryOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	attestations, err := listReferrers(image, string(provenance.EnvelopeMediaType), options)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []provenance.Statement
+	for _, attestation := range attestations {
+		for _, layer := range attestation.Layers {
+			if layer.MediaType != provenance.EnvelopeMediaType {
+				continue
+			}
+			content, err := readBlob(image.Context(), layer, options)
+			if err != nil {
+				return nil, err
+			}
+
+			statement, err := provenance.OpenEnvelope(content, publicKey)
+			if errors.Is(err, provenance.UnsignedEnvelopeError) {
+				slog.DebugContext(ctx, "skipping provenance attestation without a valid signature", "image", image)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			statements = append(statements, statement)
+		}
+	}
+
+	if publicKey != nil && len(statements) == 0 && len(attestations) > 0 {
+		return nil, fmt.Errorf("%w for %s", provenance.UnsignedEnvelopeError, image)
+	}
+	return statements, nil
+}
+
+// getProvenanceBuild describes the last build, or only the push when the image was built outside cloudctl.
+func (s *Service) getProvenanceBuild() provenance.Build {
+	if s.build != nil {
+		return *s.build
+	}
+
+	parameters := map[string]any{"source_type": string(s.getSourceType())}
+	if s.config.Source != nil && s.config.Source.Path != "" {
+		parameters["source_path"] = s.config.Source.Path
+	}
+	if s.getSourceType() == ImageSourceDaemon {
+		if image, err := s.GetSourceImageRef(); err == nil {
+			parameters["image"] = image
+		}
+	}
+	return provenance.Build{Type: provenance.BuildTypePush, Parameters: parameters}
+}
+
+// getProvenanceSource reads the git state of the repository, nil when there is no repository.
+// A detached HEAD, as CI checkouts usually are, just leaves the branch out.
+func (s *Service) getProvenanceSource(ctx context.Context) (*provenance.Source, error) {
+	if s.gitRepoInfo == nil {
+		return nil, nil
+	}
+
+	commit, err := s.gitRepoInfo.CurrentCommit()
+	if err != nil {
+		return nil, fmt.Errorf("getting current git commit: %w", err)
+	}
+	source := &provenance.Source{Commit: commit.Hash.String()}
+
+	if branch, err := s.gitRepoInfo.CurrentBranch(); err == nil {
+		source.Branch = branch
+	} else {
+		slog.DebugContext(ctx, "no git branch for the provenance attestation", "error", err)
+	}
+
+	tag, err := s.gitRepoInfo.CurrentTag()
+	if err != nil {
+		return nil, fmt.Errorf("getting current git tag: %w", err)
+	}
+	if tag != nil {
+		source.Tag = tag.Name().Short()
+	}
+
+	source.Dirty, err = s.gitRepoInfo.IsDirty()
+	if err != nil {
+		return nil, fmt.Errorf("getting git worktree state: %w", err)
+	}
+
+	source.RemoteURL, err = s.gitRepoInfo.RemoteURL("origin")
+	if err != nil {
+		return nil, fmt.Errorf("getting git remote: %w", err)
+	}
+
+	return source, nil
+}
+
+func (s *Service) getProvenanceBuilder() provenance.Builder {
+	builder := provenance.Builder{Version: map[string]string{"go": runtime.Version()}}
+	if s.config.Provenance != nil {
+		builder.ID = s.config.Provenance.BuilderID
+	}
+	if builder.ID == "" {
+		builder.ID = defaultBuilderID()
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		builder.Version["cloudctl"] = info.Main.Version
+	}
+	return builder
+}
+
+// defaultBuilderID identifies the GitHub Actions run when running in one and the user and host otherwise.
+func defaultBuilderID() string {
+	if os.Getenv(lib.GithubActionsEnv) == "true" {
+		return fmt.Sprintf("%s/%s/actions/runs/%s", os.Getenv(lib.GithubServerURLEnv), os.Getenv(lib.GithubRepositoryEnv), os.Getenv(lib.GithubRunIDEnv))
+	}
+
+	username := "unknown"
+	if current, err := user.Current(); err == nil {
+		username = current.Username
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("cloudctl://%s@%s", username, hostname)
+}
@@ -0,0 +1,108 @@
+package container_image
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/provenance"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/signing"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/stretchr/testify/require"
+)
+
+// testGitRepository is a repository on a detached HEAD, like CI checkouts are.
+type testGitRepository struct {
+	commit    plumbing.Hash
+	committed time.Time
+}
+
+func (r testGitRepository) CurrentBranch() (string, error) {
+	return "", errors.New("HEAD is not pointing to a branch")
+}
+func (r testGitRepository) CurrentCommit() (*object.Commit, error) {
+	return &object.Commit{Hash: r.commit, Committer: object.Signature{When: r.committed}}, nil
+}
+func (r testGitRepository) CurrentTag() (*plumbing.Reference, error) {
+	return plumbing.NewHashReference(plumbing.NewTagReferenceName("v1.0.0"), r.commit), nil
+}
+func (r testGitRepository) TagsPointingAt(hash plumbing.Hash) ([]*plumbing.Reference, error) {
+	return nil, nil
+}
+func (r testGitRepository) IsDirty() (bool, error) { return false, nil }
+func (r testGitRepository) RemoteURL(remote string) (string, error) {
+	return "https://github.com/org/api.git", nil
+}
+func (r testGitRepository) CurrentUser() (string, error)                { return "Jane Doe <jane@example.com>", nil }
+func (r testGitRepository) ChangedFiles(since string) ([]string, error) { return nil, nil }
+
+func TestService_AttachProvenance(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	imageRef, image := pushTestImage(t, ggcrregistry.WithReferrersSupport(true))
+	keyPath := writeSigningKey(t, signing.KeyTypeEcdsa)
+	commit := plumbing.NewHash("4b825dc642cb6eb9a060e54bf8d69288fbee4904")
+
+	svc := NewService(Config{
+		Signing:    &SigningConfig{Key: &signing.KeyConfig{File: keyPath}},
+		Provenance: &ProvenanceConfig{Enabled: true, BuilderID: "https://ci.example.com/runs/1"},
+	}, testRegistry{imageRef: imageRef}, nil, nil, nil, testGitRepository{commit: commit})
+	startedOn := time.Now().UTC().Truncate(time.Second)
+	svc.build = &provenance.Build{
+		Type:       provenance.BuildTypeCmd,
+		Parameters: map[string]any{"cmd": []string{"docker", "build", "."}},
+		StartedOn:  startedOn,
+		FinishedOn: startedOn.Add(time.Minute),
+	}
+
+	r.NoError(svc.AttachProvenance(context.Background(), image))
+	// The attestation of the first build is kept
+	r.NoError(svc.AttachProvenance(context.Background(), image))
+
+	statements, err := svc.GetImageProvenance(context.Background(), image, "")
+	r.NoError(err)
+	r.Len(statements, 1)
+
+	statement := statements[0]
+	r.Equal(image.Context().Name(), statement.Subject[0].Name)
+	r.Equal(image.DigestStr(), "sha256:"+statement.Subject[0].Digest["sha256"])
+	r.Equal(provenance.BuildTypeCmd, statement.Predicate.BuildDefinition.BuildType)
+	r.Equal("https://ci.example.com/runs/1", statement.Predicate.RunDetails.Builder.ID)
+	r.Equal(startedOn, *statement.Predicate.RunDetails.Metadata.StartedOn)
+	r.Equal([]provenance.ResourceDescriptor{{
+		URI:         "git+https://github.com/org/api.git@refs/tags/v1.0.0",
+		Digest:      map[string]string{"gitCommit": commit.String()},
+		Annotations: map[string]any{"tag": "v1.0.0", "dirty": false},
+	}}, statement.Predicate.BuildDefinition.ResolvedDependencies)
+
+	// Only attestations signed with the key are trusted
+	other := NewService(Config{
+		Signing: &SigningConfig{Key: &signing.KeyConfig{File: writeSigningKey(t, signing.KeyTypeEcdsa)}},
+	}, testRegistry{imageRef: imageRef}, nil, nil, nil, nil)
+	_, err = other.GetImageProvenance(context.Background(), image, "")
+	r.NoError(err)
+
+	publicKeyPath := writePublicKeyFile(t, keyPath)
+	statements, err = other.GetImageProvenance(context.Background(), image, publicKeyPath)
+	r.NoError(err)
+	r.Len(statements, 1)
+	_, err = svc.GetImageProvenance(context.Background(), image, writePublicKeyFile(t, writeSigningKey(t, signing.KeyTypeEcdsa)))
+	r.ErrorIs(err, provenance.UnsignedEnvelopeError)
+}
+
+func writePublicKeyFile(t *testing.T, privateKeyPath string) string {
+	key, err := signing.LoadPrivateKey(signing.KeyConfig{File: privateKeyPath}, nil)
+	require.NoError(t, err)
+	publicPem, err := signing.MarshalPublicKey(key.Public())
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "cosign.pub")
+	require.NoError(t, os.WriteFile(path, publicPem, 0o644))
+	return path
+}
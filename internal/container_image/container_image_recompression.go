@@ -0,0 +1,651 @@
+package container_image
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/layercache"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/docker/go-units"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// maxDefaultRecompressionWorkers caps the number of layers recompressed at once when no worker count is configured.
+// Every worker holds an encoder window in memory, so the memory use grows with the worker count and the level.
+const maxDefaultRecompressionWorkers = 4
+
+// recompressedLayer implements v1.Layer for a layer whose recompressed content lives in a temporary file.
+// Only the hashes and the size are kept in memory, the uncompressed content is read from the original layer.
+type recompressedLayer struct {
+	original v1.Layer
+	// compressed opens the recompressed content: a temporary or cached file, or the blob already in the registry
+	compressed func() (io.ReadCloser, error)
+	mediaType  types.MediaType
+	diffID     v1.Hash
+	digest     v1.Hash
+	size       int64
+}
+
+func (l *recompressedLayer) Digest() (v1.Hash, error) {
+	return l.digest, nil
+}
+
+func (l *recompressedLayer) DiffID() (v1.Hash, error) {
+	return l.diffID, nil
+}
+
+func (l *recompressedLayer) Compressed() (io.ReadCloser, error) {
+	return l.compressed()
+}
+
+func (l *recompressedLayer) Uncompressed() (io.ReadCloser, error) {
+	return l.original.Uncompressed()
+}
+
+func (l *recompressedLayer) Size() (int64, error) {
+	return l.size, nil
+}
+
+func (l *recompressedLayer) MediaType() (types.MediaType, error) {
+	return l.mediaType, nil
+}
+
+// contextReader stops a copy as soon as the context is cancelled instead of streaming the whole layer.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// remoteBlobLookup returns the blob with the digest from the destination registry when the registry already has it.
+type remoteBlobLookup func(ctx context.Context, digest v1.Hash) (v1.Layer, bool, error)
+
+type recompressOptions struct {
+	algorithm CompressionAlgorithm
+	level     int
+	workers   int
+	// cache is optional, without it every layer is compressed again
+	cache *layercache.Cache
+	// remoteBlob is optional, it lets layers the cache knows the digest of skip compression when the registry has the blob
+	remoteBlob remoteBlobLookup
+	// held keeps the cached blobs read by the push open, it is set by recompressImage
+	held *heldFiles
+}
+
+// heldFiles keeps cache blobs open until the push is done. The cache is shared, a prune run by another push only
+// unlinks a blob then, and the content stays readable through the open file.
+type heldFiles struct {
+	mu    sync.Mutex
+	files []*os.File
+}
+
+// open opens the file now and returns a reader of it for recompressedLayer.compressed, nil holds nothing and reopens
+// the path on every read instead.
+func (h *heldFiles) open(path string) (func() (io.ReadCloser, error), error) {
+	if h == nil {
+		return openFile(path), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	h.mu.Lock()
+	h.files = append(h.files, f)
+	h.mu.Unlock()
+
+	size := stat.Size()
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(io.NewSectionReader(f, 0, size)), nil
+	}, nil
+}
+
+func (h *heldFiles) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, f := range h.files {
+		f.Close()
+	}
+	h.files = nil
+}
+
+func recompressionWorkers(config *CompressionConfig) int {
+	if config != nil && config.Workers > 0 {
+		return config.Workers
+	}
+	return min(runtime.NumCPU(), maxDefaultRecompressionWorkers)
+}
+
+// GetLayerCache returns the cache of recompressed layers, or nil when it is disabled or nothing is compressed.
+func (c *CompressionConfig) GetLayerCache() (*layercache.Cache, error) {
+	if c == nil || c.Algorithm == CompressionNone {
+		return nil, nil
+	}
+	if c.Cache != nil && c.Cache.Disabled {
+		return nil, nil
+	}
+	if _, err := c.GetLayerCacheMaxSize(); err != nil {
+		return nil, err
+	}
+
+	if c.Cache != nil && c.Cache.Dir != "" {
+		return layercache.NewCache(c.Cache.Dir), nil
+	}
+	dir, err := layercache.DefaultDir()
+	if err != nil {
+		return nil, fmt.Errorf("getting layer cache directory: %w", err)
+	}
+	return layercache.NewCache(dir), nil
+}
+
+// GetLayerCacheMaxSize returns the configured cache size limit in bytes.
+func (c *CompressionConfig) GetLayerCacheMaxSize() (int64, error) {
+	if c == nil || c.Cache == nil || c.Cache.MaxSize == "" {
+		return layercache.DefaultMaxSize, nil
+	}
+
+	maxSize, err := units.RAMInBytes(c.Cache.MaxSize)
+	if err != nil {
+		return 0, fmt.Errorf("%w - invalid layer cache max_size '%s': %w", lib.BadUserInputError, c.Cache.MaxSize, err)
+	}
+	return maxSize, nil
+}
+
+// pruneLayerCache keeps the cache within its size limit once the pushed layers are no longer read.
+// Failures are only logged, the push itself already succeeded.
+func (s *Service) pruneLayerCache(ctx context.Context, cache *layercache.Cache) {
+	maxSize, err := s.config.Compression.GetLayerCacheMaxSize()
+	if err != nil {
+		slog.WarnContext(ctx, "skipping layer cache pruning", "error", err)
+		return
+	}
+
+	result, err := cache.Prune(maxSize)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to prune layer cache", "dir", cache.Dir(), "error", err)
+		return
+	}
+	if result.RemovedBlobs > 0 || result.RemovedMetadata > 0 {
+		slog.InfoContext(ctx, "pruned layer cache",
+			"dir", cache.Dir(),
+			"removed_layers", result.RemovedBlobs,
+			"removed_digests", result.RemovedMetadata,
+			"freed", units.BytesSize(float64(result.FreedBytes)),
+			"remaining", units.BytesSize(float64(result.RemainingBytes)))
+	}
+}
+
+func openFile(path string) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}
+}
+
+// recompressImage recompresses all layers of an image using the specified compression algorithm and level.
+// This can significantly reduce image size and improve push/pull performance when using zstd compression.
+// Layers are streamed into files under a temporary directory by up to workers layers at a time,
+// the returned cleanup removes the directory and must only be called once the image is no longer read.
+func (s *Service) recompressImage(ctx context.Context, img v1.Image, opts recompressOptions) (v1.Image, func(), error) {
+	noop := func() {}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, noop, fmt.Errorf("getting image layers: %w", err)
+	}
+
+	configFile, err := img.ConfigFile()
+	if err != nil {
+		return nil, noop, fmt.Errorf("getting image config: %w", err)
+	}
+
+	// Calculate original image size
+	var originalSize int64
+	for _, layer := range layers {
+		size, err := layer.Size()
+		if err != nil {
+			slog.WarnContext(ctx, "failed to get layer size", "error", err)
+			continue
+		}
+		originalSize += size
+	}
+
+	// Start with an empty image and add recompressed layers
+	result := empty.Image
+
+	// Create a copy of the config to avoid modifying the original.
+	// Clear DiffIDs since AppendLayers will rebuild them from the recompressed layers.
+	// Without this, we'd have original DiffIDs + appended DiffIDs = 2x the expected count.
+	newConfig := *configFile
+	newConfig.RootFS.DiffIDs = nil
+
+	// Set the config (without DiffIDs - they'll be added by AppendLayers)
+	result, err = mutate.ConfigFile(result, &newConfig)
+	if err != nil {
+		return nil, noop, fmt.Errorf("setting image config: %w", err)
+	}
+
+	workers := max(1, min(opts.workers, len(layers)))
+
+	slog.InfoContext(ctx, "recompressing image layers",
+		"algorithm", opts.algorithm,
+		"level", opts.level,
+		"layer_count", len(layers),
+		"workers", workers,
+		"original_size_mb", fmt.Sprintf("%.2f", float64(originalSize)/(1024*1024)))
+
+	dir, err := os.MkdirTemp("", "cloudctl-layers-*")
+	if err != nil {
+		return nil, noop, fmt.Errorf("creating directory for recompressed layers: %w", err)
+	}
+	opts.held = &heldFiles{}
+	cleanup := func() {
+		opts.held.close()
+		if err := os.RemoveAll(dir); err != nil {
+			slog.WarnContext(ctx, "failed to remove recompressed layers", "dir", dir, "error", err)
+		}
+	}
+
+	recompressCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type layerJob struct {
+		index int
+		layer v1.Layer
+	}
+	jobs := make(chan layerJob)
+	errs := make(chan error, len(layers))
+	recompressedLayers := make([]v1.Layer, len(layers))
+	layerSizes := make([]int64, len(layers))
+
+	for range workers {
+		go func() {
+			for job := range jobs {
+				layer, size, err := s.recompressLayer(recompressCtx, job.layer, opts, job.index, dir)
+				if err != nil {
+					cancel()
+					errs <- fmt.Errorf("recompressing layer %d: %w", job.index, err)
+					continue
+				}
+				recompressedLayers[job.index] = layer
+				layerSizes[job.index] = size
+				errs <- nil
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for i, layer := range layers {
+			select {
+			case jobs <- layerJob{index: i, layer: layer}:
+			case <-recompressCtx.Done():
+				for range layers[i:] {
+					errs <- recompressCtx.Err()
+				}
+				return
+			}
+		}
+	}()
+
+	// Layers skipped after a failure report the cancellation, the failure itself is the error worth returning
+	var firstErr error
+	for range layers {
+		err := <-errs
+		if err != nil && (firstErr == nil || errors.Is(firstErr, context.Canceled) && !errors.Is(err, context.Canceled)) {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		cleanup()
+		return nil, noop, firstErr
+	}
+
+	// Layers are appended in their original order no matter which worker finished first
+	result, err = mutate.AppendLayers(result, recompressedLayers...)
+	if err != nil {
+		cleanup()
+		return nil, noop, fmt.Errorf("appending recompressed layers: %w", err)
+	}
+
+	var newSize int64
+	for _, size := range layerSizes {
+		newSize += size
+	}
+
+	var savingsPercent float64
+	if originalSize > 0 {
+		savingsPercent = (1 - float64(newSize)/float64(originalSize)) * 100
+	}
+
+	slog.InfoContext(ctx, "image recompression complete",
+		"original_size_mb", fmt.Sprintf("%.2f", float64(originalSize)/(1024*1024)),
+		"new_size_mb", fmt.Sprintf("%.2f", float64(newSize)/(1024*1024)),
+		"savings_percent", fmt.Sprintf("%.1f%%", savingsPercent))
+
+	return result, cleanup, nil
+}
+
+// recompressSource recompresses the image, or every image of a multi-platform index.
+// Layers shared by several platforms are compressed once when the layer cache is enabled.
+func (s *Service) recompressSource(ctx context.Context, source sourceImage, opts recompressOptions) (sourceImage, func(), error) {
+	if source.index == nil {
+		image, cleanup, err := s.recompressImage(ctx, source.image, opts)
+		if err != nil {
+			return sourceImage{}, cleanup, err
+		}
+		return sourceImage{image: image}, cleanup, nil
+	}
+
+	indexManifest, err := source.index.IndexManifest()
+	if err != nil {
+		return sourceImage{}, func() {}, fmt.Errorf("reading index manifest: %w", err)
+	}
+
+	cleanups := make([]func(), 0, len(indexManifest.Manifests))
+	cleanup := func() {
+		for _, c := range cleanups {
+			c()
+		}
+	}
+
+	mediaType := indexManifest.MediaType
+	if mediaType == "" {
+		mediaType = types.OCIImageIndex
+	}
+	result := mutate.IndexMediaType(empty.Index, mediaType)
+	if len(indexManifest.Annotations) > 0 {
+		result = mutate.Annotations(result, indexManifest.Annotations).(v1.ImageIndex)
+	}
+
+	for _, desc := range indexManifest.Manifests {
+		// Only the descriptor fields describing the manifest are kept, the rest is computed from the recompressed image
+		descriptor := v1.Descriptor{Platform: desc.Platform, Annotations: desc.Annotations}
+
+		if !desc.MediaType.IsImage() {
+			child, err := source.index.ImageIndex(desc.Digest)
+			if err != nil {
+				cleanup()
+				return sourceImage{}, func() {}, fmt.Errorf("reading nested index %s: %w", desc.Digest, err)
+			}
+			result = mutate.AppendManifests(result, mutate.IndexAddendum{Add: child, Descriptor: descriptor})
+			continue
+		}
+
+		image, err := source.index.Image(desc.Digest)
+		if err != nil {
+			cleanup()
+			return sourceImage{}, func() {}, fmt.Errorf("reading image %s: %w", desc.Digest, err)
+		}
+
+		recompressed, imageCleanup, err := s.recompressImage(ctx, image, opts)
+		cleanups = append(cleanups, imageCleanup)
+		if err != nil {
+			cleanup()
+			return sourceImage{}, func() {}, fmt.Errorf("recompressing image %s: %w", desc.Digest, err)
+		}
+		result = mutate.AppendManifests(result, mutate.IndexAddendum{Add: recompressed, Descriptor: descriptor})
+	}
+
+	return sourceImage{index: result}, cleanup, nil
+}
+
+// recompressLayer streams a single layer through the compressor into a file in dir,
+// hashing the uncompressed and compressed content on the way instead of holding the layer in memory.
+// A layer found in the cache, or known to the cache and present in the registry, is not compressed again.
+// Returns the recompressed layer, its size in bytes, and any error.
+func (s *Service) recompressLayer(ctx context.Context, layer v1.Layer, opts recompressOptions, layerIndex int, dir string) (v1.Layer, int64, error) {
+	algorithm := opts.algorithm
+
+	var mediaType types.MediaType
+	switch algorithm {
+	case CompressionZstd:
+		mediaType = types.OCILayerZStd
+	case CompressionGzip:
+		mediaType = types.OCILayer
+	case CompressionNone:
+		mediaType = types.OCIUncompressedLayer
+	default:
+		return nil, 0, fmt.Errorf("unsupported compression algorithm: %s", algorithm)
+	}
+
+	var cacheKey layercache.Key
+	if opts.cache != nil {
+		diffID, err := layer.DiffID()
+		if err != nil {
+			return nil, 0, fmt.Errorf("getting layer diff ID: %w", err)
+		}
+		cacheKey = layercache.Key{DiffID: diffID, Algorithm: string(algorithm), Level: opts.level}
+
+		cachedLayer, ok := s.cachedLayer(ctx, layer, cacheKey, opts)
+		if ok {
+			size, _ := cachedLayer.Size()
+			return cachedLayer, size, nil
+		}
+	}
+
+	uncompressed, err := layer.Uncompressed()
+	if err != nil {
+		return nil, 0, fmt.Errorf("getting uncompressed layer: %w", err)
+	}
+	defer uncompressed.Close()
+
+	file, err := os.CreateTemp(dir, fmt.Sprintf("layer-%d-*", layerIndex))
+	if err != nil {
+		return nil, 0, fmt.Errorf("creating layer file: %w", err)
+	}
+	defer file.Close()
+
+	diffIDHasher := sha256.New()
+	digestHasher := sha256.New()
+	compressedSize := &countingWriter{}
+	source := io.TeeReader(&contextReader{ctx: ctx, r: uncompressed}, diffIDHasher)
+	destination := io.MultiWriter(file, digestHasher, compressedSize)
+
+	var uncompressedSize int64
+	switch algorithm {
+	case CompressionZstd:
+		uncompressedSize, err = compressWithZstd(destination, source, opts.level)
+	case CompressionGzip:
+		uncompressedSize, err = compressWithGzip(destination, source, opts.level)
+	case CompressionNone:
+		uncompressedSize, err = io.Copy(destination, source)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("compressing with %s: %w", algorithm, err)
+	}
+
+	if err := file.Close(); err != nil {
+		return nil, 0, fmt.Errorf("writing layer file: %w", err)
+	}
+
+	originalSize, _ := layer.Size()
+	var ratio float64
+	if uncompressedSize > 0 {
+		ratio = float64(compressedSize.n) / float64(uncompressedSize) * 100
+	}
+	slog.DebugContext(ctx, "recompressed layer",
+		"layer_index", layerIndex,
+		"algorithm", algorithm,
+		"original_size", originalSize,
+		"new_size", compressedSize.n,
+		"ratio", fmt.Sprintf("%.2f%%", ratio))
+
+	newLayer := &recompressedLayer{
+		original:   layer,
+		compressed: openFile(file.Name()),
+		mediaType:  mediaType,
+		diffID:     sha256Hash(diffIDHasher),
+		digest:     sha256Hash(digestHasher),
+		size:       compressedSize.n,
+	}
+
+	if opts.cache != nil {
+		// Held open before the file moves into the cache, where another push may prune it while this one reads it
+		compressed, err := opts.held.open(file.Name())
+		if err != nil {
+			return nil, 0, fmt.Errorf("opening layer file: %w", err)
+		}
+		newLayer.compressed = compressed
+
+		// The cache is an optimisation, a layer that could not be stored is still pushed from the open file
+		_, err = opts.cache.Put(cacheKey, layercache.Entry{
+			DiffID:    newLayer.diffID,
+			Digest:    newLayer.digest,
+			Size:      newLayer.size,
+			MediaType: mediaType,
+		}, file.Name())
+		if err != nil {
+			slog.WarnContext(ctx, "failed to store recompressed layer in the cache", "layer_index", layerIndex, "error", err)
+		}
+	}
+
+	return newLayer, compressedSize.n, nil
+}
+
+// cachedLayer returns the recompressed layer from the cache, or backed by the registry blob
+// when the cached blob was pruned but the registry still has a blob with the cached digest.
+func (s *Service) cachedLayer(ctx context.Context, layer v1.Layer, key layercache.Key, opts recompressOptions) (v1.Layer, bool) {
+	l := slog.With("diff_id", key.DiffID, "algorithm", key.Algorithm, "level", key.Level)
+
+	entry, ok, err := opts.cache.Get(key)
+	if err != nil {
+		l.WarnContext(ctx, "failed to read the layer cache", "error", err)
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+
+	cached := &recompressedLayer{
+		original:  layer,
+		mediaType: entry.MediaType,
+		diffID:    entry.DiffID,
+		digest:    entry.Digest,
+		size:      entry.Size,
+	}
+
+	if entry.BlobPath != "" {
+		compressed, err := opts.held.open(entry.BlobPath)
+		if err == nil {
+			l.DebugContext(ctx, "reusing cached recompressed layer", "digest", entry.Digest)
+			cached.compressed = compressed
+			return cached, true
+		}
+		// Pruned since the lookup, the registry may still have it
+		l.DebugContext(ctx, "cached recompressed layer is gone", "error", err)
+	}
+
+	if opts.remoteBlob == nil {
+		return nil, false
+	}
+	remoteLayer, exists, err := opts.remoteBlob(ctx, entry.Digest)
+	if err != nil {
+		l.WarnContext(ctx, "failed to check the recompressed layer in the registry", "digest", entry.Digest, "error", err)
+		return nil, false
+	}
+	if !exists {
+		return nil, false
+	}
+
+	l.DebugContext(ctx, "registry already has the recompressed layer", "digest", entry.Digest)
+	cached.compressed = remoteLayer.Compressed
+	return cached, true
+}
+
+func sha256Hash(h hash.Hash) v1.Hash {
+	return v1.Hash{
+		Algorithm: "sha256",
+		Hex:       hex.EncodeToString(h.Sum(nil)),
+	}
+}
+
+// compressWithZstd streams src into dst using zstd algorithm at the specified level and returns the number of bytes read.
+func compressWithZstd(dst io.Writer, src io.Reader, level int) (int64, error) {
+	// Map level to zstd encoder level (1-22, with reasonable defaults)
+	encoderLevel := zstd.EncoderLevelFromZstd(level)
+	if level <= 0 {
+		encoderLevel = zstd.SpeedDefault // level 3
+	}
+
+	// Layers are already compressed in parallel, a single goroutine per encoder keeps the memory use predictable
+	encoder, err := zstd.NewWriter(dst, zstd.WithEncoderLevel(encoderLevel), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return 0, fmt.Errorf("creating zstd encoder: %w", err)
+	}
+
+	n, err := io.Copy(encoder, src)
+	if err != nil {
+		encoder.Close()
+		return n, fmt.Errorf("writing to zstd encoder: %w", err)
+	}
+
+	if err := encoder.Close(); err != nil {
+		return n, fmt.Errorf("closing zstd encoder: %w", err)
+	}
+
+	return n, nil
+}
+
+// compressWithGzip streams src into dst using gzip algorithm at the specified level and returns the number of bytes read.
+func compressWithGzip(dst io.Writer, src io.Reader, level int) (int64, error) {
+	// Map level to gzip level (1-9)
+	if level <= 0 {
+		level = gzip.DefaultCompression
+	}
+	if level > 9 {
+		level = 9
+	}
+
+	encoder, err := gzip.NewWriterLevel(dst, level)
+	if err != nil {
+		return 0, fmt.Errorf("creating gzip encoder: %w", err)
+	}
+
+	n, err := io.Copy(encoder, src)
+	if err != nil {
+		encoder.Close()
+		return n, fmt.Errorf("writing to gzip encoder: %w", err)
+	}
+
+	if err := encoder.Close(); err != nil {
+		return n, fmt.Errorf("closing gzip encoder: %w", err)
+	}
+
+	return n, nil
+}
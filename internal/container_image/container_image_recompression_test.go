@@ -0,0 +1,363 @@
+package container_image
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/layercache"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/google/go-containerregistry/pkg/v1/validate"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_recompressImage(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		algorithm CompressionAlgorithm
+		level     int
+		mediaType types.MediaType
+	}{
+		{algorithm: CompressionZstd, level: 3, mediaType: types.OCILayerZStd},
+		{algorithm: CompressionGzip, level: 6, mediaType: types.OCILayer},
+		{algorithm: CompressionNone, mediaType: types.OCIUncompressedLayer},
+	} {
+		t.Run(string(tc.algorithm), func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			image, err := random.Image(64*1024, 5)
+			r.NoError(err)
+			originalLayers, err := image.Layers()
+			r.NoError(err)
+
+			svc := NewService(Config{}, nil, nil, nil, nil, nil)
+			recompressed, cleanup, err := svc.recompressImage(context.Background(), image, recompressOptions{algorithm: tc.algorithm, level: tc.level, workers: 3})
+			r.NoError(err)
+			defer cleanup()
+
+			r.NoError(validate.Image(recompressed, validate.Fast))
+
+			layers, err := recompressed.Layers()
+			r.NoError(err)
+			r.Len(layers, len(originalLayers))
+
+			for i, layer := range layers {
+				mediaType, err := layer.MediaType()
+				r.NoError(err)
+				r.Equal(tc.mediaType, mediaType)
+
+				// The uncompressed content and with it the DiffID stays the same, in the original order
+				originalDiffID, err := originalLayers[i].DiffID()
+				r.NoError(err)
+				diffID, err := layer.DiffID()
+				r.NoError(err)
+				r.Equal(originalDiffID, diffID)
+
+				compressed, err := layer.Compressed()
+				r.NoError(err)
+				content, err := io.ReadAll(compressed)
+				r.NoError(compressed.Close())
+				r.NoError(err)
+
+				sum := sha256.Sum256(content)
+				digest, err := layer.Digest()
+				r.NoError(err)
+				r.Equal(v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(sum[:])}, digest)
+
+				size, err := layer.Size()
+				r.NoError(err)
+				r.Equal(int64(len(content)), size)
+			}
+		})
+	}
+
+	t.Run("cleanup removes the layer files", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		image, err := random.Image(1024, 2)
+		r.NoError(err)
+
+		svc := NewService(Config{}, nil, nil, nil, nil, nil)
+		recompressed, cleanup, err := svc.recompressImage(context.Background(), image, recompressOptions{algorithm: CompressionZstd, level: 3, workers: 2})
+		r.NoError(err)
+
+		layers, err := recompressed.Layers()
+		r.NoError(err)
+		cleanup()
+
+		_, err = layers[0].Compressed()
+		r.ErrorIs(err, os.ErrNotExist)
+	})
+
+	t.Run("stops on a cancelled context", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		image, err := random.Image(1024, 4)
+		r.NoError(err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		svc := NewService(Config{}, nil, nil, nil, nil, nil)
+		_, _, err = svc.recompressImage(ctx, image, recompressOptions{algorithm: CompressionGzip, level: 6, workers: 2})
+		r.ErrorIs(err, context.Canceled)
+	})
+}
+
+// failingLayer fails when its content is read, so a test notices a layer that is compressed again.
+type failingLayer struct {
+	v1.Layer
+}
+
+func (l failingLayer) Uncompressed() (io.ReadCloser, error) {
+	return nil, errors.New("layer content must not be read")
+}
+
+func layerDigests(t *testing.T, image v1.Image) []v1.Hash {
+	layers, err := image.Layers()
+	require.NoError(t, err)
+
+	digests := make([]v1.Hash, 0, len(layers))
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		require.NoError(t, err)
+		digests = append(digests, digest)
+	}
+	return digests
+}
+
+func TestService_recompressImage_cache(t *testing.T) {
+	t.Parallel()
+
+	image, err := random.Image(32*1024, 3)
+	require.NoError(t, err)
+	layers, err := image.Layers()
+	require.NoError(t, err)
+
+	// The same layers, but reading their content fails
+	untouchable, err := mutate.AppendLayers(empty.Image, func() []v1.Layer {
+		wrapped := make([]v1.Layer, 0, len(layers))
+		for _, layer := range layers {
+			wrapped = append(wrapped, failingLayer{layer})
+		}
+		return wrapped
+	}()...)
+	require.NoError(t, err)
+
+	t.Run("reuses cached layers", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		svc := NewService(Config{}, nil, nil, nil, nil, nil)
+		opts := recompressOptions{algorithm: CompressionZstd, level: 3, workers: 2, cache: layercache.NewCache(t.TempDir())}
+
+		first, cleanup, err := svc.recompressImage(context.Background(), image, opts)
+		r.NoError(err)
+		cleanup()
+
+		second, cleanup, err := svc.recompressImage(context.Background(), untouchable, opts)
+		r.NoError(err)
+		defer cleanup()
+
+		r.Equal(layerDigests(t, first), layerDigests(t, second))
+		r.NoError(validate.Image(second, validate.Fast))
+	})
+
+	t.Run("keeps reading cached layers pruned by a concurrent push", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		cache := layercache.NewCache(t.TempDir())
+		svc := NewService(Config{}, nil, nil, nil, nil, nil)
+		opts := recompressOptions{algorithm: CompressionZstd, level: 3, workers: 2, cache: cache}
+
+		first, cleanup, err := svc.recompressImage(context.Background(), image, opts)
+		r.NoError(err)
+		cleanup()
+
+		second, cleanup, err := svc.recompressImage(context.Background(), untouchable, opts)
+		r.NoError(err)
+		defer cleanup()
+		secondLayers, err := second.Layers()
+		r.NoError(err)
+
+		// A read is in progress while the other push prunes the whole cache
+		reader, err := secondLayers[0].Compressed()
+		r.NoError(err)
+		defer reader.Close()
+		digestHasher := sha256.New()
+		_, err = io.CopyN(digestHasher, reader, 16)
+		r.NoError(err)
+
+		pruned := make(chan error)
+		go func() {
+			result, err := cache.Prune(0)
+			if err == nil && result.RemovedBlobs != len(layers) {
+				err = fmt.Errorf("pruned %d blobs, expected %d", result.RemovedBlobs, len(layers))
+			}
+			pruned <- err
+		}()
+		r.NoError(<-pruned)
+
+		_, err = io.Copy(digestHasher, reader)
+		r.NoError(err)
+		r.Equal(layerDigests(t, first)[0].Hex, hex.EncodeToString(digestHasher.Sum(nil)))
+
+		// Layers not opened yet are still readable as well
+		r.NoError(validate.Image(second, validate.Fast))
+	})
+
+	t.Run("skips layers the registry has after the blobs were pruned", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		cache := layercache.NewCache(t.TempDir())
+		svc := NewService(Config{}, nil, nil, nil, nil, nil)
+		opts := recompressOptions{algorithm: CompressionZstd, level: 3, workers: 2, cache: cache}
+
+		first, cleanup, err := svc.recompressImage(context.Background(), image, opts)
+		r.NoError(err)
+		cleanup()
+
+		_, err = cache.Prune(0)
+		r.NoError(err)
+
+		var checked []v1.Hash
+		var mu sync.Mutex
+		opts.remoteBlob = func(ctx context.Context, digest v1.Hash) (v1.Layer, bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			checked = append(checked, digest)
+			return static.NewLayer(nil, types.OCILayerZStd), true, nil
+		}
+
+		second, cleanup, err := svc.recompressImage(context.Background(), untouchable, opts)
+		r.NoError(err)
+		defer cleanup()
+
+		r.Equal(layerDigests(t, first), layerDigests(t, second))
+		r.ElementsMatch(layerDigests(t, first), checked)
+	})
+
+	t.Run("compresses layers missing in the registry again", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		cache := layercache.NewCache(t.TempDir())
+		svc := NewService(Config{}, nil, nil, nil, nil, nil)
+		opts := recompressOptions{algorithm: CompressionZstd, level: 3, workers: 2, cache: cache}
+
+		first, cleanup, err := svc.recompressImage(context.Background(), image, opts)
+		r.NoError(err)
+		cleanup()
+
+		_, err = cache.Prune(0)
+		r.NoError(err)
+
+		opts.remoteBlob = func(ctx context.Context, digest v1.Hash) (v1.Layer, bool, error) {
+			return nil, false, nil
+		}
+
+		second, cleanup, err := svc.recompressImage(context.Background(), image, opts)
+		r.NoError(err)
+		defer cleanup()
+
+		r.Equal(layerDigests(t, first), layerDigests(t, second))
+	})
+}
+
+func TestCompressionConfig_GetLayerCache(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		config   *CompressionConfig
+		dir      string
+		maxSize  int64
+		disabled bool
+		invalid  bool
+	}{
+		{name: "no compression", config: nil, maxSize: layercache.DefaultMaxSize, disabled: true},
+		{name: "uncompressed", config: &CompressionConfig{Algorithm: CompressionNone}, maxSize: layercache.DefaultMaxSize, disabled: true},
+		{name: "disabled", config: &CompressionConfig{Algorithm: CompressionZstd, Cache: &LayerCacheConfig{Disabled: true, MaxSize: "1GB"}}, maxSize: 1 << 30, disabled: true},
+		{name: "configured", config: &CompressionConfig{Algorithm: CompressionZstd, Cache: &LayerCacheConfig{Dir: "/tmp/layers", MaxSize: "2GB"}}, dir: "/tmp/layers", maxSize: 2 << 30},
+		{name: "invalid max size", config: &CompressionConfig{Algorithm: CompressionGzip, Cache: &LayerCacheConfig{MaxSize: "lots"}}, invalid: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			cache, err := tc.config.GetLayerCache()
+			maxSize, maxSizeErr := tc.config.GetLayerCacheMaxSize()
+			if tc.invalid {
+				r.ErrorIs(err, lib.BadUserInputError)
+				r.ErrorIs(maxSizeErr, lib.BadUserInputError)
+				return
+			}
+			r.NoError(err)
+			r.NoError(maxSizeErr)
+			r.Equal(tc.maxSize, maxSize)
+
+			if tc.disabled {
+				r.Nil(cache)
+				return
+			}
+			r.Equal(tc.dir, cache.Dir())
+		})
+	}
+}
+
+func TestService_recompressSource_index(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	index := mutate.AppendManifests(empty.Index,
+		platformAddendum(t, "amd64"),
+		platformAddendum(t, "arm64"),
+	)
+	source := sourceImage{index: index}
+
+	svc := NewService(Config{}, nil, nil, nil, nil, nil)
+	recompressed, cleanup, err := svc.recompressSource(context.Background(), source, recompressOptions{algorithm: CompressionZstd, level: 3, workers: 2})
+	r.NoError(err)
+	defer cleanup()
+
+	r.NotNil(recompressed.index)
+	r.NoError(validate.Index(recompressed.index, validate.Fast))
+
+	platforms, err := recompressed.Platforms()
+	r.NoError(err)
+	expected, err := source.Platforms()
+	r.NoError(err)
+	r.Equal(expected, platforms)
+
+	manifest, err := recompressed.index.IndexManifest()
+	r.NoError(err)
+	for _, desc := range manifest.Manifests {
+		image, err := recompressed.index.Image(desc.Digest)
+		r.NoError(err)
+		layers, err := image.Layers()
+		r.NoError(err)
+		for _, layer := range layers {
+			mediaType, err := layer.MediaType()
+			r.NoError(err)
+			r.Equal(types.OCILayerZStd, mediaType)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package container_image
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// emptyConfigMediaType is the OCI empty descriptor used as the config of artifacts that carry everything in their layers.
+const emptyConfigMediaType types.MediaType = "application/vnd.oci.empty.v1+json"
+
+// artifactManifest is an OCI 1.1 image manifest with an artifact type, v1.Manifest has no field for it.
+type artifactManifest struct {
+	SchemaVersion int64             `json:"schemaVersion"`
+	MediaType     types.MediaType   `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        v1.Descriptor     `json:"config"`
+	Layers        []v1.Descriptor   `json:"layers"`
+	Subject       *v1.Descriptor    `json:"subject,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+type artifactLayer struct {
+	MediaType   types.MediaType
+	Content     []byte
+	Annotations map[string]string
+}
+
+// rawManifest lets remote.Put upload a manifest built by hand.
+type rawManifest struct {
+	raw       []byte
+	mediaType types.MediaType
+}
+
+func (m rawManifest) RawManifest() ([]byte, error)        { return m.raw, nil }
+func (m rawManifest) MediaType() (types.MediaType, error) { return m.mediaType, nil }
+
+// pushReferrer uploads the layers as an artifact referring to the subject manifest and returns the artifact digest.
+// For registries without the referrers API go-containerregistry maintains the sha256-<digest> referrers tag instead.
+func pushReferrer(subject name.Digest, artifactType string, layers []artifactLayer, annotations map[string]string, options []remote.Option) (v1.Hash, error) {
+	subjectDesc, err := remote.Head(subject, options...)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("getting subject manifest %s: %w", subject, err)
+	}
+
+	repository := subject.Context()
+	config := static.NewLayer([]byte("{}"), emptyConfigMediaType)
+	configDesc, err := uploadBlob(repository, config, nil, options)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+
+	manifest := artifactManifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		ArtifactType:  artifactType,
+		Config:        configDesc,
+		Layers:        make([]v1.Descriptor, 0, len(layers)),
+		Subject:       &v1.Descriptor{MediaType: subjectDesc.MediaType, Size: subjectDesc.Size, Digest: subjectDesc.Digest},
+		Annotations:   annotations,
+	}
+	for _, layer := range layers {
+		desc, err := uploadBlob(repository, static.NewLayer(layer.Content, layer.MediaType), layer.Annotations, options)
+		if err != nil {
+			return v1.Hash{}, err
+		}
+		manifest.Layers = append(manifest.Layers, desc)
+	}
+
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("encoding artifact manifest: %w", err)
+	}
+	digest, _, err := v1.SHA256(bytes.NewReader(raw))
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("hashing artifact manifest: %w", err)
+	}
+
+	if err := remote.Put(repository.Digest(digest.String()), rawManifest{raw: raw, mediaType: manifest.MediaType}, options...); err != nil {
+		return v1.Hash{}, fmt.Errorf("pushing artifact manifest: %w", err)
+	}
+	return digest, nil
+}
+
+func uploadBlob(repository name.Repository, layer v1.Layer, annotations map[string]string, options []remote.Option) (v1.Descriptor, error) {
+	if err := remote.WriteLayer(repository, layer, options...); err != nil {
+		return v1.Descriptor{}, fmt.Errorf("uploading blob: %w", err)
+	}
+
+	digest, err := layer.Digest()
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	size, err := layer.Size()
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	mediaType, err := layer.MediaType()
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	return v1.Descriptor{MediaType: mediaType, Size: size, Digest: digest, Annotations: annotations}, nil
+}
+
+// listReferrers returns the manifests of the artifacts with the type referring to the subject.
+// The manifests are read instead of trusting the listed artifact type, since the referrers tag fallback records the config media type.
+func listReferrers(subject name.Digest, artifactType string, options []remote.Option) ([]artifactManifest, error) {
+	index, err := remote.Referrers(subject, options...)
+	if err != nil {
+		return nil, fmt.Errorf("listing referrers of %s: %w", subject, err)
+	}
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("reading referrers of %s: %w", subject, err)
+	}
+
+	var manifests []artifactManifest
+	for _, desc := range indexManifest.Manifests {
+		if desc.ArtifactType != artifactType && desc.ArtifactType != string(emptyConfigMediaType) {
+			continue
+		}
+
+		manifest, err := getArtifactManifest(subject.Context().Digest(desc.Digest.String()), options)
+		if err != nil {
+			return nil, err
+		}
+		if manifest.ArtifactType == artifactType {
+			manifests = append(manifests, manifest)
+		}
+	}
+	return manifests, nil
+}
+
+func getArtifactManifest(ref name.Digest, options []remote.Option) (artifactManifest, error) {
+	var manifest artifactManifest
+
+	desc, err := remote.Get(ref, options...)
+	if err != nil {
+		return manifest, fmt.Errorf("getting manifest %s: %w", ref, err)
+	}
+	if err := json.Unmarshal(desc.Manifest, &manifest); err != nil {
+		return manifest, fmt.Errorf("decoding manifest %s: %w", ref, err)
+	}
+	return manifest, nil
+}
+
+// readBlob downloads a small blob like a signature payload or an attestation.
+func readBlob(repository name.Repository, desc v1.Descriptor, options []remote.Option) ([]byte, error) {
+	layer, err := remote.Layer(repository.Digest(desc.Digest.String()), options...)
+	if err != nil {
+		return nil, fmt.Errorf("getting blob %s: %w", desc.Digest, err)
+	}
+	reader, err := layer.Compressed()
+	if err != nil {
+		return nil, fmt.Errorf("reading blob %s: %w", desc.Digest, err)
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("reading blob %s: %w", desc.Digest, err)
+	}
+	return content, nil
+}
@@ -0,0 +1,185 @@
+package container_image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/sbom"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// sbomTarget is an image the SBOM describes, the images of a multi-platform index get one SBOM each.
+type sbomTarget struct {
+	image    v1.Image
+	digest   v1.Hash
+	platform *v1.Platform
+}
+
+// attachSbom generates the SBOM of every pushed image and attaches it as a referrer of the image manifest.
+// Images already having an SBOM of the configured format are not attached again.
+func (s *Service) attachSbom(ctx context.Context, source sourceImage, repository name.Repository) error {
+	format := s.config.Sbom.Format
+	if format == "" {
+		format = sbom.FormatSpdx
+	}
+	mediaType, err := format.MediaType()
+	if err != nil {
+		return err
+	}
+
+	workspace, lockfile, err := s.getSbomWorkspace(ctx)
+	if err != nil {
+		return err
+	}
+
+	targets, err := getSbomTargets(source)
+	if err != nil {
+		return err
+	}
+
+	options, err := s.registryOptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		subject := repository.Digest(target.digest.String())
+
+		existing, err := listReferrers(subject, string(mediaType), options)
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 && s.config.Sbom.Output == "" {
+			slog.InfoContext(ctx, "image already has an SBOM", "image", subject, "format", format)
+			continue
+		}
+
+		doc, err := sbom.Generate(sbom.Input{
+			Repository: repository.String(),
+			Digest:     target.digest,
+			Image:      target.image,
+			Workspace:  workspace,
+			Lockfile:   lockfile,
+		})
+		if err != nil {
+			return fmt.Errorf("generating SBOM of %s: %w", subject, err)
+		}
+		content, err := sbom.Encode(doc, format)
+		if err != nil {
+			return err
+		}
+
+		if len(existing) == 0 {
+			annotations := map[string]string{
+				"org.opencontainers.image.created": doc.Created.Format(time.RFC3339),
+			}
+			layers := []artifactLayer{{MediaType: mediaType, Content: content}}
+			sbomDigest, err := pushReferrer(subject, string(mediaType), layers, annotations, options)
+			if err != nil {
+				return fmt.Errorf("pushing SBOM of %s: %w", subject, err)
+			}
+			slog.InfoContext(ctx, "SBOM attached to image",
+				"image", subject,
+				"sbom", sbomDigest,
+				"format", format,
+				"components", len(doc.Components))
+		}
+
+		if s.config.Sbom.Output != "" {
+			outputPath := sbomOutputPath(s.config.Sbom.Output, target.platform, len(targets) > 1)
+			if err := os.WriteFile(outputPath, content, 0o644); err != nil {
+				return fmt.Errorf("writing SBOM to %s: %w", outputPath, err)
+			}
+			slog.InfoContext(ctx, "SBOM written", "path", outputPath, "image", subject)
+		}
+	}
+
+	return nil
+}
+
+// getSbomWorkspace returns the workspace graph and the lockfile of the app for pipeline builds.
+func (s *Service) getSbomWorkspace(ctx context.Context) (*sbom.Workspace, string, error) {
+	if s.pipelineService == nil || s.config.Build == nil || s.config.Build.Pipeline == nil {
+		return nil, "", nil
+	}
+
+	appPackage, dependencies, err := s.pipelineService.GetAppDependencyGraph()
+	if err != nil {
+		return nil, "", fmt.Errorf("getting app dependency graph: %w", err)
+	}
+
+	workspace := &sbom.Workspace{
+		App: sbom.WorkspacePackage{
+			Name:         appPackage.Manifest.Name,
+			Version:      appPackage.Manifest.Version,
+			Path:         appPackage.Path,
+			Dependencies: appPackage.Manifest.Dependencies,
+		},
+	}
+	for _, dependency := range dependencies {
+		workspace.Packages = append(workspace.Packages, sbom.WorkspacePackage{
+			Name:         dependency.Manifest.Name,
+			Version:      dependency.Manifest.Version,
+			Path:         dependency.Path,
+			Dependencies: dependency.Manifest.Dependencies,
+		})
+	}
+
+	lockfile := filepath.Join(s.pipelineService.GetRepoRoot(), "pnpm-lock.yaml")
+	if _, err := os.Stat(lockfile); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, "", fmt.Errorf("checking pnpm lockfile: %w", err)
+		}
+		slog.WarnContext(ctx, "pnpm lockfile not found, the SBOM only lists the packages found in the image", "path", lockfile)
+		lockfile = ""
+	}
+
+	return workspace, lockfile, nil
+}
+
+func getSbomTargets(source sourceImage) ([]sbomTarget, error) {
+	if source.index == nil {
+		digest, err := source.image.Digest()
+		if err != nil {
+			return nil, fmt.Errorf("getting image digest: %w", err)
+		}
+		return []sbomTarget{{image: source.image, digest: digest}}, nil
+	}
+
+	indexManifest, err := source.index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("reading index manifest: %w", err)
+	}
+
+	var targets []sbomTarget
+	for _, desc := range indexManifest.Manifests {
+		// Build attestations are stored next to the images with an unknown platform
+		if desc.Platform == nil || desc.Platform.OS == "unknown" {
+			continue
+		}
+		image, err := source.index.Image(desc.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("getting %s image from index: %w", desc.Platform, err)
+		}
+		targets = append(targets, sbomTarget{image: image, digest: desc.Digest, platform: desc.Platform})
+	}
+	return targets, nil
+}
+
+// sbomOutputPath adds the platform before the extension when the index has several images, sbom.json becomes sbom-linux-arm64.json.
+func sbomOutputPath(output string, platform *v1.Platform, perPlatform bool) string {
+	if !perPlatform || platform == nil {
+		return output
+	}
+
+	ext := filepath.Ext(output)
+	suffix := strings.ReplaceAll(platform.String(), "/", "-")
+	return strings.TrimSuffix(output, ext) + "-" + suffix + ext
+}
@@ -0,0 +1,82 @@
+package container_image
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/sbom"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/testutil"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_PushImage_sbom(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		format   sbom.Format
+		registry []ggcrregistry.Option
+	}{
+		{name: "spdx referrer", format: "", registry: []ggcrregistry.Option{ggcrregistry.WithReferrersSupport(true)}},
+		{name: "cyclonedx referrers tag fallback", format: sbom.FormatCycloneDx},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			server := testutil.NewServer(t, ggcrregistry.New(append(tc.registry, ggcrregistry.Logger(log.New(io.Discard, "", 0)))...))
+			imageRef := strings.TrimPrefix(server.URL, "http://") + "/team/app:v1"
+
+			image, err := random.Image(256, 1)
+			r.NoError(err)
+			layoutDir := t.TempDir()
+			writeOciLayout(t, layoutDir, image)
+			output := filepath.Join(t.TempDir(), "sbom.json")
+
+			svc := NewService(Config{
+				Source: &ImageSourceConfig{Type: ImageSourceOciLayout, Path: layoutDir},
+				Sbom:   &SbomConfig{Format: tc.format, Output: output},
+			}, testRegistry{imageRef: imageRef}, nil, nil, nil, nil)
+
+			digest, err := svc.PushImage(context.Background(), PushOptions{})
+			r.NoError(err)
+
+			mediaType := sbom.SpdxMediaType
+			if tc.format == sbom.FormatCycloneDx {
+				mediaType = sbom.CycloneDxMediaType
+			}
+			subject, err := name.NewDigest(strings.TrimSuffix(imageRef, ":v1")+"@"+digest.String(), name.Insecure)
+			r.NoError(err)
+			options, err := svc.registryOptions(context.Background())
+			r.NoError(err)
+
+			referrers, err := listReferrers(subject, string(mediaType), options)
+			r.NoError(err)
+			r.Len(referrers, 1)
+			r.Equal(mediaType, referrers[0].Layers[0].MediaType)
+
+			content, err := readBlob(subject.Context(), referrers[0].Layers[0], options)
+			r.NoError(err)
+			r.True(json.Valid(content))
+			written, err := os.ReadFile(output)
+			r.NoError(err)
+			r.Equal(content, written)
+
+			// Pushing again keeps the attached SBOM
+			_, err = svc.PushImage(context.Background(), PushOptions{})
+			r.NoError(err)
+			referrers, err = listReferrers(subject, string(mediaType), options)
+			r.NoError(err)
+			r.Len(referrers, 1)
+		})
+	}
+}
@@ -1,142 +1,256 @@
 package container_image
 
 import (
-	"bytes"
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
-	"maps"
 	"math"
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/build/pipeline"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/layercache"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/provenance"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/registry"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/placeholders"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/placeholders/git"
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/daemon"
-	"github.com/google/go-containerregistry/pkg/v1/empty"
-	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
-	"github.com/google/go-containerregistry/pkg/v1/types"
-	"github.com/klauspost/compress/gzip"
-	"github.com/klauspost/compress/zstd"
 	"golang.org/x/term"
 )
 
+var (
+	ImagePlatformMismatchError = errors.New("image platform does not match the service platform")
+)
+
 type Service struct {
 	config               Config
 	registry             registry.Registry
 	placeholdersResolver *placeholders.Service
 	pipelineService      *pipeline.Service
+	signingKeys          lib.CredentialsStorage
+	gitRepoInfo          git.RepositoryInfoService
+	// build describes the last BuildImage run for the provenance attestation
+	build *provenance.Build
+	// pipelineTarball is where the last pipeline build wrote its image when it is handed directly to the push
+	pipelineTarball     string
+	pipelineTarballTemp bool
+	// buildKeyTag is pushed along the destination tags when the last pipeline build had no image to reuse
+	buildKeyTag *name.Tag
+	// reusedBuild is the build key tag of the image the last BuildImage run reused instead of building
+	reusedBuild *name.Tag
+}
+
+func NewService(config Config, registry registry.Registry, resolver *placeholders.Service, pipeline *pipeline.Service, signingKeys lib.CredentialsStorage, gitRepoInfo git.RepositoryInfoService) *Service {
+	return &Service{
+		config:               config,
+		registry:             registry,
+		placeholdersResolver: resolver,
+		pipelineService:      pipeline,
+		signingKeys:          signingKeys,
+		gitRepoInfo:          gitRepoInfo,
+	}
 }
 
-// recompressedLayer implements v1.Layer with proper DiffID support for recompressed layers.
-type recompressedLayer struct {
-	compressedData   []byte
-	uncompressedData []byte
-	mediaType        types.MediaType
-	diffID           v1.Hash
-	digest           v1.Hash
+func (s *Service) GetRegistry() registry.Registry {
+	return s.registry
 }
 
-func newRecompressedLayer(compressedData, uncompressedData []byte, mediaType types.MediaType) (v1.Layer, error) {
-	// Compute DiffID (hash of uncompressed content)
-	diffIDHash := sha256.Sum256(uncompressedData)
-	diffID := v1.Hash{
-		Algorithm: "sha256",
-		Hex:       hex.EncodeToString(diffIDHash[:]),
+func (s *Service) BuildImage(ctx context.Context) error {
+	if s.config.Build == nil {
+		slog.InfoContext(ctx, "no image build configured")
+		return nil
 	}
 
-	// Compute Digest (hash of compressed content)
-	digestHash := sha256.Sum256(compressedData)
-	digest := v1.Hash{
-		Algorithm: "sha256",
-		Hex:       hex.EncodeToString(digestHash[:]),
+	build := &provenance.Build{StartedOn: time.Now().UTC()}
+	var err error
+	switch {
+	case len(s.config.Build.Cmd) > 0:
+		build.Type = provenance.BuildTypeCmd
+		build.Parameters, err = s.buildImageViaCmd(ctx, s.config.Build.Cmd, s.config.Build.Env, s.config.Build.Dir)
+	case s.config.Build.Pipeline != nil:
+		var reused bool
+		reused, err = s.reuseUnchangedBuild(ctx)
+		if err != nil {
+			return err
+		}
+		if reused {
+			return nil
+		}
+
+		build.Type = provenance.BuildTypePipeline
+		build.Parameters = map[string]any{"pipeline": s.config.Build.Pipeline}
+		var output pipeline.Output
+		output, err = s.getPipelineOutput()
+		if err == nil {
+			err = s.pipelineService.ProcessPipeline(ctx, output)
+		}
+		if err != nil {
+			if closeErr := s.Close(); closeErr != nil {
+				slog.WarnContext(ctx, "failed to remove the pipeline image of the failed build", "error", closeErr)
+			}
+		}
+	default:
+		return fmt.Errorf("no image build strategy configured")
+	}
+	if err != nil {
+		return err
 	}
 
-	return &recompressedLayer{
-		compressedData:   compressedData,
-		uncompressedData: uncompressedData,
-		mediaType:        mediaType,
-		diffID:           diffID,
-		digest:           digest,
-	}, nil
+	build.FinishedOn = time.Now().UTC()
+	s.build = build
+	return nil
 }
 
-func (l *recompressedLayer) Digest() (v1.Hash, error) {
-	return l.digest, nil
+// CheckBuildOnly rejects configs whose built image does not outlive the command when it is built without being pushed:
+// the pipeline source without a path writes the image to a temporary file removed by Close.
+func (s *Service) CheckBuildOnly() error {
+	if s.config.Build != nil && s.config.Build.Pipeline != nil && s.getSourceType() == ImageSourcePipeline && s.config.Source.Path == "" {
+		return fmt.Errorf("%w - building without pushing requires a path for the %s image source, the image is not kept otherwise", lib.BadUserInputError, ImageSourcePipeline)
+	}
+	return nil
 }
 
-func (l *recompressedLayer) DiffID() (v1.Hash, error) {
-	return l.diffID, nil
-}
+// Close removes the temporary pipeline image of the last build. It is safe to call several times and without a build.
+func (s *Service) Close() error {
+	if !s.pipelineTarballTemp || s.pipelineTarball == "" {
+		return nil
+	}
 
-func (l *recompressedLayer) Compressed() (io.ReadCloser, error) {
-	return io.NopCloser(bytes.NewReader(l.compressedData)), nil
+	dir := filepath.Dir(s.pipelineTarball)
+	s.pipelineTarball, s.pipelineTarballTemp = "", false
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("removing temporary pipeline image %s: %w", dir, err)
+	}
+	return nil
 }
 
-func (l *recompressedLayer) Uncompressed() (io.ReadCloser, error) {
-	return io.NopCloser(bytes.NewReader(l.uncompressedData)), nil
+func (s *Service) getSourceType() ImageSourceType {
+	if s.config.Source == nil || s.config.Source.Type == "" {
+		return ImageSourceDaemon
+	}
+	return s.config.Source.Type
 }
 
-func (l *recompressedLayer) Size() (int64, error) {
-	return int64(len(l.compressedData)), nil
-}
+// getPipelineOutput exports the pipeline image to a tarball for the pipeline source and to the docker daemon otherwise.
+func (s *Service) getPipelineOutput() (pipeline.Output, error) {
+	if s.getSourceType() != ImageSourcePipeline {
+		return pipeline.Output{Image: s.config.Image}, nil
+	}
 
-func (l *recompressedLayer) MediaType() (types.MediaType, error) {
-	return l.mediaType, nil
-}
+	// The temporary image of an earlier build of this service is not used anymore
+	if err := s.Close(); err != nil {
+		return pipeline.Output{}, err
+	}
 
-func NewService(config Config, registry registry.Registry, resolver *placeholders.Service, pipeline *pipeline.Service) *Service {
-	return &Service{
-		config,
-		registry,
-		resolver,
-		pipeline,
+	tarballPath := s.config.Source.Path
+	if tarballPath == "" {
+		dir, err := os.MkdirTemp("", "cloudctl-pipeline-*")
+		if err != nil {
+			return pipeline.Output{}, fmt.Errorf("creating temporary directory for pipeline image: %w", err)
+		}
+		tarballPath = filepath.Join(dir, "image.tar")
+		s.pipelineTarballTemp = true
 	}
-}
+	s.pipelineTarball = tarballPath
 
-func (s *Service) GetRegistry() registry.Registry {
-	return s.registry
+	return pipeline.Output{TarballPath: tarballPath}, nil
 }
 
-func (s *Service) BuildImage(ctx context.Context) error {
-	if s.config.Build == nil {
-		slog.InfoContext(ctx, "no image build configured")
-		return nil
-	}
+// loadSourceImage returns the image or multi-platform index to push, its description for logs and a cleanup releasing the files backing the image.
+func (s *Service) loadSourceImage(ctx context.Context) (sourceImage, string, func(), error) {
+	noop := func() {}
 
-	switch {
-	case len(s.config.Build.Cmd) > 0:
-		return s.buildImageViaCmd(ctx, s.config.Build.Cmd, s.config.Build.Env, s.config.Build.Dir)
-	case s.config.Build.Pipeline != nil:
-		return s.pipelineService.ProcessPipeline(ctx, s.config.Image)
+	sourceType := s.getSourceType()
+	switch sourceType {
+	case ImageSourceDaemon:
+		resolvedImage, err := s.GetSourceImageRef()
+		if err != nil {
+			return sourceImage{}, "", noop, err
+		}
+		srcRef, err := name.NewTag(resolvedImage)
+		if err != nil {
+			return sourceImage{}, "", noop, fmt.Errorf("parsing source image tag: %w", err)
+		}
+
+		image, err := daemon.Image(srcRef, daemon.WithContext(ctx))
+		if err != nil {
+			return sourceImage{}, "", noop, fmt.Errorf("getting image from local daemon: %w", err)
+		}
+		return sourceImage{image: image}, srcRef.String(), noop, nil
+	case ImageSourceOciLayout:
+		if s.config.Source.Path == "" {
+			return sourceImage{}, "", noop, fmt.Errorf("%w - path is required for the %s image source", lib.BadUserInputError, sourceType)
+		}
+
+		source, err := loadImageFromOciLayout(s.config.Source.Path)
+		if err != nil {
+			return sourceImage{}, "", noop, err
+		}
+		return source, s.config.Source.Path, noop, nil
+	case ImageSourceTarball:
+		if s.config.Source.Path == "" {
+			return sourceImage{}, "", noop, fmt.Errorf("%w - path is required for the %s image source", lib.BadUserInputError, sourceType)
+		}
+
+		source, cleanup, err := loadImageFromTarball(s.config.Source.Path)
+		if err != nil {
+			return sourceImage{}, "", noop, err
+		}
+		return source, s.config.Source.Path, cleanup, nil
+	case ImageSourcePipeline:
+		tarballPath := s.pipelineTarball
+		if tarballPath == "" {
+			tarballPath = s.config.Source.Path
+		}
+		if tarballPath == "" {
+			return sourceImage{}, "", noop, fmt.Errorf("pipeline image has not been built")
+		}
+
+		source, cleanup, err := loadImageFromTarball(tarballPath)
+		if err != nil {
+			return sourceImage{}, "", noop, err
+		}
+		if s.pipelineTarballTemp {
+			tarballCleanup := cleanup
+			cleanup = func() {
+				tarballCleanup()
+				s.Close()
+			}
+		}
+		return source, tarballPath, cleanup, nil
 	}
 
-	return fmt.Errorf("no image build strategy configured")
+	return sourceImage{}, "", noop, fmt.Errorf("%w - unsupported image source type '%s'", lib.BadUserInputError, sourceType)
 }
 
-func (s *Service) buildImageViaCmd(ctx context.Context, cmd []string, env map[string]string, dir string) error {
+// buildImageViaCmd runs the build command and returns the resolved command for the provenance attestation.
+// Only the names of the configured env vars are returned since their values may be secrets.
+func (s *Service) buildImageViaCmd(ctx context.Context, cmd []string, env map[string]string, dir string) (map[string]any, error) {
 	if len(cmd) <= 0 {
-		return fmt.Errorf("no command provided for image build")
+		return nil, fmt.Errorf("no command provided for image build")
 	}
 
 	resolvedCmd := make([]string, 0, len(cmd))
 	for _, c := range cmd {
 		resolvedC, err := s.placeholdersResolver.ResolvePlaceholders(c)
 		if err != nil {
-			return fmt.Errorf("resolving placeholder in build command '%s': %w", c, err)
+			return nil, fmt.Errorf("resolving placeholder in build command '%s': %w", c, err)
 		}
 		resolvedCmd = append(resolvedCmd, resolvedC)
 	}
@@ -147,14 +261,17 @@ func (s *Service) buildImageViaCmd(ctx context.Context, cmd []string, env map[st
 	}
 
 	environment := os.Environ()
+	envNames := make([]string, 0, len(env))
 	for k, v := range env {
 		resolvedValue, err := s.placeholdersResolver.ResolvePlaceholders(v)
 		if err != nil {
-			return fmt.Errorf("resolving placeholder in build env var '%s'='%s': %w", k, v, err)
+			return nil, fmt.Errorf("resolving placeholder in build env var '%s'='%s': %w", k, v, err)
 		}
 
 		environment = append(environment, fmt.Sprintf("%s=%s", k, resolvedValue))
+		envNames = append(envNames, k)
 	}
+	slices.Sort(envNames)
 
 	command := exec.CommandContext(ctx, args[0], args[1:]...)
 	command.Env = environment
@@ -165,222 +282,169 @@ func (s *Service) buildImageViaCmd(ctx context.Context, cmd []string, env map[st
 	slog.InfoContext(ctx, "running image build command", "args", command.Args)
 
 	if err := command.Run(); err != nil {
-		return fmt.Errorf("running image build command: %w", err)
+		return nil, fmt.Errorf("running image build command: %w", err)
 	}
 
-	return nil
+	return map[string]any{"cmd": command.Args, "dir": dir, "env": envNames}, nil
 }
 
-// recompressImage recompresses all layers of an image using the specified compression algorithm and level.
-// This can significantly reduce image size and improve push/pull performance when using zstd compression.
-func (s *Service) recompressImage(ctx context.Context, img v1.Image, algorithm CompressionAlgorithm, level int) (v1.Image, error) {
-	layers, err := img.Layers()
+// GetSourceImageRef returns the local image reference with placeholders resolved.
+func (s *Service) GetSourceImageRef() (string, error) {
+	resolvedImage, err := s.placeholdersResolver.ResolvePlaceholders(s.config.Image)
 	if err != nil {
-		return nil, fmt.Errorf("getting image layers: %w", err)
+		return "", fmt.Errorf("resolving placeholders in source image '%s': %w", s.config.Image, err)
 	}
+	return resolvedImage, nil
+}
 
-	configFile, err := img.ConfigFile()
+// GetDestinationImageRefs returns every reference the image is pushed to - the registry image reference first, followed by the extra tags.
+func (s *Service) GetDestinationImageRefs(ctx context.Context) ([]string, error) {
+	destRef, err := s.registry.GetImageRef()
 	if err != nil {
-		return nil, fmt.Errorf("getting image config: %w", err)
+		return nil, fmt.Errorf("getting image reference from registry: %w", err)
 	}
-
-	// Calculate original image size
-	var originalSize int64
-	for _, layer := range layers {
-		size, err := layer.Size()
-		if err != nil {
-			slog.WarnContext(ctx, "failed to get layer size", "error", err)
-			continue
-		}
-		originalSize += size
+	if destRef == "" {
+		return nil, fmt.Errorf("container registry returned empty image reference")
 	}
 
-	// Start with an empty image and add recompressed layers
-	result := empty.Image
-
-	// Create a copy of the config to avoid modifying the original.
-	// Clear DiffIDs since AppendLayers will rebuild them from the recompressed layers.
-	// Without this, we'd have original DiffIDs + appended DiffIDs = 2x the expected count.
-	newConfig := *configFile
-	newConfig.RootFS.DiffIDs = nil
-
-	// Set the config (without DiffIDs - they'll be added by AppendLayers)
-	result, err = mutate.ConfigFile(result, &newConfig)
+	destTags, err := s.getDestinationTags(ctx, destRef)
 	if err != nil {
-		return nil, fmt.Errorf("setting image config: %w", err)
-	}
-
-	slog.InfoContext(ctx, "recompressing image layers",
-		"algorithm", algorithm,
-		"level", level,
-		"layer_count", len(layers),
-		"original_size_mb", fmt.Sprintf("%.2f", float64(originalSize)/(1024*1024)))
-
-	// Track new size during recompression
-	var newSize int64
-
-	for i, layer := range layers {
-		recompressedLayer, layerSize, err := s.recompressLayer(ctx, layer, algorithm, level, i)
-		if err != nil {
-			return nil, fmt.Errorf("recompressing layer %d: %w", i, err)
-		}
-		newSize += layerSize
-
-		result, err = mutate.AppendLayers(result, recompressedLayer)
-		if err != nil {
-			return nil, fmt.Errorf("appending recompressed layer %d: %w", i, err)
-		}
+		return nil, err
 	}
 
-	var savingsPercent float64
-	if originalSize > 0 {
-		savingsPercent = (1 - float64(newSize)/float64(originalSize)) * 100
+	refs := make([]string, 0, len(destTags))
+	for _, tag := range destTags {
+		refs = append(refs, tag.String())
 	}
-
-	slog.InfoContext(ctx, "image recompression complete",
-		"original_size_mb", fmt.Sprintf("%.2f", float64(originalSize)/(1024*1024)),
-		"new_size_mb", fmt.Sprintf("%.2f", float64(newSize)/(1024*1024)),
-		"savings_percent", fmt.Sprintf("%.1f%%", savingsPercent))
-
-	return result, nil
+	return refs, nil
 }
 
-// recompressLayer recompresses a single layer with the specified compression algorithm.
-// Returns the recompressed layer, its size in bytes, and any error.
-func (s *Service) recompressLayer(ctx context.Context, layer v1.Layer, algorithm CompressionAlgorithm, level int, layerIndex int) (v1.Layer, int64, error) {
-	// Get uncompressed content
-	uncompressed, err := layer.Uncompressed()
-	if err != nil {
-		return nil, 0, fmt.Errorf("getting uncompressed layer: %w", err)
-	}
-	defer uncompressed.Close()
-
-	// Read all uncompressed data
-	uncompressedData, err := io.ReadAll(uncompressed)
+func (s *Service) getDestinationTags(ctx context.Context, destRef string) ([]name.Tag, error) {
+	destTag, err := name.NewTag(destRef, s.nameOptions()...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("reading uncompressed layer: %w", err)
+		return nil, fmt.Errorf("parsing destination image tag: %w", err)
 	}
 
-	// Determine media type based on compression algorithm
-	var mediaType types.MediaType
-	var compressedData []byte
+	destTags := make([]name.Tag, 0, len(s.config.Registry.Tags)+1)
+	destTags = append(destTags, destTag)
+	repository := destTag.Repository.String()
+	for _, tag := range s.config.Registry.Tags {
+		extraDestRef := fmt.Sprintf("%s:%s", repository, tag)
+		slog.DebugContext(ctx, "adding extra destination image tag", "extra_dest_ref", extraDestRef)
 
-	switch algorithm {
-	case CompressionZstd:
-		mediaType = types.OCILayerZStd
-		compressedData, err = compressWithZstd(uncompressedData, level)
-		if err != nil {
-			return nil, 0, fmt.Errorf("compressing with zstd: %w", err)
-		}
-	case CompressionGzip:
-		mediaType = types.OCILayer
-		compressedData, err = compressWithGzip(uncompressedData, level)
+		extraDestTag, err := name.NewTag(extraDestRef, s.nameOptions()...)
 		if err != nil {
-			return nil, 0, fmt.Errorf("compressing with gzip: %w", err)
+			return nil, fmt.Errorf("parsing extra destination image tag '%s': %w", extraDestRef, err)
 		}
-	case CompressionNone:
-		mediaType = types.OCIUncompressedLayer
-		compressedData = uncompressedData
-	default:
-		return nil, 0, fmt.Errorf("unsupported compression algorithm: %s", algorithm)
+		destTags = append(destTags, extraDestTag)
 	}
 
-	originalSize, _ := layer.Size()
-	newSize := int64(len(compressedData))
-	slog.DebugContext(ctx, "recompressed layer",
-		"layer_index", layerIndex,
-		"algorithm", algorithm,
-		"original_size", originalSize,
-		"new_size", newSize,
-		"ratio", fmt.Sprintf("%.2f%%", float64(newSize)/float64(len(uncompressedData))*100))
+	return destTags, nil
+}
 
-	// Create a new layer from the compressed data with proper DiffID
-	newLayer, err := newRecompressedLayer(compressedData, uncompressedData, mediaType)
-	if err != nil {
-		return nil, 0, fmt.Errorf("creating recompressed layer: %w", err)
+// nameOptions allows plain HTTP destination references for insecure registries.
+func (s *Service) nameOptions() []name.Option {
+	if s.registry.IsInsecure() {
+		return []name.Option{name.Insecure}
 	}
-
-	return newLayer, newSize, nil
+	return nil
 }
 
-// compressWithZstd compresses data using zstd algorithm at the specified level.
-func compressWithZstd(data []byte, level int) ([]byte, error) {
-	// Map level to zstd encoder level (1-22, with reasonable defaults)
-	encoderLevel := zstd.EncoderLevelFromZstd(level)
-	if level <= 0 {
-		encoderLevel = zstd.SpeedDefault // level 3
+// getAuthOption authenticates with the registry keychain or the registry credentials, depending on the registry auth type.
+func (s *Service) getAuthOption() (remote.Option, error) {
+	if s.registry.GetAuthType() == registry.AuthTypeKeychain {
+		return remote.WithAuthFromKeychain(s.registry.GetKeychain()), nil
 	}
 
-	var buf bytes.Buffer
-	encoder, err := zstd.NewWriter(&buf, zstd.WithEncoderLevel(encoderLevel))
+	auth, err := s.registry.GetAuthentication()
 	if err != nil {
-		return nil, fmt.Errorf("creating zstd encoder: %w", err)
+		return nil, fmt.Errorf("getting registry authentication: %w", err)
 	}
+	return remote.WithAuth(auth), nil
+}
 
-	_, err = encoder.Write(data)
+// registryOptions returns the context, authentication and transport options for requests to the destination registry.
+func (s *Service) registryOptions(ctx context.Context) ([]remote.Option, error) {
+	authOption, err := s.getAuthOption()
 	if err != nil {
-		encoder.Close()
-		return nil, fmt.Errorf("writing to zstd encoder: %w", err)
-	}
-
-	if err := encoder.Close(); err != nil {
-		return nil, fmt.Errorf("closing zstd encoder: %w", err)
+		return nil, err
 	}
-
-	return buf.Bytes(), nil
+	return append([]remote.Option{remote.WithContext(ctx), authOption}, s.remoteOptions()...), nil
 }
 
-// compressWithGzip compresses data using gzip algorithm at the specified level.
-func compressWithGzip(data []byte, level int) ([]byte, error) {
-	// Map level to gzip level (1-9)
-	if level <= 0 {
-		level = gzip.DefaultCompression
-	}
-	if level > 9 {
-		level = 9
-	}
-
-	var buf bytes.Buffer
-	encoder, err := gzip.NewWriterLevel(&buf, level)
+// referenceOptions returns the options for reading the reference: the registry options when it is in the service
+// registry, the docker credentials otherwise.
+func (s *Service) referenceOptions(ctx context.Context, ref name.Reference) ([]remote.Option, error) {
+	destRef, err := s.registry.GetImageRef()
 	if err != nil {
-		return nil, fmt.Errorf("creating gzip encoder: %w", err)
+		return nil, fmt.Errorf("getting image reference from registry: %w", err)
 	}
-
-	_, err = encoder.Write(data)
-	if err != nil {
-		encoder.Close()
-		return nil, fmt.Errorf("writing to gzip encoder: %w", err)
+	if dest, err := name.ParseReference(destRef, s.nameOptions()...); err == nil && dest.Context().RegistryStr() == ref.Context().RegistryStr() {
+		return s.registryOptions(ctx)
 	}
+	return []remote.Option{remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain)}, nil
+}
 
-	if err := encoder.Close(); err != nil {
-		return nil, fmt.Errorf("closing gzip encoder: %w", err)
+// remoteOptions returns the transport options for the destination registry.
+func (s *Service) remoteOptions() []remote.Option {
+	if !s.registry.IsInsecure() {
+		return nil
 	}
 
-	return buf.Bytes(), nil
+	transport := remote.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	return []remote.Option{remote.WithTransport(transport)}
+}
+
+type PushOptions struct {
+	// Platform the image has to run on, the push is refused before uploading anything when the image is built for
+	// another one. Empty skips the check.
+	Platform lib.Platform
 }
 
-// TODO: add check for image architecture compatibility with target registry/platform
-func (s *Service) PushImage(ctx context.Context) error {
+// PushImage pushes the locally built image to every destination tag and returns the digest of the pushed manifest.
+func (s *Service) PushImage(ctx context.Context, pushOptions PushOptions) (v1.Hash, error) {
 	destRef, err := s.registry.GetImageRef()
 	if err != nil {
-		return fmt.Errorf("getting image reference from registry: %w", err)
+		return v1.Hash{}, fmt.Errorf("getting image reference from registry: %w", err)
 	}
 	if destRef == "" {
-		return fmt.Errorf("container registry returned empty image reference")
+		return v1.Hash{}, fmt.Errorf("container registry returned empty image reference")
 	}
 
-	resolvedImage, err := s.placeholdersResolver.ResolvePlaceholders(s.config.Image)
-	srcRef, err := name.NewTag(resolvedImage)
+	if s.reusedBuild != nil {
+		return s.pushReusedBuild(ctx, *s.reusedBuild, pushOptions)
+	}
+
+	source, srcRef, cleanup, err := s.loadSourceImage(ctx)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	defer cleanup()
+
+	if pushOptions.Platform != "" {
+		if err := checkImagePlatform(ctx, source, pushOptions.Platform); err != nil {
+			return v1.Hash{}, err
+		}
+	}
+
+	destTagsList, err := s.getDestinationTags(ctx, destRef)
 	if err != nil {
-		return fmt.Errorf("parsing source image tag: %w", err)
+		return v1.Hash{}, err
+	}
+	destTag := destTagsList[0]
+	if s.buildKeyTag != nil {
+		destTagsList = append(destTagsList, *s.buildKeyTag)
 	}
 
-	image, err := daemon.Image(srcRef, daemon.WithContext(ctx))
+	authType := s.registry.GetAuthType()
+	authOption, err := s.getAuthOption()
 	if err != nil {
-		return fmt.Errorf("getting image from local daemon: %w", err)
+		return v1.Hash{}, err
 	}
 
 	// Apply compression if configured
+	var layerCache *layercache.Cache
 	if s.config.Compression != nil && s.config.Compression.Algorithm != "" {
 		level := s.config.Compression.Level
 		if level <= 0 {
@@ -393,42 +457,47 @@ func (s *Service) PushImage(ctx context.Context) error {
 			}
 		}
 
-		image, err = s.recompressImage(ctx, image, s.config.Compression.Algorithm, level)
+		layerCache, err = s.config.Compression.GetLayerCache()
 		if err != nil {
-			return fmt.Errorf("recompressing image with %s: %w", s.config.Compression.Algorithm, err)
+			return v1.Hash{}, err
 		}
-	}
 
-	destTags := make(map[name.Reference]remote.Taggable, len(s.config.Registry.Tags)+1)
-	destTag, err := name.NewTag(destRef)
-	if err != nil {
-		return fmt.Errorf("parsing destination image tag: %w", err)
-	}
-	destTags[destTag] = image
-	repository := destTag.Repository.String()
-	for _, tag := range s.config.Registry.Tags {
-		extraDestRef := fmt.Sprintf("%s:%s", repository, tag)
-		slog.DebugContext(ctx, "adding extra destination image tag", "extra_dest_ref", extraDestRef)
+		opts := recompressOptions{
+			algorithm: s.config.Compression.Algorithm,
+			level:     level,
+			workers:   recompressionWorkers(s.config.Compression),
+			cache:     layerCache,
+			remoteBlob: func(ctx context.Context, digest v1.Hash) (v1.Layer, bool, error) {
+				options := append([]remote.Option{remote.WithContext(ctx), authOption}, s.remoteOptions()...)
+				layer, err := remote.Layer(destTag.Context().Digest(digest.String()), options...)
+				if err != nil {
+					return nil, false, err
+				}
+				exists, err := partial.Exists(layer)
+				return layer, exists, err
+			},
+		}
 
-		extraDestTag, err := name.NewTag(extraDestRef)
+		var cleanupLayers func()
+		source, cleanupLayers, err = s.recompressSource(ctx, source, opts)
 		if err != nil {
-			return fmt.Errorf("parsing extra destination image tag '%s': %w", extraDestRef, err)
+			return v1.Hash{}, fmt.Errorf("recompressing image with %s: %w", s.config.Compression.Algorithm, err)
 		}
-		destTags[extraDestTag] = image
+		defer cleanupLayers()
 	}
 
-	// Determine authentication method based on registry auth type
-	authType := s.registry.GetAuthType()
-	var authOption remote.Option
-	if authType == registry.AuthTypeKeychain {
-		keychain := s.registry.GetKeychain()
-		authOption = remote.WithAuthFromKeychain(keychain)
-	} else {
-		auth, err := s.registry.GetAuthentication()
-		if err != nil {
-			return fmt.Errorf("getting registry authentication: %w", err)
-		}
-		authOption = remote.WithAuth(auth)
+	labels, err := s.getImageLabels(ctx, source)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	source, err = labelSource(source, labels)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("adding image labels: %w", err)
+	}
+
+	digest, err := source.Digest()
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("getting image digest: %w", err)
 	}
 
 	var stdout io.Writer = os.Stdout
@@ -439,101 +508,229 @@ func (s *Service) PushImage(ctx context.Context) error {
 		tty = true
 	}
 
-	imageConfig, err := image.ConfigFile()
+	platforms, err := source.Platforms()
 	if err != nil {
-		return fmt.Errorf("getting image config file: %w", err)
+		return v1.Hash{}, err
 	}
 
-	slog.InfoContext(ctx, "pushing image to remote registry",
-		"source", srcRef,
-		"dest", destTag,
-		"extra_dest_tags", slices.Collect(maps.Keys(destTags)),
-		"os", imageConfig.OS,
-		"architecture", imageConfig.Architecture)
-
 	startTime := time.Now()
 	for {
-		progressChan := make(chan v1.Update, 32)
-
-		go func() {
-			var lastUpdateTime time.Time
-			for update := range progressChan {
-				if !tty {
-					continue
-				}
+		lookupOptions := append([]remote.Option{remote.WithContext(ctx), authOption}, s.remoteOptions()...)
+		tagsState, err := s.getDestinationTagsState(destTagsList, digest, lookupOptions)
+		if err == nil {
+			if len(tagsState.upToDate) > 0 {
+				slog.InfoContext(ctx, "destination tags already up to date",
+					"tags", tagsState.upToDate,
+					"digest", digest)
+			}
 
-				if update.Error != nil {
-					fmt.Fprintf(stderr, "Error: %v\n", update.Error)
-					continue
-				}
-				if update.Total <= 0 {
-					continue
-				}
-				if time.Since(lastUpdateTime) <= 500*time.Millisecond {
-					continue
-				}
-				lastUpdateTime = time.Now()
+			switch {
+			case len(tagsState.missing) == 0:
+			case tagsState.manifestExists:
+				// The registry has the manifest and all its blobs, the missing tags only have to point to it
+				slog.InfoContext(ctx, "tagging manifest already present in the registry",
+					"tags", tagsState.missing,
+					"digest", digest)
+				err = s.copyManifestToTags(destTag.Context().Digest(digest.String()), tagsState.missing, lookupOptions)
+			default:
+				slog.InfoContext(ctx, "pushing image to remote registry",
+					"source", srcRef,
+					"dest", destTag,
+					"tags", tagsState.missing,
+					"platforms", platforms)
+				err = s.writeImage(ctx, source.taggable(), tagsState.missing, authOption, tty, stdout, stderr)
+			}
+		}
 
-				percentage := float64(update.Complete) / float64(update.Total) * 100
+		if err != nil {
+			if isUnauthorizedError(err) {
+				slog.WarnContext(ctx, "unauthorized error pushing image to registry, resetting authentication and retrying", "error", err)
 
-				fmt.Fprintf(stdout, "Image push: %.2f%% complete\n", percentage)
-			}
-		}()
-
-		maxUploadJobs := int(math.Min(16, float64(runtime.NumCPU())))
-		options := []remote.Option{
-			remote.WithContext(ctx),
-			authOption,
-			remote.WithProgress(progressChan),
-			remote.WithJobs(maxUploadJobs),
-			remote.WithPlatform(v1.Platform{
-				Architecture: imageConfig.Architecture,
-				OS:           imageConfig.OS,
-				OSFeatures:   imageConfig.OSFeatures,
-				OSVersion:    imageConfig.OSVersion,
-				Variant:      imageConfig.Variant,
-			}),
-		}
-		if err := remote.MultiWrite(destTags, options...); err != nil {
-			var registryErr *transport.Error
-			if errors.As(err, &registryErr) {
-				isUnauthorizedErr := false
-				if registryErr.StatusCode == http.StatusUnauthorized || registryErr.StatusCode == http.StatusForbidden {
-					isUnauthorizedErr = true
+				err = s.registry.ResetAuthentication()
+				if err != nil {
+					return v1.Hash{}, fmt.Errorf("resetting registry authentication after unauthorized error: %w", err)
 				}
-				for _, desc := range registryErr.Errors {
-					if desc.Code == transport.UnauthorizedErrorCode || desc.Code == transport.DeniedErrorCode {
-						isUnauthorizedErr = true
-					}
-				}
-				if isUnauthorizedErr {
-					slog.WarnContext(ctx, "unauthorized error pushing image to registry, resetting authentication and retrying", "error", err)
-
-					err = s.registry.ResetAuthentication()
+				// Only refresh auth option for authenticator type; keychain handles refresh internally
+				if authType == registry.AuthTypeAuthenticator {
+					auth, err := s.registry.GetAuthentication()
 					if err != nil {
-						return fmt.Errorf("resetting registry authentication after unauthorized error: %w", err)
+						return v1.Hash{}, fmt.Errorf("getting registry authentication after reset: %w", err)
 					}
-					// Only refresh auth option for authenticator type; keychain handles refresh internally
-					if authType == registry.AuthTypeAuthenticator {
-						auth, err := s.registry.GetAuthentication()
-						if err != nil {
-							return fmt.Errorf("getting registry authentication after reset: %w", err)
-						}
-						authOption = remote.WithAuth(auth)
-					}
-					continue
+					authOption = remote.WithAuth(auth)
 				}
+				continue
 			}
-			return fmt.Errorf("pushing image to remote registry: %w", err)
+			return v1.Hash{}, fmt.Errorf("pushing image to remote registry: %w", err)
 		}
 
 		break
 	}
 
+	if layerCache != nil {
+		s.pruneLayerCache(ctx, layerCache)
+	}
+
 	slog.InfoContext(ctx, "image pushed successfully",
 		"source", srcRef,
 		"destination", destRef,
+		"digest", digest,
 		"duration", fmt.Sprintf("%f seconds", time.Since(startTime).Seconds()))
 
+	if s.config.Sbom != nil {
+		// The SBOM is generated while the source is loaded, pipeline tarballs are removed by the cleanup
+		if err := s.attachSbom(ctx, source, destTag.Context()); err != nil {
+			return v1.Hash{}, fmt.Errorf("attaching SBOM to image %s: %w", digest, err)
+		}
+	}
+
+	return digest, nil
+}
+
+// destinationTagsState splits the destination tags into the ones already pointing to the image and the ones to push.
+type destinationTagsState struct {
+	upToDate []name.Tag
+	missing  []name.Tag
+	// manifestExists is set when the repository already has the image manifest, so the missing tags only need a manifest copy
+	manifestExists bool
+}
+
+// getDestinationTagsState compares the remote manifest digest of every destination tag with the local image digest.
+func (s *Service) getDestinationTagsState(tags []name.Tag, digest v1.Hash, options []remote.Option) (destinationTagsState, error) {
+	var state destinationTagsState
+
+	for _, tag := range tags {
+		desc, err := remote.Head(tag, options...)
+		if isNotFoundError(err) {
+			state.missing = append(state.missing, tag)
+			continue
+		}
+		if err != nil {
+			return state, fmt.Errorf("checking destination tag %s: %w", tag, err)
+		}
+
+		if desc.Digest == digest {
+			state.upToDate = append(state.upToDate, tag)
+		} else {
+			state.missing = append(state.missing, tag)
+		}
+	}
+
+	state.manifestExists = len(state.upToDate) > 0
+	if !state.manifestExists && len(state.missing) > 0 {
+		// The manifest can be in the repository without any of the tags, e.g. pushed before under another tag
+		_, err := remote.Head(tags[0].Context().Digest(digest.String()), options...)
+		if err != nil && !isNotFoundError(err) {
+			return state, fmt.Errorf("checking manifest %s: %w", digest, err)
+		}
+		state.manifestExists = err == nil
+	}
+
+	return state, nil
+}
+
+// copyManifestToTags points the tags to a manifest already present in the registry without uploading anything.
+func (s *Service) copyManifestToTags(source name.Digest, tags []name.Tag, options []remote.Option) error {
+	desc, err := remote.Get(source, options...)
+	if err != nil {
+		return fmt.Errorf("getting manifest %s: %w", source, err)
+	}
+
+	for _, tag := range tags {
+		if err := remote.Tag(tag, desc, options...); err != nil {
+			return fmt.Errorf("tagging %s: %w", tag, err)
+		}
+	}
+
 	return nil
 }
+
+// writeImage uploads the image or index with its blobs to the tags, reporting the progress on a terminal.
+func (s *Service) writeImage(ctx context.Context, image remote.Taggable, tags []name.Tag, authOption remote.Option, tty bool, stdout, stderr io.Writer) error {
+	destTags := make(map[name.Reference]remote.Taggable, len(tags))
+	for _, tag := range tags {
+		destTags[tag] = image
+	}
+
+	progressChan := make(chan v1.Update, 32)
+
+	go func() {
+		var lastUpdateTime time.Time
+		for update := range progressChan {
+			if !tty {
+				continue
+			}
+
+			if update.Error != nil {
+				fmt.Fprintf(stderr, "Error: %v\n", update.Error)
+				continue
+			}
+			if update.Total <= 0 {
+				continue
+			}
+			if time.Since(lastUpdateTime) <= 500*time.Millisecond {
+				continue
+			}
+			lastUpdateTime = time.Now()
+
+			percentage := float64(update.Complete) / float64(update.Total) * 100
+
+			fmt.Fprintf(stdout, "Image push: %.2f%% complete\n", percentage)
+		}
+	}()
+
+	maxUploadJobs := int(math.Min(16, float64(runtime.NumCPU())))
+	options := []remote.Option{
+		remote.WithContext(ctx),
+		authOption,
+		remote.WithProgress(progressChan),
+		remote.WithJobs(maxUploadJobs),
+	}
+	options = append(options, s.remoteOptions()...)
+
+	return remote.MultiWrite(destTags, options...)
+}
+
+func isUnauthorizedError(err error) bool {
+	var registryErr *transport.Error
+	if !errors.As(err, &registryErr) {
+		return false
+	}
+	if registryErr.StatusCode == http.StatusUnauthorized || registryErr.StatusCode == http.StatusForbidden {
+		return true
+	}
+	for _, desc := range registryErr.Errors {
+		if desc.Code == transport.UnauthorizedErrorCode || desc.Code == transport.DeniedErrorCode {
+			return true
+		}
+	}
+	return false
+}
+
+func isNotFoundError(err error) bool {
+	var registryErr *transport.Error
+	return errors.As(err, &registryErr) && registryErr.StatusCode == http.StatusNotFound
+}
+
+// GetDeployImageRef returns the reference providers deploy: the repository pinned to the pushed digest,
+// or the destination tag when the config keeps tag based deploys.
+func (s *Service) GetDeployImageRef(digest v1.Hash) (string, error) {
+	destRef, err := s.registry.GetImageRef()
+	if err != nil {
+		return "", fmt.Errorf("getting image reference from registry: %w", err)
+	}
+	if s.config.DeployByTag {
+		return destRef, nil
+	}
+	if digest == (v1.Hash{}) {
+		return "", fmt.Errorf("pushed image digest is empty")
+	}
+
+	destTag, err := name.NewTag(destRef, s.nameOptions()...)
+	if err != nil {
+		return "", fmt.Errorf("parsing destination image tag: %w", err)
+	}
+
+	// The repository is kept as written in the config, name.Tag would expand e.g. docker.io to index.docker.io
+	repository := strings.TrimSuffix(destRef, ":"+destTag.TagStr())
+	return fmt.Sprintf("%s@%s", repository, digest), nil
+}
@@ -0,0 +1,266 @@
+package container_image
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/build/pipeline"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/registry"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/testutil"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_GetDeployImageRef(t *testing.T) {
+	t.Parallel()
+
+	digest := v1.Hash{Algorithm: "sha256", Hex: "3b4c2b0a4e8e42a7d0e3f6a1c1f2d9d3b4c2b0a4e8e42a7d0e3f6a1c1f2d9d3b"}
+
+	t.Run("pins the repository to the digest", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		svc := NewService(Config{}, registry.NewGithubContainerRegistry(nil, "ghcr.io/owner/app:v1", nil), nil, nil, nil, nil)
+
+		ref, err := svc.GetDeployImageRef(digest)
+		r.NoError(err)
+		r.Equal("ghcr.io/owner/app@"+digest.String(), ref)
+	})
+
+	t.Run("keeps the registry as configured", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		svc := NewService(Config{}, registry.NewOciRegistry(nil, registry.OciRegistryConfig{Image: "localhost:5000/team/app:v1", Insecure: true}, nil, nil), nil, nil, nil, nil)
+
+		ref, err := svc.GetDeployImageRef(digest)
+		r.NoError(err)
+		r.Equal("localhost:5000/team/app@"+digest.String(), ref)
+	})
+
+	t.Run("deploys the tag when configured", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		svc := NewService(Config{DeployByTag: true}, registry.NewGithubContainerRegistry(nil, "ghcr.io/owner/app:v1", nil), nil, nil, nil, nil)
+
+		ref, err := svc.GetDeployImageRef(digest)
+		r.NoError(err)
+		r.Equal("ghcr.io/owner/app:v1", ref)
+	})
+
+	t.Run("requires the digest", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		svc := NewService(Config{}, registry.NewGithubContainerRegistry(nil, "ghcr.io/owner/app:v1", nil), nil, nil, nil, nil)
+
+		_, err := svc.GetDeployImageRef(v1.Hash{})
+		r.Error(err)
+	})
+}
+
+// testRegistry points the service to a registry started by the test, accessed anonymously over plain HTTP.
+type testRegistry struct {
+	imageRef string
+}
+
+func (r testRegistry) GetAuthType() registry.AuthType                  { return registry.AuthTypeAuthenticator }
+func (r testRegistry) GetKeychain() authn.Keychain                     { return nil }
+func (r testRegistry) GetAuthentication() (authn.Authenticator, error) { return authn.Anonymous, nil }
+func (r testRegistry) GetImageRef() (string, error)                    { return r.imageRef, nil }
+func (r testRegistry) ResetAuthentication() error                      { return nil }
+func (r testRegistry) IsInsecure() bool                                { return true }
+
+// registryRequests counts the write requests reaching the registry.
+type registryRequests struct {
+	mu           sync.Mutex
+	manifestPuts []string
+	blobUploads  int
+	handler      http.Handler
+}
+
+func (c *registryRequests) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	switch {
+	case r.Method == http.MethodPut && strings.Contains(r.URL.Path, "/manifests/"):
+		c.manifestPuts = append(c.manifestPuts, path.Base(r.URL.Path))
+	case r.Method == http.MethodPost && strings.Contains(r.URL.Path, "/blobs/uploads/"):
+		c.blobUploads++
+	}
+	c.mu.Unlock()
+
+	c.handler.ServeHTTP(w, r)
+}
+
+func (c *registryRequests) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.manifestPuts = nil
+	c.blobUploads = 0
+}
+
+func TestService_PushImage(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	requests := &registryRequests{handler: ggcrregistry.New(ggcrregistry.Logger(log.New(io.Discard, "", 0)))}
+	server := testutil.NewServer(t, requests)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	image, err := random.Image(1024, 2)
+	r.NoError(err)
+	layoutDir := t.TempDir()
+	writeOciLayout(t, layoutDir, image)
+	expectedDigest, err := image.Digest()
+	r.NoError(err)
+
+	newService := func(tags ...string) *Service {
+		return NewService(Config{
+			Source:   &ImageSourceConfig{Type: ImageSourceOciLayout, Path: layoutDir},
+			Registry: RegistryConfig{Tags: tags},
+		}, testRegistry{imageRef: host + "/team/app:v1"}, nil, nil, nil, nil)
+	}
+
+	digest, err := newService("latest").PushImage(context.Background(), PushOptions{})
+	r.NoError(err)
+	r.Equal(expectedDigest, digest)
+	r.ElementsMatch([]string{"v1", "latest"}, requests.manifestPuts)
+	r.Positive(requests.blobUploads)
+
+	// Every tag already points to the image
+	requests.reset()
+	digest, err = newService("latest").PushImage(context.Background(), PushOptions{})
+	r.NoError(err)
+	r.Equal(expectedDigest, digest)
+	r.Empty(requests.manifestPuts)
+	r.Zero(requests.blobUploads)
+
+	// Only the new tag is added, by copying the manifest
+	requests.reset()
+	digest, err = newService("latest", "stable").PushImage(context.Background(), PushOptions{})
+	r.NoError(err)
+	r.Equal(expectedDigest, digest)
+	r.Equal([]string{"stable"}, requests.manifestPuts)
+	r.Zero(requests.blobUploads)
+
+	stable, err := name.NewTag(host+"/team/app:stable", name.Insecure)
+	r.NoError(err)
+	desc, err := remote.Head(stable)
+	r.NoError(err)
+	r.Equal(expectedDigest, desc.Digest)
+}
+
+func TestService_PushImage_platformMismatch(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	requests := &registryRequests{handler: ggcrregistry.New(ggcrregistry.Logger(log.New(io.Discard, "", 0)))}
+	server := testutil.NewServer(t, requests)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	layoutDir := t.TempDir()
+	writeOciLayout(t, layoutDir, platformAddendum(t, "arm64").Add.(v1.Image))
+
+	svc := NewService(Config{
+		Source: &ImageSourceConfig{Type: ImageSourceOciLayout, Path: layoutDir},
+	}, testRegistry{imageRef: host + "/team/app:v1"}, nil, nil, nil, nil)
+
+	_, err := svc.PushImage(context.Background(), PushOptions{Platform: lib.PlatformLinuxAmd64})
+	r.ErrorIs(err, ImagePlatformMismatchError)
+	r.Empty(requests.manifestPuts)
+	r.Zero(requests.blobUploads)
+}
+
+func TestService_PushImage_multiPlatform(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	server := testutil.NewServer(t, ggcrregistry.New(ggcrregistry.Logger(log.New(io.Discard, "", 0))))
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	index := mutate.AppendManifests(empty.Index,
+		platformAddendum(t, "amd64"),
+		platformAddendum(t, "arm64"),
+	)
+	layoutPath, err := layout.Write(t.TempDir(), empty.Index)
+	r.NoError(err)
+	r.NoError(layoutPath.AppendIndex(index))
+
+	svc := NewService(Config{
+		Source:      &ImageSourceConfig{Type: ImageSourceOciLayout, Path: string(layoutPath)},
+		Compression: &CompressionConfig{Algorithm: CompressionZstd, Cache: &LayerCacheConfig{Disabled: true}},
+	}, testRegistry{imageRef: host + "/team/app:v1"}, nil, nil, nil, nil)
+
+	digest, err := svc.PushImage(context.Background(), PushOptions{})
+	r.NoError(err)
+
+	// The deployed reference points to the index, so every platform pulls its own image
+	ref, err := svc.GetDeployImageRef(digest)
+	r.NoError(err)
+	pushedRef, err := name.NewDigest(ref, name.Insecure)
+	r.NoError(err)
+	pushed, err := remote.Index(pushedRef)
+	r.NoError(err)
+
+	manifest, err := pushed.IndexManifest()
+	r.NoError(err)
+	r.Len(manifest.Manifests, 2)
+	r.Equal("amd64", manifest.Manifests[0].Platform.Architecture)
+	r.Equal("arm64", manifest.Manifests[1].Platform.Architecture)
+}
+
+func TestService_Close(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	svc := NewService(Config{
+		Build:  &BuildConfig{Pipeline: &pipeline.Config{App: "api"}},
+		Source: &ImageSourceConfig{Type: ImageSourcePipeline},
+	}, testRegistry{imageRef: "localhost/team/app:v1"}, nil, nil, nil, nil)
+	r.ErrorIs(svc.CheckBuildOnly(), lib.BadUserInputError)
+
+	output, err := svc.getPipelineOutput()
+	r.NoError(err)
+	dir := filepath.Dir(output.TarballPath)
+	r.DirExists(dir)
+
+	// A new build replaces the temporary image of the previous one
+	output, err = svc.getPipelineOutput()
+	r.NoError(err)
+	r.NoDirExists(dir)
+	dir = filepath.Dir(output.TarballPath)
+
+	r.NoError(svc.Close())
+	r.NoDirExists(dir)
+	r.NoError(svc.Close())
+
+	// An image written to the configured path is kept
+	path := filepath.Join(t.TempDir(), "image.tar")
+	svc = NewService(Config{
+		Build:  &BuildConfig{Pipeline: &pipeline.Config{App: "api"}},
+		Source: &ImageSourceConfig{Type: ImageSourcePipeline, Path: path},
+	}, testRegistry{imageRef: "localhost/team/app:v1"}, nil, nil, nil, nil)
+	r.NoError(svc.CheckBuildOnly())
+	output, err = svc.getPipelineOutput()
+	r.NoError(err)
+	r.Equal(path, output.TarballPath)
+	r.NoError(svc.Close())
+	r.DirExists(filepath.Dir(path))
+}
@@ -0,0 +1,292 @@
+package container_image
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/signing"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+var (
+	ImageSignatureNotFoundError = errors.New("no valid image signature found")
+)
+
+// imageSignature is a signed payload found next to the image, in the signature tag or in a referrer.
+type imageSignature struct {
+	payload   []byte
+	signature string
+}
+
+// IsSigningEnabled reports whether pushed images are signed.
+func (s *Service) IsSigningEnabled() bool {
+	return s.config.Signing != nil && s.config.Signing.Key != nil
+}
+
+// IsSignatureRequired reports whether images need a valid signature to be deployed.
+func (s *Service) IsSignatureRequired() bool {
+	return s.config.Signing != nil && s.config.Signing.Required
+}
+
+// GetImageDigestRef returns the destination repository pinned to the digest.
+func (s *Service) GetImageDigestRef(digest v1.Hash) (name.Digest, error) {
+	destRef, err := s.registry.GetImageRef()
+	if err != nil {
+		return name.Digest{}, fmt.Errorf("getting image reference from registry: %w", err)
+	}
+	destTag, err := name.NewTag(destRef, s.nameOptions()...)
+	if err != nil {
+		return name.Digest{}, fmt.Errorf("parsing destination image tag: %w", err)
+	}
+	return destTag.Context().Digest(digest.String()), nil
+}
+
+// ResolveImageDigestRef pins the reference to the digest the registry reports for it.
+// The destination image reference is used when ref is empty.
+func (s *Service) ResolveImageDigestRef(ctx context.Context, ref string) (name.Digest, error) {
+	if ref == "" {
+		destRef, err := s.registry.GetImageRef()
+		if err != nil {
+			return name.Digest{}, fmt.Errorf("getting image reference from registry: %w", err)
+		}
+		ref = destRef
+	}
+
+	parsed, err := name.ParseReference(ref, s.nameOptions()...)
+	if err != nil {
+		return name.Digest{}, fmt.Errorf("%w - parsing image reference '%s': %s", lib.BadUserInputError, ref, err)
+	}
+	if digest, ok := parsed.(name.Digest); ok {
+		return digest, nil
+	}
+
+	options, err := s.registryOptions(ctx)
+	if err != nil {
+		return name.Digest{}, err
+	}
+	desc, err := remote.Head(parsed, options...)
+	if err != nil {
+		return name.Digest{}, fmt.Errorf("resolving image %s: %w", parsed, err)
+	}
+	return parsed.Context().Digest(desc.Digest.String()), nil
+}
+
+// SignImage signs the image manifest with the configured key and pushes the signature to the registry.
+// Nothing is pushed when the image already has a valid signature made with the key.
+func (s *Service) SignImage(ctx context.Context, image name.Digest) error {
+	if !s.IsSigningEnabled() {
+		return fmt.Errorf("%w - no signing key configured", lib.BadUserInputError)
+	}
+
+	key, err := signing.LoadPrivateKey(*s.config.Signing.Key, s.signingKeys)
+	if err != nil {
+		return fmt.Errorf("loading signing key: %w", err)
+	}
+
+	options, err := s.registryOptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	valid, err := s.countValidSignatures(ctx, image, key.Public(), options)
+	if err != nil {
+		return err
+	}
+	if valid > 0 {
+		slog.InfoContext(ctx, "image already signed with the signing key", "image", image)
+		return nil
+	}
+
+	digest, err := v1.NewHash(image.DigestStr())
+	if err != nil {
+		return fmt.Errorf("parsing image digest: %w", err)
+	}
+	payload, err := signing.NewPayload(image.Context().Name(), digest)
+	if err != nil {
+		return err
+	}
+	signature, err := signing.Sign(key, payload)
+	if err != nil {
+		return fmt.Errorf("signing image %s: %w", image, err)
+	}
+	annotations := map[string]string{signing.SignatureAnnotation: base64.StdEncoding.EncodeToString(signature)}
+
+	mode := s.config.Signing.Mode
+	switch mode {
+	case "", SignatureModeTag:
+		err = pushSignatureTag(image, payload, annotations, options)
+	case SignatureModeReferrer:
+		layer := artifactLayer{MediaType: signing.SimpleSigningMediaType, Content: payload, Annotations: annotations}
+		_, err = pushReferrer(image, signing.SignatureArtifactType, []artifactLayer{layer}, nil, options)
+	default:
+		return fmt.Errorf("%w - unsupported signature mode '%s', supported are %s, %s", lib.BadUserInputError, mode, SignatureModeTag, SignatureModeReferrer)
+	}
+	if err != nil {
+		return fmt.Errorf("pushing signature of %s: %w", image, err)
+	}
+
+	slog.InfoContext(ctx, "image signed", "image", image, "mode", mode)
+	return nil
+}
+
+// VerifyImageSignature checks the signatures stored both as the signature tag and as referrers of the image
+// and returns how many of them are valid for the public key. ImageSignatureNotFoundError is returned when none is.
+func (s *Service) VerifyImageSignature(ctx context.Context, image name.Digest, publicKeyPath string) (int, error) {
+	publicKey, err := s.getSigningPublicKey(publicKeyPath)
+	if err != nil {
+		return 0, err
+	}
+
+	options, err := s.registryOptions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	valid, err := s.countValidSignatures(ctx, image, publicKey, options)
+	if err != nil {
+		return 0, err
+	}
+	if valid == 0 {
+		return 0, fmt.Errorf("%w for %s", ImageSignatureNotFoundError, image)
+	}
+	return valid, nil
+}
+
+// getSigningPublicKey reads the public key from the path, the configured public key file or the signing key, in this order.
+func (s *Service) getSigningPublicKey(path string) (crypto.PublicKey, error) {
+	if path == "" && s.config.Signing != nil {
+		path = s.config.Signing.PublicKey
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading public key %s: %w", path, err)
+		}
+		return signing.ParsePublicKey(data)
+	}
+
+	if !s.IsSigningEnabled() {
+		return nil, fmt.Errorf("%w - a public key or a signing key is required to verify signatures", lib.BadUserInputError)
+	}
+	key, err := signing.LoadPrivateKey(*s.config.Signing.Key, s.signingKeys)
+	if err != nil {
+		return nil, fmt.Errorf("loading signing key: %w", err)
+	}
+	return key.Public(), nil
+}
+
+func (s *Service) countValidSignatures(ctx context.Context, image name.Digest, publicKey crypto.PublicKey, options []remote.Option) (int, error) {
+	digest, err := v1.NewHash(image.DigestStr())
+	if err != nil {
+		return 0, fmt.Errorf("parsing image digest: %w", err)
+	}
+
+	signatures, err := listImageSignatures(image, options)
+	if err != nil {
+		return 0, err
+	}
+
+	valid := 0
+	for _, sig := range signatures {
+		if err := verifyImageSignature(sig, publicKey, digest); err != nil {
+			slog.DebugContext(ctx, "skipping image signature", "image", image, "error", err)
+			continue
+		}
+		valid++
+	}
+	return valid, nil
+}
+
+func verifyImageSignature(sig imageSignature, publicKey crypto.PublicKey, digest v1.Hash) error {
+	signature, err := base64.StdEncoding.DecodeString(sig.signature)
+	if err != nil {
+		return fmt.Errorf("decoding signature: %w", err)
+	}
+	if err := signing.Verify(publicKey, sig.payload, signature); err != nil {
+		return err
+	}
+	_, err = signing.ParsePayload(sig.payload, digest)
+	return err
+}
+
+// signatureTag is where cosign stores the signatures of the image.
+func signatureTag(image name.Digest) name.Tag {
+	return image.Context().Tag(strings.Replace(image.DigestStr(), ":", "-", 1) + ".sig")
+}
+
+// pushSignatureTag adds the signature to the image under the signature tag, keeping the signatures already there.
+func pushSignatureTag(image name.Digest, payload []byte, annotations map[string]string, options []remote.Option) error {
+	tag := signatureTag(image)
+
+	base := mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), types.OCIConfigJSON)
+	existing, err := remote.Image(tag, options...)
+	switch {
+	case err == nil:
+		base = existing
+	case !isNotFoundError(err):
+		return fmt.Errorf("getting signatures %s: %w", tag, err)
+	}
+
+	signed, err := mutate.Append(base, mutate.Addendum{
+		Layer:       static.NewLayer(payload, signing.SimpleSigningMediaType),
+		Annotations: annotations,
+	})
+	if err != nil {
+		return fmt.Errorf("adding signature: %w", err)
+	}
+
+	return remote.Write(tag, signed, options...)
+}
+
+// listImageSignatures collects the signatures from the signature tag and the signature referrers.
+func listImageSignatures(image name.Digest, options []remote.Option) ([]imageSignature, error) {
+	var signatures []imageSignature
+
+	var layers []v1.Descriptor
+	tagged, err := remote.Image(signatureTag(image), options...)
+	switch {
+	case err == nil:
+		manifest, err := tagged.Manifest()
+		if err != nil {
+			return nil, fmt.Errorf("reading signatures manifest: %w", err)
+		}
+		layers = append(layers, manifest.Layers...)
+	case !isNotFoundError(err):
+		return nil, fmt.Errorf("getting signatures %s: %w", signatureTag(image), err)
+	}
+
+	referrers, err := listReferrers(image, signing.SignatureArtifactType, options)
+	if err != nil {
+		return nil, err
+	}
+	for _, referrer := range referrers {
+		layers = append(layers, referrer.Layers...)
+	}
+
+	for _, layer := range layers {
+		signature, ok := layer.Annotations[signing.SignatureAnnotation]
+		if layer.MediaType != signing.SimpleSigningMediaType || !ok {
+			continue
+		}
+		payload, err := readBlob(image.Context(), layer, options)
+		if err != nil {
+			return nil, err
+		}
+		signatures = append(signatures, imageSignature{payload: payload, signature: signature})
+	}
+
+	return signatures, nil
+}
@@ -0,0 +1,109 @@
+package container_image
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/signing"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/testutil"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/require"
+)
+
+// pushTestImage starts a registry, pushes a random image to it and returns the digest reference.
+func pushTestImage(t *testing.T, options ...ggcrregistry.Option) (string, name.Digest) {
+	server := testutil.NewServer(t, ggcrregistry.New(append(options, ggcrregistry.Logger(log.New(io.Discard, "", 0)))...))
+	imageRef := strings.TrimPrefix(server.URL, "http://") + "/team/app:v1"
+
+	image, err := random.Image(256, 1)
+	require.NoError(t, err)
+	tag, err := name.NewTag(imageRef, name.Insecure)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(tag, image))
+
+	digest, err := image.Digest()
+	require.NoError(t, err)
+	return imageRef, tag.Context().Digest(digest.String())
+}
+
+func writeSigningKey(t *testing.T, keyType signing.KeyType) string {
+	key, err := signing.GenerateKey(keyType)
+	require.NoError(t, err)
+	privatePem, err := signing.MarshalPrivateKey(key)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "cosign.key")
+	require.NoError(t, os.WriteFile(path, privatePem, 0o600))
+	return path
+}
+
+func TestService_SignImage(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		mode     SignatureMode
+		keyType  signing.KeyType
+		registry []ggcrregistry.Option
+	}{
+		{name: "tag", mode: SignatureModeTag, keyType: signing.KeyTypeEcdsa},
+		{name: "referrer", mode: SignatureModeReferrer, keyType: signing.KeyTypeEd25519, registry: []ggcrregistry.Option{ggcrregistry.WithReferrersSupport(true)}},
+		{name: "referrers tag fallback", mode: SignatureModeReferrer, keyType: signing.KeyTypeEcdsa},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			imageRef, image := pushTestImage(t, tc.registry...)
+			keyPath := writeSigningKey(t, tc.keyType)
+			svc := NewService(Config{
+				Signing: &SigningConfig{Key: &signing.KeyConfig{File: keyPath}, Mode: tc.mode},
+			}, testRegistry{imageRef: imageRef}, nil, nil, nil, nil)
+
+			_, err := svc.VerifyImageSignature(context.Background(), image, "")
+			r.ErrorIs(err, ImageSignatureNotFoundError)
+
+			r.NoError(svc.SignImage(context.Background(), image))
+			valid, err := svc.VerifyImageSignature(context.Background(), image, "")
+			r.NoError(err)
+			r.Equal(1, valid)
+
+			// Signing again keeps the existing signature
+			r.NoError(svc.SignImage(context.Background(), image))
+			valid, err = svc.VerifyImageSignature(context.Background(), image, "")
+			r.NoError(err)
+			r.Equal(1, valid)
+
+			// Another key does not verify the signature
+			other := NewService(Config{
+				Signing: &SigningConfig{Key: &signing.KeyConfig{File: writeSigningKey(t, tc.keyType)}},
+			}, testRegistry{imageRef: imageRef}, nil, nil, nil, nil)
+			_, err = other.VerifyImageSignature(context.Background(), image, "")
+			r.ErrorIs(err, ImageSignatureNotFoundError)
+		})
+	}
+}
+
+func TestService_ResolveImageDigestRef(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	imageRef, image := pushTestImage(t)
+	svc := NewService(Config{}, testRegistry{imageRef: imageRef}, nil, nil, nil, nil)
+
+	resolved, err := svc.ResolveImageDigestRef(context.Background(), "")
+	r.NoError(err)
+	r.Equal(image.String(), resolved.String())
+
+	resolved, err = svc.ResolveImageDigestRef(context.Background(), image.String())
+	r.NoError(err)
+	r.Equal(image.String(), resolved.String())
+}
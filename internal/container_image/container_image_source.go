@@ -0,0 +1,277 @@
+package container_image
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+type ImageSourceType string
+
+const (
+	// ImageSourceDaemon reads the built image from the local docker daemon
+	ImageSourceDaemon ImageSourceType = "daemon"
+	// ImageSourceOciLayout reads the image from an OCI image layout directory
+	ImageSourceOciLayout ImageSourceType = "oci_layout"
+	// ImageSourceTarball reads the image from a 'docker save' or OCI layout tarball
+	ImageSourceTarball ImageSourceType = "tarball"
+	// ImageSourcePipeline pushes the image produced by the build pipeline without going through the docker daemon
+	ImageSourcePipeline ImageSourceType = "pipeline"
+)
+
+type ImageSourceConfig struct {
+	Type ImageSourceType `mapstructure:"type"`
+	// Path of the OCI layout directory or the tarball. For the pipeline source it is where the pipeline writes its tarball,
+	// a temporary file is used when empty.
+	Path string `mapstructure:"path"`
+}
+
+// sourceImage is the image to push: a single image, or an image index for multi-platform builds.
+type sourceImage struct {
+	image v1.Image
+	index v1.ImageIndex
+}
+
+func (s sourceImage) Digest() (v1.Hash, error) {
+	if s.index != nil {
+		return s.index.Digest()
+	}
+	return s.image.Digest()
+}
+
+func (s sourceImage) taggable() remote.Taggable {
+	if s.index != nil {
+		return s.index
+	}
+	return s.image
+}
+
+// Platforms returns the platform of the image, or the platforms of the images in the index.
+func (s sourceImage) Platforms() ([]v1.Platform, error) {
+	if s.index == nil {
+		configFile, err := s.image.ConfigFile()
+		if err != nil {
+			return nil, fmt.Errorf("getting image config file: %w", err)
+		}
+		platform := configFile.Platform()
+		if platform == nil {
+			return nil, nil
+		}
+		return []v1.Platform{*platform}, nil
+	}
+
+	manifest, err := s.index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("reading index manifest: %w", err)
+	}
+	platforms := make([]v1.Platform, 0, len(manifest.Manifests))
+	for _, desc := range manifest.Manifests {
+		// Build attestations are stored next to the images with an unknown platform
+		if desc.Platform == nil || desc.Platform.OS == "unknown" {
+			continue
+		}
+		platforms = append(platforms, *desc.Platform)
+	}
+	return platforms, nil
+}
+
+// checkImagePlatform makes sure the image, or one of the images in the index, runs on the platform.
+// Images without platform information are let through since there is nothing to check.
+func checkImagePlatform(ctx context.Context, source sourceImage, platform lib.Platform) error {
+	required, err := v1.ParsePlatform(string(platform))
+	if err != nil {
+		return fmt.Errorf("parsing platform %s: %w", platform, err)
+	}
+
+	platforms, err := source.Platforms()
+	if err != nil {
+		return err
+	}
+	if len(platforms) == 0 {
+		slog.WarnContext(ctx, "image has no platform information, skipping the platform check", "platform", platform)
+		return nil
+	}
+
+	imagePlatforms := make([]string, 0, len(platforms))
+	for _, p := range platforms {
+		if p.OS == required.OS && p.Architecture == required.Architecture && (required.Variant == "" || p.Variant == required.Variant) {
+			return nil
+		}
+		imagePlatforms = append(imagePlatforms, p.String())
+	}
+
+	return fmt.Errorf("%w - the image is built for %s but the service runs on %s, build the image for %s (e.g. the pipeline 'platform' setting or 'docker build --platform %s')",
+		ImagePlatformMismatchError, strings.Join(imagePlatforms, ", "), platform, platform, platform)
+}
+
+// loadImageFromOciLayout reads the image or the multi-platform index of an OCI layout directory, descending into nested indexes.
+func loadImageFromOciLayout(path string) (sourceImage, error) {
+	layoutPath, err := layout.FromPath(path)
+	if err != nil {
+		return sourceImage{}, fmt.Errorf("opening OCI layout %s: %w", path, err)
+	}
+
+	index, err := layoutPath.ImageIndex()
+	if err != nil {
+		return sourceImage{}, fmt.Errorf("reading OCI layout index %s: %w", path, err)
+	}
+
+	return sourceFromIndex(index)
+}
+
+func sourceFromIndex(index v1.ImageIndex) (sourceImage, error) {
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return sourceImage{}, fmt.Errorf("reading index manifest: %w", err)
+	}
+	if len(manifest.Manifests) == 0 {
+		return sourceImage{}, fmt.Errorf("the index has no images")
+	}
+
+	if len(manifest.Manifests) > 1 {
+		// Several images only make sense as the variants of a multi-platform image
+		for _, desc := range manifest.Manifests {
+			if !desc.MediaType.IsImage() || desc.Platform == nil {
+				return sourceImage{}, fmt.Errorf("expected exactly one image or a multi-platform index, found %d images without platforms", len(manifest.Manifests))
+			}
+		}
+		return sourceImage{index: index}, nil
+	}
+
+	desc := manifest.Manifests[0]
+	switch {
+	case desc.MediaType.IsImage():
+		image, err := index.Image(desc.Digest)
+		if err != nil {
+			return sourceImage{}, fmt.Errorf("reading image %s: %w", desc.Digest, err)
+		}
+		return sourceImage{image: image}, nil
+	case desc.MediaType.IsIndex():
+		nested, err := index.ImageIndex(desc.Digest)
+		if err != nil {
+			return sourceImage{}, fmt.Errorf("reading nested index %s: %w", desc.Digest, err)
+		}
+		return sourceFromIndex(nested)
+	}
+
+	return sourceImage{}, fmt.Errorf("unsupported media type %s in the index", desc.MediaType)
+}
+
+// loadImageFromTarball reads a 'docker save' tarball directly, an OCI layout tarball is extracted to a temporary
+// directory first. The returned cleanup removes the temporary files and must be called once the image is not used anymore.
+func loadImageFromTarball(path string) (sourceImage, func(), error) {
+	noop := func() {}
+
+	entries, err := listTarballEntries(path)
+	if err != nil {
+		return sourceImage{}, noop, err
+	}
+
+	// OCI layout tarballs exported by buildkit and dagger carry a docker manifest.json as well, the index.json wins
+	// since only it describes every platform
+	if _, ok := entries["index.json"]; !ok {
+		if _, ok := entries["manifest.json"]; !ok {
+			return sourceImage{}, noop, fmt.Errorf("tarball %s is neither a docker save archive nor an OCI layout", path)
+		}
+
+		image, err := tarball.ImageFromPath(path, nil)
+		if err != nil {
+			return sourceImage{}, noop, fmt.Errorf("reading docker tarball %s: %w", path, err)
+		}
+		return sourceImage{image: image}, noop, nil
+	}
+
+	dir, err := os.MkdirTemp("", "cloudctl-oci-layout-*")
+	if err != nil {
+		return sourceImage{}, noop, fmt.Errorf("creating temporary directory for OCI layout: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	if err := extractTarball(path, dir); err != nil {
+		cleanup()
+		return sourceImage{}, noop, err
+	}
+
+	source, err := loadImageFromOciLayout(dir)
+	if err != nil {
+		cleanup()
+		return sourceImage{}, noop, err
+	}
+
+	return source, cleanup, nil
+}
+
+func listTarballEntries(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening tarball %s: %w", path, err)
+	}
+	defer f.Close()
+
+	entries := make(map[string]struct{})
+	reader := tar.NewReader(f)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading tarball %s: %w", path, err)
+		}
+		entries[strings.TrimPrefix(header.Name, "./")] = struct{}{}
+	}
+
+	return entries, nil
+}
+
+func extractTarball(path, dir string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening tarball %s: %w", path, err)
+	}
+	defer f.Close()
+
+	reader := tar.NewReader(f)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading tarball %s: %w", path, err)
+		}
+
+		target := filepath.Join(dir, filepath.Clean("/"+header.Name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return fmt.Errorf("creating directory %s: %w", target, err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return fmt.Errorf("creating directory for %s: %w", target, err)
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+			if err != nil {
+				return fmt.Errorf("creating file %s: %w", target, err)
+			}
+			_, err = io.Copy(out, reader)
+			closeErr := out.Close()
+			if err := errors.Join(err, closeErr); err != nil {
+				return fmt.Errorf("extracting %s: %w", header.Name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package container_image
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/stretchr/testify/require"
+)
+
+func writeOciLayout(t *testing.T, dir string, image v1.Image) {
+	layoutPath, err := layout.Write(dir, empty.Index)
+	require.NoError(t, err)
+	require.NoError(t, layoutPath.AppendImage(image))
+}
+
+// tarDirectory packs the directory the way an exported OCI layout tarball is laid out.
+func tarDirectory(t *testing.T, dir, target string) {
+	out, err := os.Create(target)
+	require.NoError(t, err)
+	defer out.Close()
+
+	writer := tar.NewWriter(out)
+	defer writer.Close()
+
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := writer.WriteHeader(header); err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(writer, f)
+		return err
+	})
+	require.NoError(t, err)
+}
+
+func platformAddendum(t *testing.T, architecture string) mutate.IndexAddendum {
+	image, err := random.Image(64, 1)
+	require.NoError(t, err)
+	configFile, err := image.ConfigFile()
+	require.NoError(t, err)
+	configFile.OS, configFile.Architecture = "linux", architecture
+	image, err = mutate.ConfigFile(image, configFile)
+	require.NoError(t, err)
+
+	platform := &v1.Platform{OS: "linux", Architecture: architecture}
+	return mutate.IndexAddendum{Add: image, Descriptor: v1.Descriptor{Platform: platform}}
+}
+
+func TestLoadImageFromOciLayout(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	image, err := random.Image(256, 2)
+	r.NoError(err)
+	dir := t.TempDir()
+	writeOciLayout(t, dir, image)
+
+	loaded, err := loadImageFromOciLayout(dir)
+	r.NoError(err)
+
+	expected, err := image.Digest()
+	r.NoError(err)
+	actual, err := loaded.Digest()
+	r.NoError(err)
+	r.Equal(expected, actual)
+}
+
+func TestLoadImageFromOciLayout_SeveralImages(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	layoutPath, err := layout.Write(t.TempDir(), empty.Index)
+	r.NoError(err)
+	for range 2 {
+		image, err := random.Image(64, 1)
+		r.NoError(err)
+		r.NoError(layoutPath.AppendImage(image))
+	}
+
+	_, err = loadImageFromOciLayout(string(layoutPath))
+	r.ErrorContains(err, "found 2")
+}
+
+func TestLoadImageFromOciLayout_MultiPlatform(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	index := mutate.AppendManifests(empty.Index,
+		platformAddendum(t, "amd64"),
+		platformAddendum(t, "arm64"),
+	)
+	dir := t.TempDir()
+	layoutPath, err := layout.Write(dir, empty.Index)
+	r.NoError(err)
+	r.NoError(layoutPath.AppendIndex(index))
+
+	loaded, err := loadImageFromOciLayout(dir)
+	r.NoError(err)
+	r.NotNil(loaded.index)
+
+	expected, err := index.Digest()
+	r.NoError(err)
+	actual, err := loaded.Digest()
+	r.NoError(err)
+	r.Equal(expected, actual)
+
+	platforms, err := loaded.Platforms()
+	r.NoError(err)
+	r.Equal([]v1.Platform{{OS: "linux", Architecture: "amd64"}, {OS: "linux", Architecture: "arm64"}}, platforms)
+}
+
+func TestLoadImageFromTarball(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reads docker save tarball", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		image, err := random.Image(256, 2)
+		r.NoError(err)
+		tag, err := name.NewTag("example.com/app:v1")
+		r.NoError(err)
+		path := filepath.Join(t.TempDir(), "image.tar")
+		r.NoError(tarball.WriteToFile(path, tag, image))
+
+		loaded, cleanup, err := loadImageFromTarball(path)
+		r.NoError(err)
+		defer cleanup()
+
+		expected, err := image.ConfigName()
+		r.NoError(err)
+		actual, err := loaded.image.ConfigName()
+		r.NoError(err)
+		r.Equal(expected, actual)
+	})
+
+	t.Run("reads OCI layout tarball", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		image, err := random.Image(256, 2)
+		r.NoError(err)
+		dir := t.TempDir()
+		writeOciLayout(t, filepath.Join(dir, "layout"), image)
+		path := filepath.Join(dir, "image.tar")
+		tarDirectory(t, filepath.Join(dir, "layout"), path)
+
+		loaded, cleanup, err := loadImageFromTarball(path)
+		r.NoError(err)
+
+		expected, err := image.Digest()
+		r.NoError(err)
+		actual, err := loaded.Digest()
+		r.NoError(err)
+		r.Equal(expected, actual)
+
+		layers, err := loaded.image.Layers()
+		r.NoError(err)
+		r.Len(layers, 2)
+
+		cleanup()
+	})
+
+	t.Run("rejects unknown tarballs", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		dir := t.TempDir()
+		r.NoError(os.MkdirAll(filepath.Join(dir, "content"), 0o755))
+		r.NoError(os.WriteFile(filepath.Join(dir, "content", "file.txt"), []byte("hello"), 0o644))
+		path := filepath.Join(dir, "archive.tar")
+		tarDirectory(t, filepath.Join(dir, "content"), path)
+
+		_, _, err := loadImageFromTarball(path)
+		r.ErrorContains(err, "neither a docker save archive nor an OCI layout")
+	})
+}
+
+func TestCheckImagePlatform(t *testing.T) {
+	t.Parallel()
+
+	arm64Image := platformAddendum(t, "arm64").Add.(v1.Image)
+	index := sourceImage{index: mutate.AppendManifests(empty.Index,
+		platformAddendum(t, "amd64"),
+		platformAddendum(t, "arm64"),
+	)}
+
+	t.Run("accepts the matching image", func(t *testing.T) {
+		t.Parallel()
+		require.NoError(t, checkImagePlatform(context.Background(), sourceImage{image: arm64Image}, lib.PlatformLinuxArm64))
+	})
+
+	t.Run("accepts an index with the platform", func(t *testing.T) {
+		t.Parallel()
+		require.NoError(t, checkImagePlatform(context.Background(), index, lib.PlatformLinuxAmd64))
+	})
+
+	t.Run("refuses an image built for another platform", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		err := checkImagePlatform(context.Background(), sourceImage{image: arm64Image}, lib.PlatformLinuxAmd64)
+		r.ErrorIs(err, ImagePlatformMismatchError)
+		r.ErrorContains(err, "linux/arm64")
+	})
+
+	t.Run("refuses an index without the platform", func(t *testing.T) {
+		t.Parallel()
+		require.ErrorIs(t, checkImagePlatform(context.Background(), index, "windows/amd64"), ImagePlatformMismatchError)
+	})
+}
@@ -0,0 +1,299 @@
+package layercache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// DefaultMaxSize is the size the cached blobs are pruned to after a push when no limit is configured.
+const DefaultMaxSize int64 = 5 << 30
+
+// MaxPrunedEntries is the number of pruned blobs whose metadata is kept at most, the most recently written first.
+const MaxPrunedEntries = 1000
+
+const (
+	blobExtension     = ".blob"
+	metadataExtension = ".json"
+)
+
+// Key identifies a recompressed layer: the same uncompressed content compressed with the same settings
+// always produces the same blob, so it does not have to be compressed again.
+type Key struct {
+	DiffID    v1.Hash
+	Algorithm string
+	Level     int
+}
+
+// Entry describes a cached recompressed layer.
+// The metadata outlives the blob when the blob is pruned, so the digest can still be checked in the registry,
+// until it is older than every blob left in the cache or too many pruned entries are newer.
+type Entry struct {
+	DiffID    v1.Hash         `json:"diff_id"`
+	Digest    v1.Hash         `json:"digest"`
+	Size      int64           `json:"size"`
+	MediaType types.MediaType `json:"media_type"`
+	CreatedAt time.Time       `json:"created_at"`
+	// BlobPath is where the recompressed blob is stored, empty when it was pruned
+	BlobPath string `json:"-"`
+}
+
+type PruneResult struct {
+	RemovedBlobs    int
+	RemovedMetadata int
+	FreedBytes      int64
+	RemainingBytes  int64
+}
+
+// Cache stores recompressed layer blobs and their digests on disk.
+// Blobs and metadata are written to temporary files and renamed into place,
+// so several cloudctl processes can share the same cache directory.
+type Cache struct {
+	dir string
+}
+
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// DefaultDir returns the layer cache directory inside the user cache directory.
+func DefaultDir() (string, error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("getting user cache directory: %w", err)
+	}
+	return filepath.Join(userCacheDir, "cloudctl", "layers"), nil
+}
+
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Get returns the entry for the key. The blob access time is refreshed on a hit, so the blob is pruned last.
+func (c *Cache) Get(key Key) (Entry, bool, error) {
+	var entry Entry
+
+	metadataPath, blobPath := c.paths(key)
+	data, err := os.ReadFile(metadataPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return entry, false, nil
+	}
+	if err != nil {
+		return entry, false, fmt.Errorf("reading cached layer metadata: %w", err)
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return entry, false, fmt.Errorf("decoding cached layer metadata %s: %w", metadataPath, err)
+	}
+
+	stat, err := os.Stat(blobPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return entry, false, fmt.Errorf("checking cached layer blob: %w", err)
+	}
+	if stat != nil && stat.Size() == entry.Size {
+		now := time.Now()
+		if err := os.Chtimes(blobPath, now, now); err != nil {
+			return entry, false, fmt.Errorf("refreshing cached layer blob access time: %w", err)
+		}
+		entry.BlobPath = blobPath
+	}
+
+	return entry, true, nil
+}
+
+// Put moves the blob at path into the cache and stores the entry for the key.
+// The returned entry points to the cached blob, path must not be used afterwards.
+func (c *Cache) Put(key Key, entry Entry, path string) (Entry, error) {
+	metadataPath, blobPath := c.paths(key)
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0o755); err != nil {
+		return entry, fmt.Errorf("creating layer cache directory: %w", err)
+	}
+
+	if err := moveFile(path, blobPath); err != nil {
+		return entry, fmt.Errorf("moving layer blob into the cache: %w", err)
+	}
+
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return entry, fmt.Errorf("encoding cached layer metadata: %w", err)
+	}
+	if err := writeFileAtomically(metadataPath, data); err != nil {
+		return entry, fmt.Errorf("writing cached layer metadata: %w", err)
+	}
+
+	entry.BlobPath = blobPath
+	return entry, nil
+}
+
+// Prune removes the least recently used blobs until the cached blobs take at most maxSize bytes.
+// The metadata of pruned blobs is small and still lets a push skip layers the registry already has,
+// it is removed once it is older than the least recently used blob that is kept or exceeds MaxPrunedEntries,
+// so it does not pile up.
+func (c *Cache) Prune(maxSize int64) (PruneResult, error) {
+	var result PruneResult
+
+	type file struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	blobs := make([]file, 0, 32)
+	metadata := make([]file, 0, 32)
+	err := filepath.WalkDir(c.dir, func(path string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		ext := filepath.Ext(path)
+		if ext != blobExtension && ext != metadataExtension {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		f := file{path: path, size: info.Size(), modTime: info.ModTime()}
+		if ext == metadataExtension {
+			metadata = append(metadata, f)
+			return nil
+		}
+		blobs = append(blobs, f)
+		result.RemainingBytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("listing cached layers: %w", err)
+	}
+
+	slices.SortFunc(blobs, func(a, b file) int {
+		return a.modTime.Compare(b.modTime)
+	})
+
+	kept := make(map[string]bool, len(blobs))
+	var oldestKept time.Time
+	for _, b := range blobs {
+		if result.RemainingBytes <= maxSize {
+			if oldestKept.IsZero() {
+				oldestKept = b.modTime
+			}
+			kept[strings.TrimSuffix(b.path, blobExtension)] = true
+			continue
+		}
+		if err := os.Remove(b.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return result, fmt.Errorf("removing cached layer %s: %w", b.path, err)
+		}
+		result.RemovedBlobs++
+		result.FreedBytes += b.size
+		result.RemainingBytes -= b.size
+	}
+
+	// Newer metadata, or all of it when no blob is kept, is capped to the most recently written entries
+	orphaned := make([]file, 0, len(metadata))
+	for _, m := range metadata {
+		if kept[strings.TrimSuffix(m.path, metadataExtension)] {
+			continue
+		}
+		if oldestKept.IsZero() || !m.modTime.Before(oldestKept) {
+			orphaned = append(orphaned, m)
+			continue
+		}
+		if err := os.Remove(m.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return result, fmt.Errorf("removing cached layer metadata %s: %w", m.path, err)
+		}
+		result.RemovedMetadata++
+	}
+
+	slices.SortFunc(orphaned, func(a, b file) int {
+		return b.modTime.Compare(a.modTime)
+	})
+	for _, m := range orphaned[min(len(orphaned), MaxPrunedEntries):] {
+		if err := os.Remove(m.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return result, fmt.Errorf("removing cached layer metadata %s: %w", m.path, err)
+		}
+		result.RemovedMetadata++
+	}
+
+	return result, nil
+}
+
+// Clear removes the whole cache including the metadata.
+func (c *Cache) Clear() error {
+	if err := os.RemoveAll(c.dir); err != nil {
+		return fmt.Errorf("removing layer cache %s: %w", c.dir, err)
+	}
+	return nil
+}
+
+func (c *Cache) paths(key Key) (metadataPath, blobPath string) {
+	settings := strings.Join([]string{key.Algorithm, strconv.Itoa(key.Level)}, "-")
+	base := filepath.Join(c.dir, settings, key.DiffID.Algorithm+"-"+key.DiffID.Hex)
+	return base + metadataExtension, base + blobExtension
+}
+
+// moveFile renames the file and falls back to copying when the source is on another file system.
+func moveFile(source, target string) error {
+	if err := os.Rename(source, target); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), filepath.Base(target)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return err
+	}
+
+	return os.Remove(source)
+}
+
+func writeFileAtomically(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
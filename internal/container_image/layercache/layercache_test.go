@@ -0,0 +1,153 @@
+package layercache
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/require"
+)
+
+func writeBlob(t *testing.T, size int) string {
+	path := filepath.Join(t.TempDir(), "blob")
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0o644))
+	return path
+}
+
+func hashOf(t *testing.T, content string) v1.Hash {
+	hash, _, err := v1.SHA256(strings.NewReader(content))
+	require.NoError(t, err)
+	return hash
+}
+
+func testKey(t *testing.T, content string) Key {
+	return Key{DiffID: hashOf(t, content), Algorithm: "zstd", Level: 3}
+}
+
+func TestCache_GetPut(t *testing.T) {
+	t.Parallel()
+
+	t.Run("misses unknown layers", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		_, ok, err := NewCache(t.TempDir()).Get(testKey(t, "aa"))
+		r.NoError(err)
+		r.False(ok)
+	})
+
+	t.Run("returns the stored layer", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		cache := NewCache(t.TempDir())
+		key := testKey(t, "aa")
+		digest := hashOf(t, "compressed")
+		source := writeBlob(t, 10)
+
+		stored, err := cache.Put(key, Entry{DiffID: key.DiffID, Digest: digest, Size: 10, MediaType: types.OCILayerZStd}, source)
+		r.NoError(err)
+		r.NoFileExists(source)
+		r.FileExists(stored.BlobPath)
+
+		entry, ok, err := cache.Get(key)
+		r.NoError(err)
+		r.True(ok)
+		r.Equal(digest, entry.Digest)
+		r.Equal(int64(10), entry.Size)
+		r.Equal(types.OCILayerZStd, entry.MediaType)
+		r.Equal(stored.BlobPath, entry.BlobPath)
+
+		// Other compression settings are another layer
+		_, ok, err = cache.Get(Key{DiffID: key.DiffID, Algorithm: "zstd", Level: 9})
+		r.NoError(err)
+		r.False(ok)
+	})
+}
+
+func TestCache_Prune(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	cache := NewCache(t.TempDir())
+	old := time.Now().Add(-time.Hour)
+	for i, content := range []string{"01", "02", "03", "04"} {
+		entry, err := cache.Put(testKey(t, content), Entry{DiffID: hashOf(t, content), Digest: hashOf(t, "compressed "+content), Size: 100}, writeBlob(t, 100))
+		r.NoError(err)
+
+		modTime := old.Add(time.Duration(i) * time.Minute)
+		r.NoError(os.Chtimes(entry.BlobPath, modTime, modTime))
+		r.NoError(os.Chtimes(strings.TrimSuffix(entry.BlobPath, blobExtension)+metadataExtension, modTime, modTime))
+	}
+
+	// The layer was stored again after the blob of 04, its metadata is newer than every kept blob
+	metadataPath, _ := cache.paths(testKey(t, "02"))
+	newer := old.Add(5 * time.Minute)
+	r.NoError(os.Chtimes(metadataPath, newer, newer))
+
+	// Reading a layer makes it the most recently used one
+	_, ok, err := cache.Get(testKey(t, "01"))
+	r.NoError(err)
+	r.True(ok)
+
+	result, err := cache.Prune(250)
+	r.NoError(err)
+	r.Equal(PruneResult{RemovedBlobs: 2, RemovedMetadata: 1, FreedBytes: 200, RemainingBytes: 200}, result)
+
+	for _, content := range []string{"01", "04"} {
+		entry, ok, err := cache.Get(testKey(t, content))
+		r.NoError(err)
+		r.True(ok)
+		r.NotEmpty(entry.BlobPath)
+	}
+
+	// The metadata of pruned blobs is kept while it is newer than the least recently used kept blob
+	entry, ok, err := cache.Get(testKey(t, "02"))
+	r.NoError(err)
+	r.True(ok)
+	r.Empty(entry.BlobPath)
+
+	_, ok, err = cache.Get(testKey(t, "03"))
+	r.NoError(err)
+	r.False(ok)
+
+	r.NoError(cache.Clear())
+	_, ok, err = cache.Get(testKey(t, "01"))
+	r.NoError(err)
+	r.False(ok)
+}
+
+func TestCache_Prune_capsPrunedEntries(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	cache := NewCache(t.TempDir())
+	old := time.Now().Add(-time.Hour)
+	for i := range MaxPrunedEntries + 1 {
+		content := strconv.Itoa(i)
+		entry, err := cache.Put(testKey(t, content), Entry{DiffID: hashOf(t, content), Digest: hashOf(t, "compressed "+content), Size: 1}, writeBlob(t, 1))
+		r.NoError(err)
+
+		modTime := old.Add(time.Duration(i) * time.Second)
+		r.NoError(os.Chtimes(strings.TrimSuffix(entry.BlobPath, blobExtension)+metadataExtension, modTime, modTime))
+	}
+
+	// No blob is kept, so only the number of entries limits the metadata
+	result, err := cache.Prune(0)
+	r.NoError(err)
+	r.Equal(MaxPrunedEntries+1, result.RemovedBlobs)
+	r.Equal(1, result.RemovedMetadata)
+
+	_, ok, err := cache.Get(testKey(t, "0"))
+	r.NoError(err)
+	r.False(ok)
+
+	_, ok, err = cache.Get(testKey(t, strconv.Itoa(MaxPrunedEntries)))
+	r.NoError(err)
+	r.True(ok)
+}
@@ -0,0 +1,162 @@
+package provenance
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+const (
+	StatementType = "https://in-toto.io/Statement/v1"
+	// PredicateType is the SLSA build provenance predicate
+	PredicateType = "https://slsa.dev/provenance/v1"
+	// PredicateTypeAnnotation is set on the attestation manifest so it can be filtered without downloading it
+	PredicateTypeAnnotation = "in-toto.io/predicate-type"
+)
+
+// Build types tell how to interpret the external parameters of the build definition.
+const (
+	BuildTypeCmd      = "https://github.com/AnotherFullstackDev/cloud-ctl/build/cmd@v1"
+	BuildTypePipeline = "https://github.com/AnotherFullstackDev/cloud-ctl/build/pipeline@v1"
+	// BuildTypePush is used for images built outside cloudctl, only the push is attested
+	BuildTypePush = "https://github.com/AnotherFullstackDev/cloud-ctl/push@v1"
+)
+
+// Statement is an in-toto statement with a SLSA provenance predicate.
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     Predicate `json:"predicate"`
+}
+
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type Predicate struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+type BuildDefinition struct {
+	BuildType            string               `json:"buildType"`
+	ExternalParameters   map[string]any       `json:"externalParameters"`
+	ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+type ResourceDescriptor struct {
+	URI         string            `json:"uri,omitempty"`
+	Digest      map[string]string `json:"digest,omitempty"`
+	Annotations map[string]any    `json:"annotations,omitempty"`
+}
+
+type RunDetails struct {
+	Builder  Builder  `json:"builder"`
+	Metadata Metadata `json:"metadata"`
+}
+
+type Builder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+type Metadata struct {
+	InvocationID string     `json:"invocationId,omitempty"`
+	StartedOn    *time.Time `json:"startedOn,omitempty"`
+	FinishedOn   *time.Time `json:"finishedOn,omitempty"`
+}
+
+// Source is the state of the git repository the image was built from.
+type Source struct {
+	RemoteURL string
+	Commit    string
+	Branch    string
+	Tag       string
+	Dirty     bool
+}
+
+// Build is what produced the image, StartedOn and FinishedOn are left out of the statement when zero.
+type Build struct {
+	Type       string
+	Parameters map[string]any
+	StartedOn  time.Time
+	FinishedOn time.Time
+}
+
+// NewStatement describes how the image in the repository with the digest was built.
+func NewStatement(repository string, digest v1.Hash, build Build, source *Source, builder Builder) Statement {
+	statement := Statement{
+		Type:          StatementType,
+		Subject:       []Subject{{Name: repository, Digest: map[string]string{digest.Algorithm: digest.Hex}}},
+		PredicateType: PredicateType,
+		Predicate: Predicate{
+			BuildDefinition: BuildDefinition{
+				BuildType:          build.Type,
+				ExternalParameters: build.Parameters,
+			},
+			RunDetails: RunDetails{Builder: builder},
+		},
+	}
+	if statement.Predicate.BuildDefinition.ExternalParameters == nil {
+		statement.Predicate.BuildDefinition.ExternalParameters = map[string]any{}
+	}
+	if !build.StartedOn.IsZero() {
+		startedOn := build.StartedOn.UTC()
+		statement.Predicate.RunDetails.Metadata.StartedOn = &startedOn
+	}
+	if !build.FinishedOn.IsZero() {
+		finishedOn := build.FinishedOn.UTC()
+		statement.Predicate.RunDetails.Metadata.FinishedOn = &finishedOn
+	}
+
+	if source != nil {
+		statement.Predicate.BuildDefinition.ResolvedDependencies = []ResourceDescriptor{source.descriptor()}
+	}
+
+	return statement
+}
+
+// descriptor returns the source the way SLSA names git dependencies: git+<remote>@<ref> with the commit as digest.
+func (s Source) descriptor() ResourceDescriptor {
+	descriptor := ResourceDescriptor{
+		Digest: map[string]string{"gitCommit": s.Commit},
+		Annotations: map[string]any{
+			"dirty": s.Dirty,
+		},
+	}
+	if s.Branch != "" {
+		descriptor.Annotations["branch"] = s.Branch
+	}
+	if s.Tag != "" {
+		descriptor.Annotations["tag"] = s.Tag
+	}
+
+	if s.RemoteURL != "" {
+		descriptor.URI = "git+" + redactRemoteURL(s.RemoteURL)
+		switch {
+		case s.Tag != "":
+			descriptor.URI += "@refs/tags/" + s.Tag
+		case s.Branch != "":
+			descriptor.URI += "@refs/heads/" + s.Branch
+		}
+	}
+
+	return descriptor
+}
+
+// redactRemoteURL drops credentials CI systems put into https remotes, scp-like ssh remotes are returned as is.
+func redactRemoteURL(remote string) string {
+	if !strings.Contains(remote, "://") {
+		return remote
+	}
+	parsed, err := url.Parse(remote)
+	if err != nil {
+		return remote
+	}
+	parsed.User = nil
+	return parsed.String()
+}
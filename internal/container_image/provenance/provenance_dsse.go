@@ -0,0 +1,108 @@
+package provenance
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/signing"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+var (
+	UnsignedEnvelopeError = errors.New("attestation has no valid signature")
+)
+
+const (
+	// EnvelopeMediaType is both the artifact type of the attestation referrer and the media type of its layer
+	EnvelopeMediaType types.MediaType = "application/vnd.dsse.envelope.v1+json"
+	// PayloadType marks in-toto statements carried in DSSE envelopes
+	PayloadType = "application/vnd.in-toto+json"
+)
+
+// Envelope is a DSSE envelope, the signatures cover the pre-authentication encoding of the payload.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// NewEnvelope wraps the statement in an envelope signed with the key, the envelope has no signatures when the key is nil.
+func NewEnvelope(statement Statement, key crypto.Signer) ([]byte, error) {
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return nil, fmt.Errorf("encoding provenance statement: %w", err)
+	}
+
+	envelope := Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures:  []Signature{},
+	}
+	if key != nil {
+		signature, err := signing.Sign(key, pae(PayloadType, payload))
+		if err != nil {
+			return nil, fmt.Errorf("signing provenance statement: %w", err)
+		}
+		envelope.Signatures = append(envelope.Signatures, Signature{Sig: base64.StdEncoding.EncodeToString(signature)})
+	}
+
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, fmt.Errorf("encoding DSSE envelope: %w", err)
+	}
+	return data, nil
+}
+
+// OpenEnvelope decodes the statement of the envelope. When a public key is given,
+// UnsignedEnvelopeError is returned unless one of the signatures is valid for it.
+func OpenEnvelope(data []byte, publicKey crypto.PublicKey) (Statement, error) {
+	var statement Statement
+
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return statement, fmt.Errorf("decoding DSSE envelope: %w", err)
+	}
+	if envelope.PayloadType != PayloadType {
+		return statement, fmt.Errorf("unexpected DSSE payload type %q", envelope.PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return statement, fmt.Errorf("decoding DSSE payload: %w", err)
+	}
+
+	if publicKey != nil && !hasValidSignature(envelope, payload, publicKey) {
+		return statement, UnsignedEnvelopeError
+	}
+
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		return statement, fmt.Errorf("decoding provenance statement: %w", err)
+	}
+	return statement, nil
+}
+
+func hasValidSignature(envelope Envelope, payload []byte, publicKey crypto.PublicKey) bool {
+	encoded := pae(envelope.PayloadType, payload)
+	for _, sig := range envelope.Signatures {
+		signature, err := base64.StdEncoding.DecodeString(sig.Sig)
+		if err != nil {
+			continue
+		}
+		if signing.Verify(publicKey, encoded, signature) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// pae is the DSSE pre-authentication encoding the signature is computed over.
+func pae(payloadType string, payload []byte) []byte {
+	return fmt.Appendf(nil, "DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload)
+}
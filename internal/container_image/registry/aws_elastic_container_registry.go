@@ -36,6 +36,10 @@ func (r *AwsECR) GetKeychain() authn.Keychain {
 	return authn.NewKeychainFromHelper(helper)
 }
 
+func (r *AwsECR) IsInsecure() bool {
+	return false
+}
+
 func (r *AwsECR) GetImageRef() (string, error) {
 	// Required format: <aws_account_id>.dkr.ecr.<region>.amazonaws.com/<repository>:<tag>
 	imageID := string(r.config)
@@ -0,0 +1,129 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/azure/identity"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+const (
+	ACRDomainSuffix = ".azurecr.io"
+	// acrTokenUsername is the fixed user name ACR expects together with a refresh token obtained via token exchange
+	acrTokenUsername = "00000000-0000-0000-0000-000000000000"
+)
+
+// AzureContainerRegistryConfig - Azure Container Registry destination config
+type AzureContainerRegistryConfig string
+
+type acrExchangeResponse struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type AzureContainerRegistry struct {
+	storage      lib.CredentialsStorage
+	config       AzureContainerRegistryConfig
+	envKeys      identity.EnvKeys
+	authorityURL string
+	scheme       string
+}
+
+func NewAzureContainerRegistry(storage lib.CredentialsStorage, config AzureContainerRegistryConfig, envKeys identity.EnvKeys) Registry {
+	return &AzureContainerRegistry{
+		storage:      storage,
+		config:       config,
+		envKeys:      envKeys,
+		authorityURL: identity.DefaultAuthorityURL,
+		scheme:       "https",
+	}
+}
+
+func (r *AzureContainerRegistry) GetAuthType() AuthType {
+	return AuthTypeAuthenticator
+}
+
+// GetAuthentication exchanges a Microsoft Entra ID token of the service principal for an ACR refresh token,
+// the same way 'az acr login' does.
+func (r *AzureContainerRegistry) GetAuthentication() (authn.Authenticator, error) {
+	ctx := context.Background()
+
+	ref, err := name.NewTag(string(r.config))
+	if err != nil {
+		return nil, fmt.Errorf("parsing acr image reference: %w", err)
+	}
+	registryHost := ref.RegistryStr()
+
+	sp, err := identity.GetServicePrincipal(r.storage, r.envKeys)
+	if err != nil {
+		return nil, fmt.Errorf("requesting acr credentials: %w", err)
+	}
+
+	accessToken, err := identity.GetToken(ctx, r.authorityURL, sp, identity.ManagementScope)
+	if err != nil {
+		return nil, fmt.Errorf("getting azure access token for acr: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "access_token")
+	form.Set("service", registryHost)
+	form.Set("tenant", sp.TenantID)
+	form.Set("access_token", accessToken)
+
+	exchangeURL := fmt.Sprintf("%s://%s/oauth2/exchange", r.scheme, registryHost)
+	slog.Debug("exchanging azure access token for acr refresh token", "url", exchangeURL)
+
+	var exchange acrExchangeResponse
+	if _, err := identity.PostForm(ctx, exchangeURL, form, &exchange); err != nil {
+		return nil, fmt.Errorf("exchanging azure access token for acr refresh token: %w", err)
+	}
+	if exchange.RefreshToken == "" {
+		return nil, fmt.Errorf("acr token exchange returned no refresh token")
+	}
+
+	return authn.FromConfig(authn.AuthConfig{
+		Username: acrTokenUsername,
+		Password: exchange.RefreshToken,
+	}), nil
+}
+
+func (r *AzureContainerRegistry) ResetAuthentication() error {
+	if err := identity.ResetServicePrincipal(r.storage); err != nil {
+		return fmt.Errorf("resetting acr credentials: %w", err)
+	}
+	return nil
+}
+
+func (r *AzureContainerRegistry) GetKeychain() authn.Keychain {
+	return nil
+}
+
+func (r *AzureContainerRegistry) IsInsecure() bool {
+	return false
+}
+
+func (r *AzureContainerRegistry) GetImageRef() (string, error) {
+	// Required format: <registry>.azurecr.io/<repository>:<tag>, the repository can be nested
+	imageID := string(r.config)
+	parts := strings.SplitN(imageID, "/", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("%w - invalid ACR image format: %s, expected format: <registry>.azurecr.io/<repository>:<tag>", lib.BadUserInputError, imageID)
+	}
+
+	registryHost := parts[0]
+	if !strings.HasSuffix(strings.ToLower(registryHost), ACRDomainSuffix) || len(registryHost) == len(ACRDomainSuffix) {
+		return "", fmt.Errorf("%w - invalid ACR host: %s, expected format: <registry>%s", lib.BadUserInputError, registryHost, ACRDomainSuffix)
+	}
+
+	repositoryAndTag := strings.SplitN(parts[1], ":", 2)
+	if len(repositoryAndTag) != 2 || repositoryAndTag[0] == "" || repositoryAndTag[1] == "" {
+		return "", fmt.Errorf("%w - invalid ACR image format: %s, missing tag", lib.BadUserInputError, imageID)
+	}
+
+	return imageID, nil
+}
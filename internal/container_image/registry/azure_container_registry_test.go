@@ -0,0 +1,124 @@
+package registry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/azure/identity"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// newTokenStandIn serves both the Entra ID token endpoint and the ACR token exchange endpoint.
+func newTokenStandIn(t *testing.T, clientSecret string) *httptest.Server {
+	server := testutil.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/tenant-1/oauth2/v2.0/token":
+			if r.PostForm.Get("grant_type") != "client_credentials" || r.PostForm.Get("client_id") != "client-1" || r.PostForm.Get("client_secret") != clientSecret {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"invalid_client"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token":"aad-token","token_type":"Bearer","expires_in":3600}`))
+		case "/oauth2/exchange":
+			if r.PostForm.Get("grant_type") != "access_token" || r.PostForm.Get("access_token") != "aad-token" ||
+				r.PostForm.Get("tenant") != "tenant-1" || r.PostForm.Get("service") != r.Host {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"refresh_token":"acr-refresh-token"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	return server
+}
+
+func newTestAzureContainerRegistry(server *httptest.Server, storage lib.CredentialsStorage) *AzureContainerRegistry {
+	host := strings.TrimPrefix(server.URL, "http://")
+	r := NewAzureContainerRegistry(storage, AzureContainerRegistryConfig(host+"/app:v1"), identity.EnvKeys{}).(*AzureContainerRegistry)
+	r.authorityURL = server.URL
+	r.scheme = "http"
+	return r
+}
+
+func TestAzureContainerRegistry_GetAuthentication(t *testing.T) {
+	t.Parallel()
+
+	t.Run("exchanges the service principal token for an acr refresh token", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		server := newTokenStandIn(t, "secret")
+		storage := testutil.NewMemoryCredentialsStorage(map[string]string{
+			"azure_tenant_id":     "tenant-1",
+			"azure_client_id":     "client-1",
+			"azure_client_secret": "secret",
+		})
+		registry := newTestAzureContainerRegistry(server, storage)
+
+		auth, err := registry.GetAuthentication()
+		r.NoError(err)
+
+		authConfig, err := auth.Authorization()
+		r.NoError(err)
+		r.Equal(acrTokenUsername, authConfig.Username)
+		r.Equal("acr-refresh-token", authConfig.Password)
+	})
+
+	t.Run("reports rejected service principal as unauthorized", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		server := newTokenStandIn(t, "secret")
+		storage := testutil.NewMemoryCredentialsStorage(map[string]string{
+			"azure_tenant_id":     "tenant-1",
+			"azure_client_id":     "client-1",
+			"azure_client_secret": "wrong-secret",
+		})
+		registry := newTestAzureContainerRegistry(server, storage)
+
+		_, err := registry.GetAuthentication()
+		r.ErrorIs(err, identity.UnauthorizedError)
+
+		r.NoError(registry.ResetAuthentication())
+		r.Empty(storage.Values())
+	})
+}
+
+func TestAzureContainerRegistry_GetImageRef(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		image string
+		valid bool
+	}{
+		{image: "myregistry.azurecr.io/app:v1", valid: true},
+		{image: "myregistry.azurecr.io/team/app:v1", valid: true},
+		{image: "myregistry.azurecr.io/app", valid: false},
+		{image: "ghcr.io/owner/app:v1", valid: false},
+		{image: "app:v1", valid: false},
+	} {
+		t.Run(tc.image, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			imageRef, err := NewAzureContainerRegistry(testutil.NewMemoryCredentialsStorage(nil), AzureContainerRegistryConfig(tc.image), identity.EnvKeys{}).GetImageRef()
+			if !tc.valid {
+				r.ErrorIs(err, lib.BadUserInputError)
+				return
+			}
+			r.NoError(err)
+			r.Equal(tc.image, imageRef)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+)
+
+const DockerHubDomain = "docker.io"
+
+// DockerHubConfig - Docker Hub destination config, the registry domain can be omitted
+type DockerHubConfig string
+
+type DockerHub struct {
+	*OciRegistry
+	config DockerHubConfig
+}
+
+func NewDockerHub(storage lib.CredentialsStorage, config DockerHubConfig, usernameEnvs, passwordEnvs []string) Registry {
+	image := string(config)
+	if first, _, found := strings.Cut(image, "/"); !found || !isDockerHubDomain(first) {
+		image = fmt.Sprintf("%s/%s", DockerHubDomain, image)
+	}
+
+	return &DockerHub{
+		OciRegistry: NewOciRegistry(storage, OciRegistryConfig{Image: image}, usernameEnvs, passwordEnvs).(*OciRegistry),
+		config:      config,
+	}
+}
+
+func (r *DockerHub) GetImageRef() (string, error) {
+	// Required format: [docker.io/]<namespace>/<repository>:<tag>
+	imageID := r.OciRegistry.config.Image
+	parts := strings.Split(imageID, "/")
+	if len(parts) != 3 || !isDockerHubDomain(parts[0]) {
+		return "", fmt.Errorf("%w - invalid Docker Hub image format: %s, expected format: [docker.io/]<namespace>/<repository>:<tag>", lib.BadUserInputError, r.config)
+	}
+
+	repositoryAndTag := strings.SplitN(parts[2], ":", 2)
+	if len(repositoryAndTag) != 2 || repositoryAndTag[0] == "" || repositoryAndTag[1] == "" {
+		return "", fmt.Errorf("%w - invalid Docker Hub image format: %s, missing tag", lib.BadUserInputError, r.config)
+	}
+
+	return imageID, nil
+}
+
+func isDockerHubDomain(host string) bool {
+	return strings.EqualFold(host, DockerHubDomain) || strings.EqualFold(host, "index.docker.io")
+}
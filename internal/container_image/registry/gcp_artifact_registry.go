@@ -39,6 +39,10 @@ func (r *GcpArtifactRegistry) GetKeychain() authn.Keychain {
 	return google.Keychain
 }
 
+func (r *GcpArtifactRegistry) IsInsecure() bool {
+	return false
+}
+
 func (r *GcpArtifactRegistry) GetImageRef() (string, error) {
 	imageID := string(r.config)
 
@@ -76,6 +76,10 @@ func (r *GithubContainerRegistry) GetKeychain() authn.Keychain {
 	return nil
 }
 
+func (r *GithubContainerRegistry) IsInsecure() bool {
+	return false
+}
+
 func (r *GithubContainerRegistry) GetImageRef() (string, error) {
 	// Required format: ghcr.io/<owner>/<repository>:<tag>
 	imageID := string(r.config)
@@ -0,0 +1,165 @@
+package registry
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// OciRegistryConfig - any OCI distribution compatible registry (Quay, Harbor, self-hosted registry:2, etc.)
+type OciRegistryConfig struct {
+	Image string `mapstructure:"image"`
+	// Insecure allows plain HTTP and skips TLS verification, meant for local and test registries
+	Insecure bool `mapstructure:"insecure"`
+}
+
+// OciRegistry resolves credentials in the following order: docker config.json (including credential helpers),
+// basic auth from the credentials storage or environment, anonymous access.
+// Once the registry rejected the credentials they are requested from the terminal and stored.
+type OciRegistry struct {
+	storage           lib.CredentialsStorage
+	config            OciRegistryConfig
+	keychain          authn.Keychain
+	usernameEnvs      []string
+	passwordEnvs      []string
+	promptCredentials bool
+}
+
+func NewOciRegistry(storage lib.CredentialsStorage, config OciRegistryConfig, usernameEnvs, passwordEnvs []string) Registry {
+	return &OciRegistry{
+		storage:      storage,
+		config:       config,
+		keychain:     authn.DefaultKeychain,
+		usernameEnvs: usernameEnvs,
+		passwordEnvs: passwordEnvs,
+	}
+}
+
+func (r *OciRegistry) GetAuthType() AuthType {
+	return AuthTypeAuthenticator
+}
+
+func (r *OciRegistry) GetAuthentication() (authn.Authenticator, error) {
+	reg, err := r.parseRegistry()
+	if err != nil {
+		return nil, err
+	}
+	host := reg.RegistryStr()
+	usernameKey, passwordKey := r.storageKeys(host)
+
+	if r.promptCredentials {
+		// Environment is skipped on purpose - it is where the rejected credentials could have come from
+		username, err := lib.GetSecretFromEnvOrInput(r.storage, usernameKey, fmt.Sprintf("Registry Username (%s)", host), nil, os.Stdin, os.Stdout, fmt.Sprintf("Please provide username for %s", host))
+		if err != nil {
+			return nil, fmt.Errorf("requesting %s username: %w", host, err)
+		}
+		password, err := lib.GetSecretFromEnvOrInput(r.storage, passwordKey, fmt.Sprintf("Registry Password (%s)", host), nil, os.Stdin, os.Stdout, fmt.Sprintf("Please provide password or access token for %s", host))
+		if err != nil {
+			return nil, fmt.Errorf("requesting %s password: %w", host, err)
+		}
+
+		return authn.FromConfig(authn.AuthConfig{Username: username, Password: password}), nil
+	}
+
+	auth, err := r.keychain.Resolve(reg)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s credentials from docker config: %w", host, err)
+	}
+	if auth != authn.Anonymous {
+		slog.Debug("using docker config credentials", "registry", host)
+		return auth, nil
+	}
+
+	username, err := r.lookupSecret(usernameKey, r.usernameEnvs)
+	if err != nil {
+		return nil, err
+	}
+	password, err := r.lookupSecret(passwordKey, r.passwordEnvs)
+	if err != nil {
+		return nil, err
+	}
+	if username != "" && password != "" {
+		slog.Debug("using stored basic auth credentials", "registry", host)
+		return authn.FromConfig(authn.AuthConfig{Username: username, Password: password}), nil
+	}
+
+	slog.Debug("no credentials found, using anonymous access", "registry", host)
+	return authn.Anonymous, nil
+}
+
+// lookupSecret reads the secret from the storage or the environment without asking the user.
+func (r *OciRegistry) lookupSecret(storageKey string, envKeys []string) (string, error) {
+	secret, err := r.storage.Get(storageKey)
+	if err != nil {
+		return "", fmt.Errorf("retrieving %s from storage: %w", storageKey, err)
+	}
+	if secret != "" {
+		return secret, nil
+	}
+
+	for _, envKey := range envKeys {
+		if secret = strings.TrimSpace(os.Getenv(envKey)); secret != "" {
+			return secret, nil
+		}
+	}
+
+	return "", nil
+}
+
+func (r *OciRegistry) ResetAuthentication() error {
+	reg, err := r.parseRegistry()
+	if err != nil {
+		return err
+	}
+
+	usernameKey, passwordKey := r.storageKeys(reg.RegistryStr())
+	if err := r.storage.Remove(usernameKey); err != nil {
+		return fmt.Errorf("resetting %s: %w", usernameKey, err)
+	}
+	if err := r.storage.Remove(passwordKey); err != nil {
+		return fmt.Errorf("resetting %s: %w", passwordKey, err)
+	}
+	r.promptCredentials = true
+
+	return nil
+}
+
+func (r *OciRegistry) GetKeychain() authn.Keychain {
+	return nil
+}
+
+func (r *OciRegistry) IsInsecure() bool {
+	return r.config.Insecure
+}
+
+func (r *OciRegistry) GetImageRef() (string, error) {
+	// Required format: <registry host>/<repository>:<tag>
+	if _, err := name.NewTag(r.config.Image, r.nameOptions(name.StrictValidation)...); err != nil {
+		return "", fmt.Errorf("%w - invalid OCI image format: %s, expected format: <registry host>/<repository>:<tag>: %v", lib.BadUserInputError, r.config.Image, err)
+	}
+	return r.config.Image, nil
+}
+
+func (r *OciRegistry) parseRegistry() (name.Registry, error) {
+	ref, err := name.NewTag(r.config.Image, r.nameOptions()...)
+	if err != nil {
+		return name.Registry{}, fmt.Errorf("parsing image reference %s: %w", r.config.Image, err)
+	}
+	return ref.Context().Registry, nil
+}
+
+func (r *OciRegistry) nameOptions(opts ...name.Option) []name.Option {
+	if r.config.Insecure {
+		opts = append(opts, name.Insecure)
+	}
+	return opts
+}
+
+func (r *OciRegistry) storageKeys(host string) (string, string) {
+	return fmt.Sprintf("oci_username_%s", host), fmt.Sprintf("oci_password_%s", host)
+}
@@ -0,0 +1,146 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/testutil"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/stretchr/testify/require"
+)
+
+type staticKeychain map[string]authn.Authenticator
+
+func (k staticKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	if auth, ok := k[target.RegistryStr()]; ok {
+		return auth, nil
+	}
+	return authn.Anonymous, nil
+}
+
+func newTestOciRegistry(image string, storage lib.CredentialsStorage, keychain authn.Keychain) *OciRegistry {
+	r := NewOciRegistry(storage, OciRegistryConfig{Image: image}, nil, nil).(*OciRegistry)
+	r.keychain = keychain
+	return r
+}
+
+func TestOciRegistry_GetAuthentication(t *testing.T) {
+	t.Parallel()
+
+	t.Run("prefers docker config credentials", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		storage := testutil.NewMemoryCredentialsStorage(map[string]string{
+			"oci_username_registry.example.com": "stored-user",
+			"oci_password_registry.example.com": "stored-password",
+		})
+		keychain := staticKeychain{"registry.example.com": authn.FromConfig(authn.AuthConfig{Username: "docker-user", Password: "docker-password"})}
+		registry := newTestOciRegistry("registry.example.com/team/app:v1", storage, keychain)
+
+		auth, err := registry.GetAuthentication()
+		r.NoError(err)
+		authConfig, err := auth.Authorization()
+		r.NoError(err)
+		r.Equal("docker-user", authConfig.Username)
+	})
+
+	t.Run("falls back to stored basic auth", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		storage := testutil.NewMemoryCredentialsStorage(map[string]string{
+			"oci_username_localhost:5000": "stored-user",
+			"oci_password_localhost:5000": "stored-password",
+		})
+		registry := newTestOciRegistry("localhost:5000/app:v1", storage, staticKeychain{})
+
+		auth, err := registry.GetAuthentication()
+		r.NoError(err)
+		authConfig, err := auth.Authorization()
+		r.NoError(err)
+		r.Equal("stored-user", authConfig.Username)
+		r.Equal("stored-password", authConfig.Password)
+	})
+
+	t.Run("falls back to anonymous access", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		registry := newTestOciRegistry("localhost:5000/app:v1", testutil.NewMemoryCredentialsStorage(nil), staticKeychain{})
+
+		auth, err := registry.GetAuthentication()
+		r.NoError(err)
+		r.Equal(authn.Anonymous, auth)
+	})
+
+	t.Run("reset removes stored credentials", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		storage := testutil.NewMemoryCredentialsStorage(map[string]string{
+			"oci_username_localhost:5000": "stored-user",
+			"oci_password_localhost:5000": "stored-password",
+		})
+		registry := newTestOciRegistry("localhost:5000/app:v1", storage, staticKeychain{})
+
+		r.NoError(registry.ResetAuthentication())
+		r.Empty(storage.Values())
+		r.True(registry.promptCredentials)
+	})
+}
+
+func TestOciRegistry_GetImageRef(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		image string
+		valid bool
+	}{
+		{image: "localhost:5000/app:v1", valid: true},
+		{image: "quay.io/team/app:v1", valid: true},
+		{image: "harbor.example.com/project/team/app:v1", valid: true},
+		{image: "quay.io/team/app", valid: false},
+		{image: "app:v1", valid: false},
+	} {
+		t.Run(tc.image, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			imageRef, err := NewOciRegistry(testutil.NewMemoryCredentialsStorage(nil), OciRegistryConfig{Image: tc.image}, nil, nil).GetImageRef()
+			if !tc.valid {
+				r.ErrorIs(err, lib.BadUserInputError)
+				return
+			}
+			r.NoError(err)
+			r.Equal(tc.image, imageRef)
+		})
+	}
+}
+
+func TestDockerHub_GetImageRef(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		image    string
+		expected string
+	}{
+		{image: "owner/app:v1", expected: "docker.io/owner/app:v1"},
+		{image: "docker.io/owner/app:v1", expected: "docker.io/owner/app:v1"},
+		{image: "owner/app", expected: ""},
+		{image: "quay.io/owner/app:v1", expected: ""},
+	} {
+		t.Run(tc.image, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			imageRef, err := NewDockerHub(testutil.NewMemoryCredentialsStorage(nil), DockerHubConfig(tc.image), nil, nil).GetImageRef()
+			if tc.expected == "" {
+				r.ErrorIs(err, lib.BadUserInputError)
+				return
+			}
+			r.NoError(err)
+			r.Equal(tc.expected, imageRef)
+		})
+	}
+}
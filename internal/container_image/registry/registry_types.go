@@ -17,4 +17,6 @@ type Registry interface {
 	GetAuthentication() (authn.Authenticator, error)
 	GetImageRef() (string, error)
 	ResetAuthentication() error
+	// IsInsecure reports whether the registry is reached over plain HTTP or without TLS verification.
+	IsInsecure() bool
 }
@@ -0,0 +1,236 @@
+package sbom
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+type Format string
+
+const (
+	FormatSpdx      Format = "spdx"
+	FormatCycloneDx Format = "cyclonedx"
+)
+
+const (
+	SpdxMediaType      types.MediaType = "application/spdx+json"
+	CycloneDxMediaType types.MediaType = "application/vnd.cyclonedx+json"
+)
+
+// MediaType is used both as the artifact type of the SBOM referrer and as the media type of its layer.
+func (f Format) MediaType() (types.MediaType, error) {
+	switch f {
+	case FormatSpdx:
+		return SpdxMediaType, nil
+	case FormatCycloneDx:
+		return CycloneDxMediaType, nil
+	}
+	return "", fmt.Errorf("%w - unsupported SBOM format '%s', supported are %s, %s", lib.BadUserInputError, f, FormatSpdx, FormatCycloneDx)
+}
+
+type ComponentType string
+
+const (
+	ComponentTypeContainer   ComponentType = "container"
+	ComponentTypeApplication ComponentType = "application"
+	ComponentTypeLibrary     ComponentType = "library"
+	ComponentTypeOsPackage   ComponentType = "os-package"
+)
+
+// Component is a package found in one of the sources, identified by its package URL.
+type Component struct {
+	ID      string
+	Type    ComponentType
+	Name    string
+	Version string
+	Purl    string
+	License string
+	// DependsOn lists the IDs of the components this one depends on, for the image the components it contains
+	DependsOn []string
+}
+
+// Document is the format independent SBOM of an image.
+type Document struct {
+	Image      Component
+	Components []Component
+	Created    time.Time
+}
+
+// WorkspacePackage is a package of the pnpm workspace, Path is relative to the workspace root like the lockfile importers.
+type WorkspacePackage struct {
+	Name         string
+	Version      string
+	Path         string
+	Dependencies map[string]string
+}
+
+// Workspace is the app built into the image and the workspace packages it depends on.
+type Workspace struct {
+	App      WorkspacePackage
+	Packages []WorkspacePackage
+}
+
+type Input struct {
+	// Repository and Digest identify the image the SBOM describes
+	Repository string
+	Digest     v1.Hash
+	// Image is scanned for OS and node packages when set
+	Image v1.Image
+	// Workspace and Lockfile add the packages of the pnpm workspace when set
+	Workspace *Workspace
+	Lockfile  string
+}
+
+// Generate collects the components of the image from the workspace graph, the lockfile and the image filesystem.
+// Components found in several sources are merged by their package URL.
+func Generate(input Input) (Document, error) {
+	components := newComponentSet()
+	image := Component{
+		ID:      ociPurl(input.Repository, input.Digest),
+		Type:    ComponentTypeContainer,
+		Name:    input.Repository,
+		Version: input.Digest.String(),
+		Purl:    ociPurl(input.Repository, input.Digest),
+	}
+
+	if input.Workspace != nil {
+		appID := addWorkspace(components, *input.Workspace)
+		image.DependsOn = append(image.DependsOn, appID)
+
+		if input.Lockfile != "" {
+			lockfile, err := readPnpmLockfile(input.Lockfile)
+			if err != nil {
+				return Document{}, err
+			}
+			if err := addLockfilePackages(components, lockfile, *input.Workspace); err != nil {
+				return Document{}, err
+			}
+		}
+	}
+
+	if input.Image != nil {
+		found, err := scanImage(input.Image)
+		if err != nil {
+			return Document{}, err
+		}
+		for _, component := range found {
+			components.add(component)
+			if !slices.Contains(image.DependsOn, component.ID) {
+				image.DependsOn = append(image.DependsOn, component.ID)
+			}
+		}
+	}
+
+	return Document{Image: image, Components: components.list(), Created: time.Now().UTC()}, nil
+}
+
+// Encode writes the document in the format.
+func Encode(doc Document, format Format) ([]byte, error) {
+	switch format {
+	case FormatSpdx:
+		return encodeSpdx(doc)
+	case FormatCycloneDx:
+		return encodeCycloneDx(doc)
+	}
+	_, err := format.MediaType()
+	return nil, err
+}
+
+func addWorkspace(components *componentSet, workspace Workspace) string {
+	members := append([]WorkspacePackage{workspace.App}, workspace.Packages...)
+	ids := make(map[string]string, len(members))
+	for i, member := range members {
+		componentType := ComponentTypeLibrary
+		if i == 0 {
+			componentType = ComponentTypeApplication
+		}
+		component := components.add(npmComponent(member.Name, member.Version, componentType))
+		ids[member.Name] = component.ID
+	}
+
+	for _, member := range members {
+		for _, name := range slices.Sorted(maps.Keys(member.Dependencies)) {
+			if id, ok := ids[name]; ok {
+				components.dependsOn(ids[member.Name], id)
+			}
+		}
+	}
+
+	return ids[workspace.App.Name]
+}
+
+func npmComponent(name, version string, componentType ComponentType) Component {
+	purl := npmPurl(name, version)
+	return Component{ID: purl, Type: componentType, Name: name, Version: version, Purl: purl}
+}
+
+// npmPurl returns the package URL, the scope of scoped packages is the namespace.
+func npmPurl(name, version string) string {
+	namespace, pkg, scoped := strings.Cut(name, "/")
+	purl := "pkg:npm/" + purlEscape(name)
+	if scoped && strings.HasPrefix(namespace, "@") {
+		purl = "pkg:npm/" + purlEscape(namespace) + "/" + purlEscape(pkg)
+	}
+	if version != "" {
+		purl += "@" + purlEscape(version)
+	}
+	return purl
+}
+
+// purlEscape encodes a package URL segment, the @ separating the version is escaped too.
+func purlEscape(segment string) string {
+	return strings.ReplaceAll(url.PathEscape(segment), "@", "%40")
+}
+
+func ociPurl(repository string, digest v1.Hash) string {
+	name := repository[strings.LastIndex(repository, "/")+1:]
+	return fmt.Sprintf("pkg:oci/%s@%s?repository_url=%s", name, purlEscape(digest.String()), url.QueryEscape(repository))
+}
+
+// componentSet keeps the components in the order they were found.
+type componentSet struct {
+	ids  []string
+	byID map[string]*Component
+}
+
+func newComponentSet() *componentSet {
+	return &componentSet{byID: make(map[string]*Component)}
+}
+
+// add stores the component, or fills the details missing in the already stored one with the same ID.
+func (s *componentSet) add(component Component) *Component {
+	if existing, ok := s.byID[component.ID]; ok {
+		if existing.License == "" {
+			existing.License = component.License
+		}
+		return existing
+	}
+
+	s.ids = append(s.ids, component.ID)
+	s.byID[component.ID] = &component
+	return &component
+}
+
+func (s *componentSet) dependsOn(from, to string) {
+	component, ok := s.byID[from]
+	if !ok || from == to || slices.Contains(component.DependsOn, to) {
+		return
+	}
+	component.DependsOn = append(component.DependsOn, to)
+}
+
+func (s *componentSet) list() []Component {
+	components := make([]Component, 0, len(s.ids))
+	for _, id := range s.ids {
+		components = append(components, *s.byID[id])
+	}
+	return components
+}
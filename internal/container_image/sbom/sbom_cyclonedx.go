@@ -0,0 +1,97 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// cycloneDxDocument is the subset of the CycloneDX 1.5 JSON schema the SBOM uses.
+type cycloneDxDocument struct {
+	BomFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDxMetadata     `json:"metadata"`
+	Components   []cycloneDxComponent  `json:"components"`
+	Dependencies []cycloneDxDependency `json:"dependencies"`
+}
+
+type cycloneDxMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     cycloneDxTools     `json:"tools"`
+	Component cycloneDxComponent `json:"component"`
+}
+
+type cycloneDxTools struct {
+	Components []cycloneDxComponent `json:"components"`
+}
+
+type cycloneDxComponent struct {
+	BomRef   string             `json:"bom-ref,omitempty"`
+	Type     string             `json:"type"`
+	Name     string             `json:"name"`
+	Version  string             `json:"version,omitempty"`
+	Purl     string             `json:"purl,omitempty"`
+	Licenses []cycloneDxLicense `json:"licenses,omitempty"`
+}
+
+type cycloneDxLicense struct {
+	Expression string `json:"expression"`
+}
+
+type cycloneDxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+func encodeCycloneDx(doc Document) ([]byte, error) {
+	cycloneDx := cycloneDxDocument{
+		BomFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + uuid.NewString(),
+		Version:      1,
+		Metadata: cycloneDxMetadata{
+			Timestamp: doc.Created.UTC().Format(time.RFC3339),
+			Tools:     cycloneDxTools{Components: []cycloneDxComponent{{Type: "application", Name: "cloudctl"}}},
+			Component: cycloneDxComponentOf(doc.Image),
+		},
+		Components: make([]cycloneDxComponent, 0, len(doc.Components)),
+	}
+
+	cycloneDx.Dependencies = append(cycloneDx.Dependencies, cycloneDxDependency{Ref: doc.Image.ID, DependsOn: doc.Image.DependsOn})
+	for _, component := range doc.Components {
+		cycloneDx.Components = append(cycloneDx.Components, cycloneDxComponentOf(component))
+		cycloneDx.Dependencies = append(cycloneDx.Dependencies, cycloneDxDependency{Ref: component.ID, DependsOn: component.DependsOn})
+	}
+
+	content, err := json.MarshalIndent(cycloneDx, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding CycloneDX document: %w", err)
+	}
+	return content, nil
+}
+
+func cycloneDxComponentOf(component Component) cycloneDxComponent {
+	componentType := "library"
+	switch component.Type {
+	case ComponentTypeContainer:
+		componentType = "container"
+	case ComponentTypeApplication:
+		componentType = "application"
+	}
+
+	result := cycloneDxComponent{
+		BomRef:  component.ID,
+		Type:    componentType,
+		Name:    component.Name,
+		Version: component.Version,
+		Purl:    component.Purl,
+	}
+	if component.License != "" {
+		result.Licenses = []cycloneDxLicense{{Expression: component.License}}
+	}
+	return result
+}
@@ -0,0 +1,228 @@
+package sbom
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+)
+
+// maxManifestSize skips files too big to be package metadata instead of reading them into memory.
+const maxManifestSize = 16 << 20
+
+var nodeModulesManifestPattern = regexp.MustCompile(`(^|/)node_modules/(@[^/]+/)?[^/]+/package\.json$`)
+
+// osPackage is an entry of the apk or dpkg database, the package URL needs the distribution from os-release.
+type osPackage struct {
+	manager      string
+	name         string
+	version      string
+	architecture string
+	license      string
+}
+
+// scanImage reads the flattened image filesystem and returns the installed OS packages and node modules.
+func scanImage(image v1.Image) ([]Component, error) {
+	reader := mutate.Extract(image)
+	defer reader.Close()
+
+	distribution := ""
+	var osPackages []osPackage
+	var components []Component
+
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading image filesystem: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || header.Size > maxManifestSize {
+			continue
+		}
+
+		filePath := strings.TrimPrefix(strings.TrimPrefix(header.Name, "./"), "/")
+		switch {
+		case filePath == "etc/os-release" || (filePath == "usr/lib/os-release" && distribution == ""):
+			content, err := io.ReadAll(tarReader)
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %w", filePath, err)
+			}
+			distribution = parseOsReleaseID(content)
+		case filePath == "lib/apk/db/installed":
+			content, err := io.ReadAll(tarReader)
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %w", filePath, err)
+			}
+			osPackages = append(osPackages, parseApkDatabase(content)...)
+		case filePath == "var/lib/dpkg/status":
+			content, err := io.ReadAll(tarReader)
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %w", filePath, err)
+			}
+			osPackages = append(osPackages, parseDpkgStatus(content)...)
+		case nodeModulesManifestPattern.MatchString(filePath):
+			content, err := io.ReadAll(tarReader)
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %w", filePath, err)
+			}
+			component, ok := parseNodeModuleManifest(content)
+			if ok {
+				components = append(components, component)
+			}
+		}
+	}
+
+	if distribution == "" {
+		distribution = "unknown"
+	}
+	osComponents := make([]Component, 0, len(osPackages))
+	for _, pkg := range osPackages {
+		purl := fmt.Sprintf("pkg:%s/%s/%s@%s", pkg.manager, purlEscape(distribution), purlEscape(pkg.name), purlEscape(pkg.version))
+		if pkg.architecture != "" {
+			purl += "?arch=" + url.QueryEscape(pkg.architecture)
+		}
+		osComponents = append(osComponents, Component{
+			ID:      purl,
+			Type:    ComponentTypeOsPackage,
+			Name:    pkg.name,
+			Version: pkg.version,
+			Purl:    purl,
+			License: pkg.license,
+		})
+	}
+
+	return append(osComponents, components...), nil
+}
+
+func parseOsReleaseID(content []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "ID="); ok {
+			return strings.Trim(value, `"'`)
+		}
+	}
+	return ""
+}
+
+// parseApkDatabase reads the alpine package database, a blank line separated list of single letter fields.
+func parseApkDatabase(content []byte) []osPackage {
+	var packages []osPackage
+	var current osPackage
+
+	flush := func() {
+		if current.name != "" {
+			current.manager = "apk"
+			packages = append(packages, current)
+		}
+		current = osPackage{}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxManifestSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch field {
+		case "P":
+			current.name = value
+		case "V":
+			current.version = value
+		case "A":
+			current.architecture = value
+		case "L":
+			current.license = value
+		}
+	}
+	flush()
+
+	return packages
+}
+
+// parseDpkgStatus reads the debian package database, keeping the packages that are installed.
+func parseDpkgStatus(content []byte) []osPackage {
+	var packages []osPackage
+	var current osPackage
+	installed := false
+
+	flush := func() {
+		if current.name != "" && installed {
+			current.manager = "deb"
+			packages = append(packages, current)
+		}
+		current = osPackage{}
+		installed = false
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxManifestSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		field, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		switch field {
+		case "Package":
+			current.name = value
+		case "Version":
+			current.version = value
+		case "Architecture":
+			current.architecture = value
+		case "Status":
+			installed = strings.HasSuffix(value, " installed")
+		}
+	}
+	flush()
+
+	return packages
+}
+
+func parseNodeModuleManifest(content []byte) (Component, bool) {
+	var manifest struct {
+		Name    string          `json:"name"`
+		Version string          `json:"version"`
+		License json.RawMessage `json:"license"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil || manifest.Name == "" || manifest.Version == "" {
+		return Component{}, false
+	}
+
+	component := npmComponent(manifest.Name, manifest.Version, ComponentTypeLibrary)
+
+	// The license is an SPDX expression, or an object with a type in older packages
+	var license string
+	if err := json.Unmarshal(manifest.License, &license); err != nil {
+		var licenseObject struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(manifest.License, &licenseObject) == nil {
+			license = licenseObject.Type
+		}
+	}
+	component.License = license
+
+	return component, true
+}
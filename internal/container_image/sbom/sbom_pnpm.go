@@ -0,0 +1,181 @@
+package sbom
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// pnpmLockfile is the part of pnpm-lock.yaml describing the resolved dependency graph, lockfile versions 6 and 9 are supported.
+type pnpmLockfile struct {
+	LockfileVersion string                        `yaml:"lockfileVersion"`
+	Importers       map[string]pnpmImporter       `yaml:"importers"`
+	Packages        map[string]pnpmPackageEntry   `yaml:"packages"`
+	Snapshots       map[string]pnpmPackageEntry   `yaml:"snapshots"`
+	Dependencies    map[string]pnpmDependencySpec `yaml:"dependencies"`
+	// OptionalDependencies of a lockfile without importers, i.e. a repository with a single package
+	OptionalDependencies map[string]pnpmDependencySpec `yaml:"optionalDependencies"`
+}
+
+type pnpmImporter struct {
+	Dependencies         map[string]pnpmDependencySpec `yaml:"dependencies"`
+	OptionalDependencies map[string]pnpmDependencySpec `yaml:"optionalDependencies"`
+}
+
+type pnpmPackageEntry struct {
+	Dependencies         map[string]string `yaml:"dependencies"`
+	OptionalDependencies map[string]string `yaml:"optionalDependencies"`
+}
+
+// pnpmDependencySpec is the resolved version of an importer dependency, a plain version in older lockfiles.
+type pnpmDependencySpec struct {
+	Version string
+}
+
+func (s *pnpmDependencySpec) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		s.Version = node.Value
+		return nil
+	}
+
+	var spec struct {
+		Version string `yaml:"version"`
+	}
+	if err := node.Decode(&spec); err != nil {
+		return err
+	}
+	s.Version = spec.Version
+	return nil
+}
+
+func readPnpmLockfile(lockfilePath string) (pnpmLockfile, error) {
+	var lockfile pnpmLockfile
+
+	content, err := os.ReadFile(lockfilePath)
+	if err != nil {
+		return lockfile, fmt.Errorf("reading pnpm lockfile %s: %w", lockfilePath, err)
+	}
+	if err := yaml.Unmarshal(content, &lockfile); err != nil {
+		return lockfile, fmt.Errorf("decoding pnpm lockfile %s: %w", lockfilePath, err)
+	}
+
+	major, _, _ := strings.Cut(lockfile.LockfileVersion, ".")
+	if version, err := strconv.Atoi(major); err != nil || version < 6 {
+		return lockfile, fmt.Errorf("unsupported pnpm lockfile version '%s' in %s, version 6 or newer is required", lockfile.LockfileVersion, lockfilePath)
+	}
+
+	if len(lockfile.Importers) == 0 {
+		lockfile.Importers = map[string]pnpmImporter{
+			".": {Dependencies: lockfile.Dependencies, OptionalDependencies: lockfile.OptionalDependencies},
+		}
+	}
+
+	return lockfile, nil
+}
+
+// legacyKeys reports whether package keys start with a slash, as they do up to lockfile version 6.
+func (l pnpmLockfile) legacyKeys() bool {
+	return strings.HasPrefix(l.LockfileVersion, "6")
+}
+
+// packageKey returns the key of the resolved dependency in the packages section, empty for workspace links.
+func (l pnpmLockfile) packageKey(name, version string) string {
+	if strings.HasPrefix(version, "link:") || strings.HasPrefix(version, "file:") {
+		return ""
+	}
+
+	// Aliased dependencies resolve to the full key of another package
+	base, _, _ := strings.Cut(version, "(")
+	aliased := strings.Contains(strings.TrimPrefix(base, "/"), "@")
+
+	if l.legacyKeys() {
+		if aliased || strings.HasPrefix(version, "/") {
+			return "/" + strings.TrimPrefix(version, "/")
+		}
+		return "/" + name + "@" + version
+	}
+	if aliased {
+		return version
+	}
+	return name + "@" + version
+}
+
+// dependencies returns the dependencies of the package, found in the snapshots section since lockfile version 9.
+func (l pnpmLockfile) dependencies(key string) map[string]string {
+	entry, ok := l.Snapshots[key]
+	if !ok {
+		entry = l.Packages[key]
+	}
+
+	dependencies := make(map[string]string, len(entry.Dependencies)+len(entry.OptionalDependencies))
+	maps.Copy(dependencies, entry.Dependencies)
+	maps.Copy(dependencies, entry.OptionalDependencies)
+	return dependencies
+}
+
+// parsePackageKey splits a key like /@scope/name@1.0.0(peer@2.0.0) into the package name and version.
+func parsePackageKey(key string) (string, string) {
+	key = strings.TrimPrefix(key, "/")
+	key, _, _ = strings.Cut(key, "(")
+
+	at := strings.LastIndex(key, "@")
+	if at <= 0 {
+		return key, ""
+	}
+	return key[:at], key[at+1:]
+}
+
+// addLockfilePackages adds the production dependencies of the workspace packages and everything they pull in.
+func addLockfilePackages(components *componentSet, lockfile pnpmLockfile, workspace Workspace) error {
+	members := append([]WorkspacePackage{workspace.App}, workspace.Packages...)
+
+	visited := make(map[string]string)
+	var visit func(key string) string
+	visit = func(key string) string {
+		if id, ok := visited[key]; ok {
+			return id
+		}
+
+		name, version := parsePackageKey(key)
+		component := components.add(npmComponent(name, version, ComponentTypeLibrary))
+		visited[key] = component.ID
+
+		dependencies := lockfile.dependencies(key)
+		// Sorted, so the same lockfile always produces the same SBOM
+		for _, depName := range slices.Sorted(maps.Keys(dependencies)) {
+			depKey := lockfile.packageKey(depName, dependencies[depName])
+			if depKey == "" {
+				continue
+			}
+			components.dependsOn(component.ID, visit(depKey))
+		}
+		return component.ID
+	}
+
+	for _, member := range members {
+		importerPath := path.Clean(member.Path)
+		importer, ok := lockfile.Importers[importerPath]
+		if !ok {
+			return fmt.Errorf("workspace package %s not found in the pnpm lockfile importers", importerPath)
+		}
+
+		memberID := npmComponent(member.Name, member.Version, ComponentTypeLibrary).ID
+		for _, dependencies := range []map[string]pnpmDependencySpec{importer.Dependencies, importer.OptionalDependencies} {
+			for _, name := range slices.Sorted(maps.Keys(dependencies)) {
+				key := lockfile.packageKey(name, dependencies[name].Version)
+				if key == "" {
+					continue
+				}
+				components.dependsOn(memberID, visit(key))
+			}
+		}
+	}
+
+	return nil
+}
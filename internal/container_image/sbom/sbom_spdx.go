@@ -0,0 +1,134 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const spdxNoAssertion = "NOASSERTION"
+
+// spdxDocument is the subset of the SPDX 2.3 JSON schema the SBOM uses.
+type spdxDocument struct {
+	SpdxVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID                string            `json:"SPDXID"`
+	Name                  string            `json:"name"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	LicenseDeclared       string            `json:"licenseDeclared"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose,omitempty"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SpdxElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSpdxElement string `json:"relatedSpdxElement"`
+}
+
+func encodeSpdx(doc Document) ([]byte, error) {
+	spdx := spdxDocument{
+		SpdxVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              doc.Image.Name + "@" + doc.Image.Version,
+		DocumentNamespace: fmt.Sprintf("https://spdx.org/spdxdocs/cloudctl/%s", uuid.NewString()),
+		CreationInfo: spdxCreationInfo{
+			Created:  doc.Created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: cloudctl"},
+		},
+	}
+
+	spdxIDs := make(map[string]string, len(doc.Components)+1)
+	for i, component := range append([]Component{doc.Image}, doc.Components...) {
+		spdxIDs[component.ID] = fmt.Sprintf("SPDXRef-Package-%d", i)
+		spdx.Packages = append(spdx.Packages, spdxPackageOf(component, spdxIDs[component.ID]))
+	}
+
+	imageID := spdxIDs[doc.Image.ID]
+	spdx.Relationships = append(spdx.Relationships, spdxRelationship{
+		SpdxElementID:      spdx.SPDXID,
+		RelationshipType:   "DESCRIBES",
+		RelatedSpdxElement: imageID,
+	})
+	for _, id := range doc.Image.DependsOn {
+		spdx.Relationships = append(spdx.Relationships, spdxRelationship{
+			SpdxElementID:      imageID,
+			RelationshipType:   "CONTAINS",
+			RelatedSpdxElement: spdxIDs[id],
+		})
+	}
+	for _, component := range doc.Components {
+		for _, id := range component.DependsOn {
+			spdx.Relationships = append(spdx.Relationships, spdxRelationship{
+				SpdxElementID:      spdxIDs[component.ID],
+				RelationshipType:   "DEPENDS_ON",
+				RelatedSpdxElement: spdxIDs[id],
+			})
+		}
+	}
+
+	content, err := json.MarshalIndent(spdx, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding SPDX document: %w", err)
+	}
+	return content, nil
+}
+
+func spdxPackageOf(component Component, spdxID string) spdxPackage {
+	license := component.License
+	if license == "" {
+		license = spdxNoAssertion
+	}
+
+	pkg := spdxPackage{
+		SPDXID:           spdxID,
+		Name:             component.Name,
+		VersionInfo:      component.Version,
+		DownloadLocation: spdxNoAssertion,
+		LicenseDeclared:  license,
+	}
+
+	switch component.Type {
+	case ComponentTypeContainer:
+		pkg.PrimaryPackagePurpose = "CONTAINER"
+	case ComponentTypeApplication:
+		pkg.PrimaryPackagePurpose = "APPLICATION"
+	case ComponentTypeLibrary, ComponentTypeOsPackage:
+		pkg.PrimaryPackagePurpose = "LIBRARY"
+	}
+
+	if component.Purl != "" {
+		pkg.ExternalRefs = []spdxExternalRef{{
+			ReferenceCategory: "PACKAGE-MANAGER",
+			ReferenceType:     "purl",
+			ReferenceLocator:  component.Purl,
+		}}
+	}
+
+	return pkg
+}
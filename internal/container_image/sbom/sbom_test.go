@@ -0,0 +1,275 @@
+package sbom
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/require"
+)
+
+const pnpmLockfileV9 = `lockfileVersion: '9.0'
+
+importers:
+  .: {}
+  apps/api:
+    dependencies:
+      '@org/shared':
+        specifier: workspace:*
+        version: link:../../packages/shared
+      express:
+        specifier: ^4.19.2
+        version: 4.19.2
+    devDependencies:
+      typescript:
+        specifier: ^5.4.0
+        version: 5.4.5
+  packages/shared:
+    dependencies:
+      zod:
+        specifier: ^3.23.0
+        version: 3.23.8
+
+packages:
+  express@4.19.2:
+    resolution: {integrity: sha512-a}
+  debug@2.6.9:
+    resolution: {integrity: sha512-b}
+  ms@2.0.0:
+    resolution: {integrity: sha512-c}
+  zod@3.23.8:
+    resolution: {integrity: sha512-d}
+  typescript@5.4.5:
+    resolution: {integrity: sha512-e}
+
+snapshots:
+  express@4.19.2:
+    dependencies:
+      debug: 2.6.9
+  debug@2.6.9:
+    dependencies:
+      ms: 2.0.0
+  ms@2.0.0: {}
+  zod@3.23.8: {}
+  typescript@5.4.5: {}
+`
+
+const pnpmLockfileV6 = `lockfileVersion: '6.0'
+
+dependencies:
+  express:
+    specifier: ^4.19.2
+    version: 4.19.2
+
+packages:
+  /express@4.19.2:
+    resolution: {integrity: sha512-a}
+    dependencies:
+      debug: 2.6.9
+  /debug@2.6.9:
+    resolution: {integrity: sha512-b}
+    dependencies:
+      ms: 2.0.0
+  /ms@2.0.0:
+    resolution: {integrity: sha512-c}
+`
+
+func writeLockfile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "pnpm-lock.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func componentsByID(doc Document) map[string]Component {
+	components := make(map[string]Component, len(doc.Components))
+	for _, component := range doc.Components {
+		components[component.ID] = component
+	}
+	return components
+}
+
+func TestGenerate_pnpmLockfile(t *testing.T) {
+	t.Parallel()
+
+	digest := v1.Hash{Algorithm: "sha256", Hex: "0000000000000000000000000000000000000000000000000000000000000001"}
+
+	t.Run("workspace with lockfile v9", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		doc, err := Generate(Input{
+			Repository: "ghcr.io/org/api",
+			Digest:     digest,
+			Workspace: &Workspace{
+				App: WorkspacePackage{Name: "@org/api", Version: "1.2.0", Path: "apps/api", Dependencies: map[string]string{"@org/shared": "workspace:*", "express": "^4.19.2"}},
+				Packages: []WorkspacePackage{
+					{Name: "@org/shared", Version: "0.1.0", Path: "packages/shared", Dependencies: map[string]string{"zod": "^3.23.0"}},
+				},
+			},
+			Lockfile: writeLockfile(t, pnpmLockfileV9),
+		})
+		r.NoError(err)
+
+		components := componentsByID(doc)
+		r.Len(components, 6)
+		r.Equal([]string{"pkg:npm/%40org/api@1.2.0"}, doc.Image.DependsOn)
+		r.Equal(ComponentTypeApplication, components["pkg:npm/%40org/api@1.2.0"].Type)
+		r.Equal([]string{"pkg:npm/%40org/shared@0.1.0", "pkg:npm/express@4.19.2"}, components["pkg:npm/%40org/api@1.2.0"].DependsOn)
+		r.Equal([]string{"pkg:npm/zod@3.23.8"}, components["pkg:npm/%40org/shared@0.1.0"].DependsOn)
+		r.Equal([]string{"pkg:npm/debug@2.6.9"}, components["pkg:npm/express@4.19.2"].DependsOn)
+		r.Equal([]string{"pkg:npm/ms@2.0.0"}, components["pkg:npm/debug@2.6.9"].DependsOn)
+		r.NotContains(components, "pkg:npm/typescript@5.4.5")
+	})
+
+	t.Run("single package with lockfile v6", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		doc, err := Generate(Input{
+			Repository: "ghcr.io/org/api",
+			Digest:     digest,
+			Workspace:  &Workspace{App: WorkspacePackage{Name: "api", Version: "1.0.0", Path: "."}},
+			Lockfile:   writeLockfile(t, pnpmLockfileV6),
+		})
+		r.NoError(err)
+
+		components := componentsByID(doc)
+		r.Len(components, 4)
+		r.Equal([]string{"pkg:npm/express@4.19.2"}, components["pkg:npm/api@1.0.0"].DependsOn)
+		r.Equal([]string{"pkg:npm/ms@2.0.0"}, components["pkg:npm/debug@2.6.9"].DependsOn)
+	})
+
+	t.Run("unsupported lockfile version", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		_, err := Generate(Input{
+			Repository: "ghcr.io/org/api",
+			Digest:     digest,
+			Workspace:  &Workspace{App: WorkspacePackage{Name: "api", Path: "."}},
+			Lockfile:   writeLockfile(t, "lockfileVersion: 5.4\n"),
+		})
+		r.ErrorContains(err, "unsupported pnpm lockfile version")
+	})
+}
+
+func testImage(t *testing.T, files map[string]string) v1.Image {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range []string{"etc/os-release", "lib/apk/db/installed", "var/lib/dpkg/status", "app/node_modules/.pnpm/express@4.19.2/node_modules/express/package.json", "usr/local/lib/node_modules/@scope/tool/package.json", "app/node_modules/broken/package.json"} {
+		content, ok := files[name]
+		if !ok {
+			continue
+		}
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	image, err := mutate.AppendLayers(empty.Image, static.NewLayer(buf.Bytes(), types.OCIUncompressedLayer))
+	require.NoError(t, err)
+	return image
+}
+
+func TestScanImage(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	image := testImage(t, map[string]string{
+		"lib/apk/db/installed": "C:Q1abc\nP:musl\nV:1.2.5-r0\nA:aarch64\nL:MIT\n\nP:busybox\nV:1.36.1-r29\nA:aarch64\nL:GPL-2.0-only\n",
+		"etc/os-release":       "NAME=\"Alpine Linux\"\nID=alpine\nVERSION_ID=3.20.3\n",
+		"var/lib/dpkg/status":  "Package: tzdata\nStatus: install ok installed\nVersion: 2024a-0\nArchitecture: all\n\nPackage: removed\nStatus: deinstall ok config-files\nVersion: 1.0\n",
+		"app/node_modules/.pnpm/express@4.19.2/node_modules/express/package.json": `{"name":"express","version":"4.19.2","license":"MIT"}`,
+		"usr/local/lib/node_modules/@scope/tool/package.json":                     `{"name":"@scope/tool","version":"2.0.0","license":{"type":"Apache-2.0"}}`,
+		"app/node_modules/broken/package.json":                                    `{"name":"broken"}`,
+	})
+
+	components, err := scanImage(image)
+	r.NoError(err)
+
+	var purls, licenses []string
+	for _, component := range components {
+		purls = append(purls, component.Purl)
+		licenses = append(licenses, component.License)
+	}
+	r.Equal([]string{
+		"pkg:apk/alpine/musl@1.2.5-r0?arch=aarch64",
+		"pkg:apk/alpine/busybox@1.36.1-r29?arch=aarch64",
+		"pkg:deb/alpine/tzdata@2024a-0?arch=all",
+		"pkg:npm/express@4.19.2",
+		"pkg:npm/%40scope/tool@2.0.0",
+	}, purls)
+	r.Equal([]string{"MIT", "GPL-2.0-only", "", "MIT", "Apache-2.0"}, licenses)
+}
+
+func TestEncode(t *testing.T) {
+	t.Parallel()
+
+	doc := Document{
+		Image: Component{ID: "pkg:oci/api@sha256%3A01", Type: ComponentTypeContainer, Name: "ghcr.io/org/api", Version: "sha256:01", Purl: "pkg:oci/api@sha256%3A01", DependsOn: []string{"pkg:npm/api@1.0.0"}},
+		Components: []Component{
+			{ID: "pkg:npm/api@1.0.0", Type: ComponentTypeApplication, Name: "api", Version: "1.0.0", Purl: "pkg:npm/api@1.0.0", DependsOn: []string{"pkg:npm/ms@2.0.0"}},
+			{ID: "pkg:npm/ms@2.0.0", Type: ComponentTypeLibrary, Name: "ms", Version: "2.0.0", Purl: "pkg:npm/ms@2.0.0", License: "MIT"},
+		},
+		Created: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	t.Run("spdx", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		content, err := Encode(doc, FormatSpdx)
+		r.NoError(err)
+
+		var spdx spdxDocument
+		r.NoError(json.Unmarshal(content, &spdx))
+		r.Equal("SPDX-2.3", spdx.SpdxVersion)
+		r.Equal("2025-01-02T03:04:05Z", spdx.CreationInfo.Created)
+		r.Len(spdx.Packages, 3)
+		r.Equal("NOASSERTION", spdx.Packages[1].LicenseDeclared)
+		r.Equal("MIT", spdx.Packages[2].LicenseDeclared)
+		r.Equal("pkg:npm/ms@2.0.0", spdx.Packages[2].ExternalRefs[0].ReferenceLocator)
+		r.Equal([]spdxRelationship{
+			{SpdxElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSpdxElement: "SPDXRef-Package-0"},
+			{SpdxElementID: "SPDXRef-Package-0", RelationshipType: "CONTAINS", RelatedSpdxElement: "SPDXRef-Package-1"},
+			{SpdxElementID: "SPDXRef-Package-1", RelationshipType: "DEPENDS_ON", RelatedSpdxElement: "SPDXRef-Package-2"},
+		}, spdx.Relationships)
+	})
+
+	t.Run("cyclonedx", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		content, err := Encode(doc, FormatCycloneDx)
+		r.NoError(err)
+
+		var cycloneDx cycloneDxDocument
+		r.NoError(json.Unmarshal(content, &cycloneDx))
+		r.Equal("1.5", cycloneDx.SpecVersion)
+		r.Regexp(`^urn:uuid:[0-9a-f-]{36}$`, cycloneDx.SerialNumber)
+		r.Equal("container", cycloneDx.Metadata.Component.Type)
+		r.Len(cycloneDx.Components, 2)
+		r.Equal([]cycloneDxLicense{{Expression: "MIT"}}, cycloneDx.Components[1].Licenses)
+		r.Equal([]cycloneDxDependency{
+			{Ref: "pkg:oci/api@sha256%3A01", DependsOn: []string{"pkg:npm/api@1.0.0"}},
+			{Ref: "pkg:npm/api@1.0.0", DependsOn: []string{"pkg:npm/ms@2.0.0"}},
+			{Ref: "pkg:npm/ms@2.0.0"},
+		}, cycloneDx.Dependencies)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		t.Parallel()
+		_, err := Encode(doc, "swid")
+		require.ErrorContains(t, err, "unsupported SBOM format")
+	})
+}
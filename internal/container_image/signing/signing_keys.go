@@ -0,0 +1,208 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+type KeyType string
+
+const (
+	KeyTypeEcdsa   KeyType = "ecdsa"
+	KeyTypeEd25519 KeyType = "ed25519"
+)
+
+const (
+	privateKeyPemType          = "PRIVATE KEY"
+	ecPrivateKeyPemType        = "EC PRIVATE KEY"
+	publicKeyPemType           = "PUBLIC KEY"
+	sigstorePrivateKeyPemType  = "ENCRYPTED SIGSTORE PRIVATE KEY"
+	legacyCosignPrivateKeyType = "ENCRYPTED COSIGN PRIVATE KEY"
+)
+
+// KeyConfig points to the PEM encoded private key, either a file or an entry of the signing keys keyring.
+type KeyConfig struct {
+	File    string `mapstructure:"file"`
+	Keyring string `mapstructure:"keyring"`
+}
+
+// LoadPrivateKey reads the private key from the file or the keyring. Keys encrypted by 'cosign generate-key-pair'
+// are decrypted with the password from the environment.
+func LoadPrivateKey(cfg KeyConfig, storage lib.CredentialsStorage) (crypto.Signer, error) {
+	var data []byte
+	switch {
+	case cfg.File != "" && cfg.Keyring != "":
+		return nil, fmt.Errorf("%w - only one of the signing key file and keyring can be set", lib.BadUserInputError)
+	case cfg.File != "":
+		content, err := os.ReadFile(cfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("reading signing key %s: %w", cfg.File, err)
+		}
+		data = content
+	case cfg.Keyring != "":
+		if storage == nil {
+			return nil, fmt.Errorf("no keyring available for the signing key %s", cfg.Keyring)
+		}
+		content, err := storage.Get(cfg.Keyring)
+		if err != nil {
+			return nil, fmt.Errorf("reading signing key %s from the keyring: %w", cfg.Keyring, err)
+		}
+		if content == "" {
+			return nil, fmt.Errorf("%w - signing key %s not found in the keyring, add it with 'cloudctl image key'", lib.BadUserInputError, cfg.Keyring)
+		}
+		data = []byte(content)
+	default:
+		return nil, fmt.Errorf("%w - the signing key needs a file or a keyring entry", lib.BadUserInputError)
+	}
+
+	password := os.Getenv(lib.SigningKeyPasswordEnv)
+	if password == "" {
+		password = os.Getenv(lib.CosignNativeKeyPasswordEnv)
+	}
+	return ParsePrivateKey(data, []byte(password))
+}
+
+// ParsePrivateKey decodes a PKCS#8 or SEC 1 PEM private key, or a cosign encrypted one using the password.
+func ParsePrivateKey(data []byte, password []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key is not PEM encoded")
+	}
+
+	der := block.Bytes
+	switch block.Type {
+	case ecPrivateKeyPemType:
+		key, err := x509.ParseECPrivateKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("parsing EC private key: %w", err)
+		}
+		return key, nil
+	case sigstorePrivateKeyPemType, legacyCosignPrivateKeyType:
+		decrypted, err := decryptCosignKey(der, password)
+		if err != nil {
+			return nil, err
+		}
+		der = decrypted
+	case privateKeyPemType:
+	default:
+		return nil, fmt.Errorf("unsupported signing key PEM type %q", block.Type)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("parsing PKCS#8 private key: %w", err)
+	}
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w - unsupported signing key type %T, ECDSA and ed25519 keys are supported", lib.BadUserInputError, key)
+}
+
+// encryptedKey is the scrypt and secretbox envelope cosign stores its private keys in.
+type encryptedKey struct {
+	KDF struct {
+		Name   string `json:"name"`
+		Params struct {
+			N int `json:"N"`
+			R int `json:"r"`
+			P int `json:"p"`
+		} `json:"params"`
+		Salt []byte `json:"salt"`
+	} `json:"kdf"`
+	Cipher struct {
+		Name  string `json:"name"`
+		Nonce []byte `json:"nonce"`
+	} `json:"cipher"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func decryptCosignKey(data []byte, password []byte) ([]byte, error) {
+	var envelope encryptedKey
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("decoding encrypted signing key: %w", err)
+	}
+	if envelope.KDF.Name != "scrypt" || envelope.Cipher.Name != "nacl/secretbox" {
+		return nil, fmt.Errorf("unsupported signing key encryption %s with %s", envelope.Cipher.Name, envelope.KDF.Name)
+	}
+	if len(envelope.Cipher.Nonce) != 24 {
+		return nil, fmt.Errorf("invalid signing key nonce length %d", len(envelope.Cipher.Nonce))
+	}
+
+	derived, err := scrypt.Key(password, envelope.KDF.Salt, envelope.KDF.Params.N, envelope.KDF.Params.R, envelope.KDF.Params.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("deriving signing key password: %w", err)
+	}
+
+	var key [32]byte
+	var nonce [24]byte
+	copy(key[:], derived)
+	copy(nonce[:], envelope.Cipher.Nonce)
+	decrypted, ok := secretbox.Open(nil, envelope.Ciphertext, &nonce, &key)
+	if !ok {
+		return nil, fmt.Errorf("%w - decrypting signing key failed, check %s", lib.BadUserInputError, lib.SigningKeyPasswordEnv)
+	}
+	return decrypted, nil
+}
+
+// ParsePublicKey decodes a PKIX PEM public key as written by 'cosign generate-key-pair'.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != publicKeyPemType {
+		return nil, fmt.Errorf("public key is not a PEM encoded %s", publicKeyPemType)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key: %w", err)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w - unsupported public key type %T, ECDSA and ed25519 keys are supported", lib.BadUserInputError, key)
+}
+
+// GenerateKey creates a new key, ECDSA keys use the P-256 curve like cosign does.
+func GenerateKey(keyType KeyType) (crypto.Signer, error) {
+	switch keyType {
+	case KeyTypeEcdsa:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyTypeEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	}
+	return nil, fmt.Errorf("%w - unsupported key type '%s', supported are %s, %s", lib.BadUserInputError, keyType, KeyTypeEcdsa, KeyTypeEd25519)
+}
+
+// MarshalPrivateKey encodes the key as an unencrypted PKCS#8 PEM block.
+func MarshalPrivateKey(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("encoding private key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: privateKeyPemType, Bytes: der}), nil
+}
+
+// MarshalPublicKey encodes the key as a PKIX PEM block, the format cosign reads public keys in.
+func MarshalPublicKey(key crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("encoding public key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: publicKeyPemType, Bytes: der}), nil
+}
@@ -0,0 +1,103 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+var (
+	InvalidSignatureError = errors.New("invalid signature")
+)
+
+const (
+	// SimpleSigningMediaType is the media type of the layer holding the signed payload
+	SimpleSigningMediaType types.MediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	// SignatureArtifactType marks signatures attached as OCI referrers
+	SignatureArtifactType = "application/vnd.dev.cosign.artifact.sig.v1+json"
+	// SignatureAnnotation holds the base64 encoded signature of the payload layer
+	SignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+	payloadType = "cosign container image signature"
+)
+
+// Payload is the cosign simple signing document naming the signed manifest.
+type Payload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional map[string]any `json:"optional"`
+}
+
+// NewPayload returns the encoded payload for the manifest digest in the repository.
+func NewPayload(repository string, digest v1.Hash) ([]byte, error) {
+	var payload Payload
+	payload.Critical.Identity.DockerReference = repository
+	payload.Critical.Image.DockerManifestDigest = digest.String()
+	payload.Critical.Type = payloadType
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encoding signature payload: %w", err)
+	}
+	return data, nil
+}
+
+// ParsePayload decodes the payload and makes sure it signs the manifest digest.
+func ParsePayload(data []byte, digest v1.Hash) (Payload, error) {
+	var payload Payload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return payload, fmt.Errorf("decoding signature payload: %w", err)
+	}
+	if payload.Critical.Type != payloadType {
+		return payload, fmt.Errorf("%w - unexpected payload type %q", InvalidSignatureError, payload.Critical.Type)
+	}
+	if payload.Critical.Image.DockerManifestDigest != digest.String() {
+		return payload, fmt.Errorf("%w - payload signs %s instead of %s", InvalidSignatureError, payload.Critical.Image.DockerManifestDigest, digest)
+	}
+	return payload, nil
+}
+
+// Sign signs the payload the way cosign does: ECDSA keys sign the SHA-256 digest of the payload, ed25519 keys the payload itself.
+func Sign(key crypto.Signer, payload []byte) ([]byte, error) {
+	switch key.(type) {
+	case *ecdsa.PrivateKey:
+		sum := sha256.Sum256(payload)
+		return key.Sign(rand.Reader, sum[:], crypto.SHA256)
+	case ed25519.PrivateKey:
+		return key.Sign(rand.Reader, payload, crypto.Hash(0))
+	}
+	return nil, fmt.Errorf("unsupported signing key type %T", key)
+}
+
+// Verify checks the signature of the payload against the public key.
+func Verify(key crypto.PublicKey, payload, signature []byte) error {
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		sum := sha256.Sum256(payload)
+		if !ecdsa.VerifyASN1(key, sum[:], signature) {
+			return InvalidSignatureError
+		}
+		return nil
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, payload, signature) {
+			return InvalidSignatureError
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported public key type %T", key)
+}
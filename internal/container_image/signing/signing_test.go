@@ -0,0 +1,124 @@
+package signing
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/testutil"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+var testDigest = v1.Hash{Algorithm: "sha256", Hex: "3b4c2b0a4e8e42a7d0e3f6a1c1f2d9d3b4c2b0a4e8e42a7d0e3f6a1c1f2d9d3b"}
+
+func TestSignVerify(t *testing.T) {
+	t.Parallel()
+
+	for _, keyType := range []KeyType{KeyTypeEcdsa, KeyTypeEd25519} {
+		t.Run(string(keyType), func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			key, err := GenerateKey(keyType)
+			r.NoError(err)
+
+			// The keys survive the PEM round trip
+			privatePem, err := MarshalPrivateKey(key)
+			r.NoError(err)
+			key, err = ParsePrivateKey(privatePem, nil)
+			r.NoError(err)
+			publicPem, err := MarshalPublicKey(key.Public())
+			r.NoError(err)
+			publicKey, err := ParsePublicKey(publicPem)
+			r.NoError(err)
+
+			payload, err := NewPayload("ghcr.io/owner/app", testDigest)
+			r.NoError(err)
+			signature, err := Sign(key, payload)
+			r.NoError(err)
+
+			r.NoError(Verify(publicKey, payload, signature))
+			r.ErrorIs(Verify(publicKey, append(payload, ' '), signature), InvalidSignatureError)
+
+			otherKey, err := GenerateKey(keyType)
+			r.NoError(err)
+			r.ErrorIs(Verify(otherKey.Public(), payload, signature), InvalidSignatureError)
+		})
+	}
+}
+
+func TestParsePayload(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	data, err := NewPayload("ghcr.io/owner/app", testDigest)
+	r.NoError(err)
+	r.JSONEq(`{"critical":{"identity":{"docker-reference":"ghcr.io/owner/app"},"image":{"docker-manifest-digest":"`+testDigest.String()+`"},"type":"cosign container image signature"},"optional":null}`, string(data))
+
+	payload, err := ParsePayload(data, testDigest)
+	r.NoError(err)
+	r.Equal("ghcr.io/owner/app", payload.Critical.Identity.DockerReference)
+
+	_, err = ParsePayload(data, v1.Hash{Algorithm: "sha256", Hex: "00" + testDigest.Hex[2:]})
+	r.ErrorIs(err, InvalidSignatureError)
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	t.Setenv("CLOUDCTL_SIGNING_KEY_PASSWORD", "secret")
+
+	key, err := GenerateKey(KeyTypeEcdsa)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	t.Run("decrypts cosign keys", func(t *testing.T) {
+		r := require.New(t)
+
+		var envelope encryptedKey
+		envelope.KDF.Name = "scrypt"
+		envelope.KDF.Params.N, envelope.KDF.Params.R, envelope.KDF.Params.P = 1<<10, 8, 1
+		envelope.KDF.Salt = []byte("0123456789abcdef0123456789abcdef")
+		envelope.Cipher.Name = "nacl/secretbox"
+		envelope.Cipher.Nonce = make([]byte, 24)
+		_, err := rand.Read(envelope.Cipher.Nonce)
+		r.NoError(err)
+
+		derived, err := scrypt.Key([]byte("secret"), envelope.KDF.Salt, 1<<10, 8, 1, 32)
+		r.NoError(err)
+		var secret [32]byte
+		var nonce [24]byte
+		copy(secret[:], derived)
+		copy(nonce[:], envelope.Cipher.Nonce)
+		envelope.Ciphertext = secretbox.Seal(nil, der, &nonce, &secret)
+
+		data, err := json.Marshal(envelope)
+		r.NoError(err)
+		path := filepath.Join(t.TempDir(), "cosign.key")
+		r.NoError(os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: sigstorePrivateKeyPemType, Bytes: data}), 0o600))
+
+		loaded, err := LoadPrivateKey(KeyConfig{File: path}, nil)
+		r.NoError(err)
+		r.Equal(key.Public(), loaded.Public())
+	})
+
+	t.Run("reads the keyring", func(t *testing.T) {
+		r := require.New(t)
+
+		privatePem, err := MarshalPrivateKey(key)
+		r.NoError(err)
+
+		loaded, err := LoadPrivateKey(KeyConfig{Keyring: "release"}, testutil.NewMemoryCredentialsStorage(map[string]string{"release": string(privatePem)}))
+		r.NoError(err)
+		r.Equal(key.Public(), loaded.Public())
+
+		_, err = LoadPrivateKey(KeyConfig{Keyring: "missing"}, testutil.NewMemoryCredentialsStorage(nil))
+		r.Error(err)
+	})
+}
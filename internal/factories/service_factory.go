@@ -2,28 +2,44 @@ package factories
 
 import (
 	"fmt"
-	"log"
 	"log/slog"
 	"path/filepath"
 
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/build/pipeline"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/aws"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/azure"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/azure/identity"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/gcp"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/railway"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/clouds/render"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/config"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/layercache"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/container_image/registry"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/placeholders"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/placeholders/git"
 )
 
+// cloudProviderKeys are listed in the order NewCloudProvider applies them - the last configured one wins.
+var cloudProviderKeys = []string{
+	lib.RenderProviderKey,
+	lib.AwsEcsProviderKey,
+	lib.AwsAppRunnerProviderKey,
+	lib.GcpCloudRunProviderKey,
+	lib.RailwayProviderKey,
+	lib.AzureContainerAppsProviderKey,
+}
+
 type ServiceFactory struct {
 	service                    string
 	config                     *config.Config
 	registryCredentialsStorage lib.CredentialsStorage
 	cloudApiCredentialsStorage lib.CredentialsStorage
+	signingKeysStorage         lib.CredentialsStorage
 	placeholdersService        *placeholders.Service
+	gitRepository              git.RepositoryInfoService
 }
 
 func NewServiceFactory(service string, executionCtx *SharedServicesLocator) *ServiceFactory {
@@ -32,13 +48,13 @@ func NewServiceFactory(service string, executionCtx *SharedServicesLocator) *Ser
 		config:                     executionCtx.Config,
 		registryCredentialsStorage: executionCtx.RegistryCredentialsStorage,
 		cloudApiCredentialsStorage: executionCtx.CloudApiCredentialsStorage,
+		signingKeysStorage:         executionCtx.SigningKeysStorage,
 		placeholdersService:        executionCtx.PlaceholdersService,
+		gitRepository:              executionCtx.GitRepository,
 	}
 }
 
 func (f *ServiceFactory) NewImageService() (*container_image.Service, error) {
-	l := slog.With("context", "service_factory", "method", "NewImageService")
-
 	var imageConfig container_image.Config
 	if err := f.config.LoadVariableServiceConfigPart(&imageConfig, f.service, "container"); err != nil {
 		return nil, fmt.Errorf("error loading image build config: %w", err)
@@ -70,10 +86,72 @@ func (f *ServiceFactory) NewImageService() (*container_image.Service, error) {
 		}
 
 		containerRegistry = registry.NewGcpArtifactRegistry(registry.GcpArtifactRegistryConfig(resolvedGcpAr))
+	case imageConfig.Registry.AzACR != nil:
+		resolvedAcr, err := f.placeholdersService.ResolvePlaceholders(string(*imageConfig.Registry.AzACR))
+		if err != nil {
+			return nil, fmt.Errorf("resolving Azure Container Registry placeholder: %w", err)
+		}
+
+		containerRegistry = registry.NewAzureContainerRegistry(f.registryCredentialsStorage, registry.AzureContainerRegistryConfig(resolvedAcr), azureEnvKeys())
+	case imageConfig.Registry.DockerHub != nil:
+		resolvedDockerHub, err := f.placeholdersService.ResolvePlaceholders(string(*imageConfig.Registry.DockerHub))
+		if err != nil {
+			return nil, fmt.Errorf("resolving Docker Hub registry placeholder: %w", err)
+		}
+
+		containerRegistry = registry.NewDockerHub(f.registryCredentialsStorage, registry.DockerHubConfig(resolvedDockerHub),
+			[]string{lib.DockerHubUsernameEnv}, []string{lib.DockerHubTokenEnv})
+	case imageConfig.Registry.Oci != nil:
+		ociCfg := *imageConfig.Registry.Oci
+		resolvedOci, err := f.placeholdersService.ResolvePlaceholders(ociCfg.Image)
+		if err != nil {
+			return nil, fmt.Errorf("resolving OCI registry placeholder: %w", err)
+		}
+		ociCfg.Image = resolvedOci
+
+		containerRegistry = registry.NewOciRegistry(f.registryCredentialsStorage, ociCfg, []string{lib.OciUsernameEnv}, []string{lib.OciPasswordEnv})
 	default:
-		log.Fatalf("no registry configured for image: %s", imageConfig.Image)
+		return nil, fmt.Errorf("%w - no registry configured for image: %s", lib.BadUserInputError, imageConfig.Image)
 	}
 
+	pipelineService := f.newPipelineService(imageConfig)
+
+	return container_image.NewService(imageConfig, containerRegistry, f.placeholdersService, pipelineService, f.signingKeysStorage, f.gitRepository), nil
+}
+
+// NewPipelineService builds the pipeline of the service alone, for the commands that inspect the workspace without
+// building or pushing the image.
+func (f *ServiceFactory) NewPipelineService() (*pipeline.Service, error) {
+	var imageConfig container_image.Config
+	if err := f.config.LoadVariableServiceConfigPart(&imageConfig, f.service, "container"); err != nil {
+		return nil, fmt.Errorf("error loading image build config: %w", err)
+	}
+
+	return f.newPipelineService(imageConfig), nil
+}
+
+// NewLayerCache returns the cache of recompressed layers of the service with its size limit, for the commands that
+// maintain the cache without pushing. The cache is nil when it is disabled or the service compresses nothing.
+func (f *ServiceFactory) NewLayerCache() (*layercache.Cache, int64, error) {
+	var imageConfig container_image.Config
+	if err := f.config.LoadVariableServiceConfigPart(&imageConfig, f.service, "container"); err != nil {
+		return nil, 0, fmt.Errorf("error loading image build config: %w", err)
+	}
+
+	cache, err := imageConfig.Compression.GetLayerCache()
+	if err != nil {
+		return nil, 0, err
+	}
+	maxSize, err := imageConfig.Compression.GetLayerCacheMaxSize()
+	if err != nil {
+		return nil, 0, err
+	}
+	return cache, maxSize, nil
+}
+
+func (f *ServiceFactory) newPipelineService(imageConfig container_image.Config) *pipeline.Service {
+	l := slog.With("context", "service_factory", "method", "newPipelineService")
+
 	pipelineConfig := &pipeline.Config{}
 	if imageConfig.Build != nil && imageConfig.Build.Pipeline != nil {
 		pipelineConfig = imageConfig.Build.Pipeline
@@ -85,9 +163,28 @@ func (f *ServiceFactory) NewImageService() (*container_image.Service, error) {
 	}
 	repoRoot = filepath.Clean(repoRoot)
 	monorepoProvider := pipeline.NewPnpmMonorepo(repoRoot)
-	pipelineService := pipeline.NewService(*pipelineConfig, repoRoot, monorepoProvider, f.placeholdersService)
 
-	return container_image.NewService(imageConfig, containerRegistry, f.placeholdersService, pipelineService), nil
+	return pipeline.NewService(*pipelineConfig, repoRoot, monorepoProvider, f.placeholdersService)
+}
+
+// GetCloudProviderKey returns the config key of the provider NewCloudProvider builds for the service.
+func (f *ServiceFactory) GetCloudProviderKey() (string, error) {
+	svc, ok := f.config.Services[f.service]
+	if !ok {
+		return "", fmt.Errorf("service %s not found in config", f.service)
+	}
+
+	providerKey := ""
+	for _, key := range cloudProviderKeys {
+		if _, ok := svc.Extras[key]; ok {
+			providerKey = key
+		}
+	}
+	if providerKey == "" {
+		return "", fmt.Errorf("service %s has no valid cloud provider configured", f.service)
+	}
+
+	return providerKey, nil
 }
 
 func (f *ServiceFactory) NewCloudProvider() (clouds.CloudProvider, error) {
@@ -106,10 +203,14 @@ func (f *ServiceFactory) NewCloudProvider() (clouds.CloudProvider, error) {
 			return nil, fmt.Errorf("error loading render config: %w", err)
 		}
 
-		cloudProvider = render.MustNewProvider(f.service, renderCfg, f.cloudApiCredentialsStorage, []string{
+		renderProvider, err := render.NewProvider(f.service, renderCfg, f.cloudApiCredentialsStorage, []string{
 			lib.RenderApiKeyEnv,
 			lib.RenderNativeApiKeyEnv,
 		})
+		if err != nil {
+			return nil, fmt.Errorf("error creating Render provider: %w", err)
+		}
+		cloudProvider = renderProvider
 	}
 
 	if _, ok := svc.Extras[lib.AwsEcsProviderKey]; ok {
@@ -157,9 +258,53 @@ func (f *ServiceFactory) NewCloudProvider() (clouds.CloudProvider, error) {
 		cloudProvider = cloudRunProvider
 	}
 
+	if _, ok := svc.Extras[lib.RailwayProviderKey]; ok {
+		slog.Info("loading Railway provider for service", "service", f.service)
+
+		var railwayCfg railway.Config
+		if err := f.config.LoadVariableServiceConfigPart(&railwayCfg, f.service, lib.RailwayProviderKey); err != nil {
+			return nil, fmt.Errorf("error loading Railway config: %w", err)
+		}
+		if err := railwayCfg.Validate(); err != nil {
+			return nil, err
+		}
+
+		railwayProvider, err := railway.NewProviderFromStorage(f.service, railwayCfg, f.cloudApiCredentialsStorage, []string{
+			lib.RailwayApiTokenEnv,
+			lib.RailwayNativeApiTokenEnv,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error creating Railway provider: %w", err)
+		}
+		cloudProvider = railwayProvider
+	}
+
+	if _, ok := svc.Extras[lib.AzureContainerAppsProviderKey]; ok {
+		slog.Info("loading Azure Container Apps provider for service", "service", f.service)
+
+		var containerAppsCfg azure.ContainerAppsConfig
+		if err := f.config.LoadVariableServiceConfigPart(&containerAppsCfg, f.service, lib.AzureContainerAppsProviderKey); err != nil {
+			return nil, fmt.Errorf("error loading Azure Container Apps config: %w", err)
+		}
+
+		containerAppsProvider, err := azure.NewContainerAppsProvider(containerAppsCfg, f.cloudApiCredentialsStorage, azureEnvKeys())
+		if err != nil {
+			return nil, fmt.Errorf("error creating Azure Container Apps provider: %w", err)
+		}
+		cloudProvider = containerAppsProvider
+	}
+
 	if cloudProvider == nil {
 		return nil, fmt.Errorf("service %s has no valid cloud provider configured", f.service)
 	}
 
 	return cloudProvider, nil
 }
+
+func azureEnvKeys() identity.EnvKeys {
+	return identity.EnvKeys{
+		TenantID:     []string{lib.AzureTenantIDEnv, lib.AzureNativeTenantIDEnv},
+		ClientID:     []string{lib.AzureClientIDEnv, lib.AzureNativeClientIDEnv},
+		ClientSecret: []string{lib.AzureClientSecretEnv, lib.AzureNativeClientSecretEnv},
+	}
+}
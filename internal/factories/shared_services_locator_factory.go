@@ -2,22 +2,33 @@ package factories
 
 import (
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/config"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/ledger"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lockfile"
 	"github.com/AnotherFullstackDev/cloud-ctl/internal/placeholders"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/placeholders/git"
 )
 
 type SharedServicesLocator struct {
 	Config                                                 *config.Config
 	RegistryCredentialsStorage, CloudApiCredentialsStorage lib.CredentialsStorage
+	SigningKeysStorage                                     lib.CredentialsStorage
 	PlaceholdersService                                    *placeholders.Service
+	GitRepository                                          git.RepositoryInfoService
+	Lockfile                                               *lockfile.Service
+	Ledger                                                 *ledger.Service
 }
 
-func NewSharedServicesLocator(config *config.Config, registryCredentialsStorage, cloudApiCredentialsStorage lib.CredentialsStorage, placeholders *placeholders.Service) *SharedServicesLocator {
+func NewSharedServicesLocator(config *config.Config, registryCredentialsStorage, cloudApiCredentialsStorage, signingKeysStorage lib.CredentialsStorage, placeholders *placeholders.Service, gitRepository git.RepositoryInfoService, lock *lockfile.Service, history *ledger.Service) *SharedServicesLocator {
 	return &SharedServicesLocator{
 		config,
 		registryCredentialsStorage,
 		cloudApiCredentialsStorage,
+		signingKeysStorage,
 		placeholders,
+		gitRepository,
+		lock,
+		history,
 	}
 }
 
@@ -26,6 +37,10 @@ func (l *SharedServicesLocator) WithConfig(config *config.Config) *SharedService
 		config,
 		l.RegistryCredentialsStorage,
 		l.CloudApiCredentialsStorage,
+		l.SigningKeysStorage,
 		l.PlaceholdersService,
+		l.GitRepository,
+		l.Lockfile,
+		l.Ledger,
 	}
 }
@@ -0,0 +1,246 @@
+package ledger
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+)
+
+const DefaultPath = ".cloudctl/history.jsonl"
+
+type Phase string
+
+const (
+	PhaseBuild  Phase = "build"
+	PhasePush   Phase = "push"
+	PhaseDeploy Phase = "deploy"
+)
+
+type Outcome string
+
+const (
+	OutcomeSucceeded Outcome = "succeeded"
+	OutcomeFailed    Outcome = "failed"
+)
+
+var (
+	EntryNotFoundError = errors.New("history entry not found")
+)
+
+// PhaseRecord is a step of the command, failed phases keep their error.
+type PhaseRecord struct {
+	Phase      Phase     `json:"phase"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Entry is a single run of a command for a service.
+type Entry struct {
+	ID          string        `json:"id"`
+	Command     string        `json:"command"`
+	Service     string        `json:"service"`
+	Environment string        `json:"environment"`
+	User        string        `json:"user,omitempty"`
+	Commit      string        `json:"commit,omitempty"`
+	ImageRef    string        `json:"image_ref,omitempty"`
+	Digest      string        `json:"digest,omitempty"`
+	Outcome     Outcome       `json:"outcome"`
+	Error       string        `json:"error,omitempty"`
+	StartedAt   time.Time     `json:"started_at"`
+	FinishedAt  time.Time     `json:"finished_at"`
+	Phases      []PhaseRecord `json:"phases,omitempty"`
+}
+
+func (e Entry) Duration() time.Duration {
+	return e.FinishedAt.Sub(e.StartedAt)
+}
+
+// Service appends entries to the JSONL ledger and reads them back. Entries are never rewritten.
+type Service struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewService(path string) *Service {
+	return &Service{path: path}
+}
+
+func (s *Service) GetPath() string {
+	return s.path
+}
+
+// Append writes the entry as a single line at the end of the ledger, creating the ledger when needed.
+func (s *Service) Append(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding history entry: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("creating history directory: %w", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening history %s: %w", s.path, err)
+	}
+	defer f.Close()
+
+	// A line cut short by an interrupted write is ended first, so it does not swallow this entry
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			line = append([]byte{'\n'}, line...)
+		}
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing history %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// List returns the entries of the service in the environment, newest first. Empty filters match everything.
+func (s *Service) List(service, env string) ([]Entry, error) {
+	entries, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	entries = slices.DeleteFunc(entries, func(entry Entry) bool {
+		return (service != "" && entry.Service != service) || (env != "" && entry.Environment != env)
+	})
+	slices.Reverse(entries)
+	return entries, nil
+}
+
+// Get returns the entry with the ID, a unique prefix of the ID is enough.
+func (s *Service) Get(id string) (Entry, error) {
+	entries, err := s.read()
+	if err != nil {
+		return Entry{}, err
+	}
+
+	var matches []Entry
+	for _, entry := range entries {
+		if entry.ID == id {
+			return entry, nil
+		}
+		if id != "" && strings.HasPrefix(entry.ID, id) {
+			matches = append(matches, entry)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return Entry{}, fmt.Errorf("%w: %s", EntryNotFoundError, id)
+	case 1:
+		return matches[0], nil
+	default:
+		return Entry{}, fmt.Errorf("%w - history entry ID prefix '%s' matches %d entries", lib.BadUserInputError, id, len(matches))
+	}
+}
+
+// read returns the entries in the order they were appended. Lines that can not be decoded, like one cut short by an
+// interrupted write, are skipped.
+func (s *Service) read() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening history %s: %w", s.path, err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			slog.Debug("skipping invalid history line", "path", s.path, "line", lineNumber, "error", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading history %s: %w", s.path, err)
+	}
+
+	return entries, nil
+}
+
+// Run times the phases of a command and appends its entry to the ledger once finished.
+// A Run of a nil Service only runs the phases.
+type Run struct {
+	ledger *Service
+	entry  Entry
+}
+
+// Start begins a run of the command described by the entry, the ID and start time are set here.
+func (s *Service) Start(entry Entry) *Run {
+	entry.ID = newID()
+	entry.StartedAt = time.Now().UTC()
+	return &Run{ledger: s, entry: entry}
+}
+
+// Phase runs the step and records how long it took.
+func (r *Run) Phase(phase Phase, step func() error) error {
+	record := PhaseRecord{Phase: phase, StartedAt: time.Now().UTC()}
+	err := step()
+	record.DurationMs = time.Since(record.StartedAt).Milliseconds()
+	if err != nil {
+		record.Error = err.Error()
+	}
+	r.entry.Phases = append(r.entry.Phases, record)
+	return err
+}
+
+// SetImage records the image the run produced or deployed.
+func (r *Run) SetImage(imageRef, digest string) {
+	r.entry.ImageRef = imageRef
+	r.entry.Digest = digest
+}
+
+// Finish sets the outcome from the command error and appends the entry.
+func (r *Run) Finish(err error) (Entry, error) {
+	r.entry.FinishedAt = time.Now().UTC()
+	r.entry.Outcome = OutcomeSucceeded
+	if err != nil {
+		r.entry.Outcome = OutcomeFailed
+		r.entry.Error = err.Error()
+	}
+
+	if r.ledger == nil {
+		return r.entry, nil
+	}
+	return r.entry, r.ledger.Append(r.entry)
+}
+
+func newID() string {
+	id := make([]byte, 6)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,100 @@
+package ledger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Run(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	path := filepath.Join(t.TempDir(), DefaultPath)
+	svc := NewService(path)
+
+	run := svc.Start(Entry{Command: "deploy", Service: "api", Environment: "prod", User: "Jane Doe <jane@example.com>", Commit: "abc123"})
+	r.NoError(run.Phase(PhaseBuild, func() error { return nil }))
+	r.NoError(run.Phase(PhasePush, func() error { return nil }))
+	run.SetImage("ghcr.io/org/api@sha256:aaa", "sha256:aaa")
+	deployErr := errors.New("provider unavailable")
+	r.ErrorIs(run.Phase(PhaseDeploy, func() error { return deployErr }), deployErr)
+	entry, err := run.Finish(deployErr)
+	r.NoError(err)
+
+	r.Len(entry.ID, 12)
+	r.Equal(OutcomeFailed, entry.Outcome)
+	r.Equal("provider unavailable", entry.Error)
+	r.Len(entry.Phases, 3)
+	r.Equal(PhaseDeploy, entry.Phases[2].Phase)
+	r.Equal("provider unavailable", entry.Phases[2].Error)
+	r.False(entry.FinishedAt.Before(entry.StartedAt))
+
+	stored, err := svc.Get(entry.ID)
+	r.NoError(err)
+	r.Equal(entry.ID, stored.ID)
+	r.Equal("sha256:aaa", stored.Digest)
+	r.Equal(entry.Phases[0].Phase, stored.Phases[0].Phase)
+}
+
+func TestService_List(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	svc := NewService(path)
+
+	r.NoError(svc.Append(Entry{ID: "aaa111", Service: "api", Environment: "staging"}))
+	r.NoError(svc.Append(Entry{ID: "aaa222", Service: "api", Environment: "prod"}))
+	r.NoError(svc.Append(Entry{ID: "bbb333", Service: "admin", Environment: "prod"}))
+
+	// A line cut short by an interrupted write does not hide the other entries
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	r.NoError(err)
+	_, err = f.WriteString(`{"id":"ccc`)
+	r.NoError(err)
+	r.NoError(f.Close())
+	r.NoError(svc.Append(Entry{ID: "ddd444", Service: "api", Environment: "prod"}))
+
+	entries, err := svc.List("api", "prod")
+	r.NoError(err)
+	r.Len(entries, 2)
+	r.Equal("ddd444", entries[0].ID)
+	r.Equal("aaa222", entries[1].ID)
+
+	entries, err = svc.List("", "prod")
+	r.NoError(err)
+	r.Equal([]string{"ddd444", "bbb333", "aaa222"}, []string{entries[0].ID, entries[1].ID, entries[2].ID})
+
+	entry, err := svc.Get("bbb")
+	r.NoError(err)
+	r.Equal("admin", entry.Service)
+
+	_, err = svc.Get("aaa")
+	r.ErrorIs(err, lib.BadUserInputError)
+
+	_, err = svc.Get("eee")
+	r.ErrorIs(err, EntryNotFoundError)
+
+	empty, err := NewService(filepath.Join(t.TempDir(), "missing.jsonl")).List("", "")
+	r.NoError(err)
+	r.Empty(empty)
+}
+
+func TestService_Run_withoutLedger(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	var svc *Service
+	run := svc.Start(Entry{Command: "build"})
+	ran := false
+	r.NoError(run.Phase(PhaseBuild, func() error { ran = true; return nil }))
+	entry, err := run.Finish(nil)
+	r.NoError(err)
+	r.True(ran)
+	r.Equal(OutcomeSucceeded, entry.Outcome)
+}
@@ -3,10 +3,12 @@ package lib
 import "fmt"
 
 const (
-	RenderProviderKey       = "render"
-	AwsEcsProviderKey       = "aws_ecs"
-	AwsAppRunnerProviderKey = "aws_apprunner"
-	GcpCloudRunProviderKey  = "gcp_cloudrun"
+	RenderProviderKey             = "render"
+	AwsEcsProviderKey             = "aws_ecs"
+	AwsAppRunnerProviderKey       = "aws_apprunner"
+	GcpCloudRunProviderKey        = "gcp_cloudrun"
+	RailwayProviderKey            = "railway"
+	AzureContainerAppsProviderKey = "azure_containerapps"
 )
 
 const (
@@ -15,6 +17,10 @@ const (
 
 var (
 	LogLevelEnv = fmt.Sprintf("%s_%s", EnvKeyPrefix, "LOG_LEVEL")
+	// LockfilePathEnv overrides where the deploy lockfile is written, e.g. to keep it as a CI artifact
+	LockfilePathEnv = fmt.Sprintf("%s_%s", EnvKeyPrefix, "LOCKFILE")
+	// HistoryPathEnv overrides where the local history of builds, pushes and deploys is appended
+	HistoryPathEnv = fmt.Sprintf("%s_%s", EnvKeyPrefix, "HISTORY")
 )
 
 var (
@@ -22,14 +28,52 @@ var (
 	GithubTokenEnv   = "GITHUB_TOKEN"
 )
 
+var (
+	GithubActionsEnv    = "GITHUB_ACTIONS"
+	GithubServerURLEnv  = "GITHUB_SERVER_URL"
+	GithubRepositoryEnv = "GITHUB_REPOSITORY"
+	GithubRunIDEnv      = "GITHUB_RUN_ID"
+)
+
+var (
+	// SourceDateEpochEnv pins the image created time for reproducible builds, see https://reproducible-builds.org/docs/source-date-epoch/
+	SourceDateEpochEnv = "SOURCE_DATE_EPOCH"
+)
+
+var (
+	DockerHubUsernameEnv = fmt.Sprintf("%s_%s", EnvKeyPrefix, "DOCKER_HUB_USERNAME")
+	DockerHubTokenEnv    = fmt.Sprintf("%s_%s", EnvKeyPrefix, "DOCKER_HUB_TOKEN")
+	OciUsernameEnv       = fmt.Sprintf("%s_%s", EnvKeyPrefix, "OCI_USERNAME")
+	OciPasswordEnv       = fmt.Sprintf("%s_%s", EnvKeyPrefix, "OCI_PASSWORD")
+)
+
 var (
 	RenderApiKeyEnv       = fmt.Sprintf("%s_%s", EnvKeyPrefix, "RENDER_API_KEY")
 	RenderNativeApiKeyEnv = "RENDER_API_KEY"
 )
 
+var (
+	RailwayApiTokenEnv       = fmt.Sprintf("%s_%s", EnvKeyPrefix, "RAILWAY_API_TOKEN")
+	RailwayNativeApiTokenEnv = "RAILWAY_API_TOKEN"
+)
+
+var (
+	AzureTenantIDEnv           = fmt.Sprintf("%s_%s", EnvKeyPrefix, "AZURE_TENANT_ID")
+	AzureNativeTenantIDEnv     = "AZURE_TENANT_ID"
+	AzureClientIDEnv           = fmt.Sprintf("%s_%s", EnvKeyPrefix, "AZURE_CLIENT_ID")
+	AzureNativeClientIDEnv     = "AZURE_CLIENT_ID"
+	AzureClientSecretEnv       = fmt.Sprintf("%s_%s", EnvKeyPrefix, "AZURE_CLIENT_SECRET")
+	AzureNativeClientSecretEnv = "AZURE_CLIENT_SECRET"
+)
+
 type Platform string
 
 const (
 	PlatformLinuxAmd64 Platform = "linux/amd64"
 	PlatformLinuxArm64 Platform = "linux/arm64"
 )
+
+var (
+	SigningKeyPasswordEnv      = fmt.Sprintf("%s_%s", EnvKeyPrefix, "SIGNING_KEY_PASSWORD")
+	CosignNativeKeyPasswordEnv = "COSIGN_PASSWORD"
+)
@@ -35,3 +35,11 @@ func ConfigEntryToTypedSlice[T any](entry interface{}, identifier string) ([]T,
 
 	return result, nil
 }
+
+// ValueOrDash returns the value, or "-" for an empty one so table columns never look missing.
+func ValueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -1,17 +1,28 @@
 package lib
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"strings"
+	"sync"
 
 	"golang.org/x/term"
 )
 
+// secretInputMu serializes the prompts, the services of a batch resolve their credentials concurrently
+// but share the terminal and the credentials storage.
+var secretInputMu sync.Mutex
+
 func RequestSecretInput(in io.Reader, out io.Writer, prompt string) (string, error) {
+	secretInputMu.Lock()
+	defer secretInputMu.Unlock()
+
+	return requestSecretInput(in, out, prompt)
+}
+
+func requestSecretInput(in io.Reader, out io.Writer, prompt string) (string, error) {
 	_, err := fmt.Fprintf(out, "%s: ", prompt)
 	if err != nil {
 		return "", fmt.Errorf("writing prompt: %w", err)
@@ -32,8 +43,7 @@ func RequestSecretInput(in io.Reader, out io.Writer, prompt string) (string, err
 	slog.Debug("Not a terminal, falling back to normal input reading", "prompt", prompt)
 
 	// When not a terminal, fall back to normal input reading
-	reader := bufio.NewReader(in)
-	secret, err := reader.ReadString('\n')
+	secret, err := readLine(in)
 	if err != nil {
 		return "", fmt.Errorf("reading secret input: %w", err)
 	}
@@ -43,7 +53,30 @@ func RequestSecretInput(in io.Reader, out io.Writer, prompt string) (string, err
 	return strings.TrimSpace(secret), nil
 }
 
+// readLine reads up to the newline one byte at a time, so no input meant for a later prompt is buffered away.
+func readLine(in io.Reader) (string, error) {
+	var line strings.Builder
+	b := make([]byte, 1)
+	for {
+		n, err := in.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				return line.String(), nil
+			}
+			line.WriteByte(b[0])
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// GetSecretFromEnvOrInput returns the secret from the storage, the environment or the input, in this order.
+// Concurrent calls are serialized, so a secret entered once is found in the storage by the other callers.
 func GetSecretFromEnvOrInput(storage CredentialsStorage, storageKey, storageLabel string, envVars []string, in io.Reader, out io.Writer, prompt string) (string, error) {
+	secretInputMu.Lock()
+	defer secretInputMu.Unlock()
+
 	authToken, err := storage.Get(storageKey)
 	if err != nil {
 		return "", fmt.Errorf("retrieving secret from storage: %w", err)
@@ -59,7 +92,7 @@ func GetSecretFromEnvOrInput(storage CredentialsStorage, storageKey, storageLabe
 	}
 
 	if authToken == "" {
-		authToken, err = RequestSecretInput(in, out, prompt)
+		authToken, err = requestSecretInput(in, out, prompt)
 		if err != nil {
 			return "", fmt.Errorf("requesting secret input: %w", err)
 		}
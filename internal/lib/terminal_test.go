@@ -0,0 +1,85 @@
+package lib_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// syncWriter guards the buffer, so only the prompts themselves are checked for races.
+type syncWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *syncWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+// getSecretsConcurrently resolves the keys at once like services deployed in parallel do.
+func getSecretsConcurrently(storage lib.CredentialsStorage, in io.Reader, out io.Writer, keys ...string) ([]string, []error) {
+	secrets := make([]string, len(keys))
+	errs := make([]error, len(keys))
+
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			secrets[i], errs[i] = lib.GetSecretFromEnvOrInput(storage, key, key, nil, in, out, "Please provide "+key)
+		}()
+	}
+	wg.Wait()
+
+	return secrets, errs
+}
+
+func TestGetSecretFromEnvOrInput_concurrent(t *testing.T) {
+	t.Parallel()
+
+	t.Run("prompts once for a secret shared by two deploys", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		storage := testutil.NewMemoryCredentialsStorage(nil)
+		in := strings.NewReader("token\nunread\n")
+		out := &syncWriter{}
+
+		secrets, errs := getSecretsConcurrently(storage, in, out, "render_api_key", "render_api_key")
+		r.Equal([]error{nil, nil}, errs)
+		r.Equal([]string{"token", "token"}, secrets)
+		r.Equal(1, strings.Count(out.String(), "Please provide"))
+
+		rest, err := io.ReadAll(in)
+		r.NoError(err)
+		r.Equal("unread\n", string(rest))
+	})
+
+	t.Run("reads one line per prompt for different secrets", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		storage := testutil.NewMemoryCredentialsStorage(nil)
+		out := &syncWriter{}
+
+		secrets, errs := getSecretsConcurrently(storage, strings.NewReader("first\nsecond\n"), out, "render_api_key", "railway_api_token")
+		r.Equal([]error{nil, nil}, errs)
+		r.ElementsMatch([]string{"first", "second"}, secrets)
+		r.Equal(map[string]string{"render_api_key": secrets[0], "railway_api_token": secrets[1]}, storage.Values())
+		r.Equal(2, strings.Count(out.String(), "Please provide"))
+	})
+}
@@ -0,0 +1,201 @@
+package lockfile
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultPath = "cloudctl.lock.yaml"
+	// maxEntries is how many deploys are kept per service and environment, older ones are dropped
+	maxEntries = 20
+)
+
+var (
+	EntryNotFoundError = errors.New("lockfile entry not found")
+)
+
+// Entry records a successful deploy of a service image to an environment.
+type Entry struct {
+	Service     string    `yaml:"service" json:"service"`
+	Environment string    `yaml:"environment" json:"environment"`
+	Provider    string    `yaml:"provider" json:"provider"`
+	ImageRef    string    `yaml:"image_ref" json:"image_ref"`
+	Digest      string    `yaml:"digest" json:"digest"`
+	Commit      string    `yaml:"commit,omitempty" json:"commit,omitempty"`
+	DeployedAt  time.Time `yaml:"deployed_at" json:"deployed_at"`
+}
+
+// PinnedRef returns the image reference pinned to the digest, tag based deploys record the tag as the image reference.
+func (e Entry) PinnedRef() string {
+	if e.Digest == "" || strings.Contains(e.ImageRef, "@") {
+		return e.ImageRef
+	}
+
+	repository := e.ImageRef
+	if colon := strings.LastIndex(repository, ":"); colon > strings.LastIndex(repository, "/") {
+		repository = repository[:colon]
+	}
+	return repository + "@" + e.Digest
+}
+
+// Matches reports whether the image reference points to the recorded image. A tag can only be compared with the
+// recorded tag, since it does not tell which digest it pointed to.
+func (e Entry) Matches(imageRef string) bool {
+	if _, digest, ok := strings.Cut(imageRef, "@"); ok {
+		return digest == e.Digest
+	}
+	return imageRef == e.ImageRef
+}
+
+type file struct {
+	Deployments []Entry `yaml:"deployments"`
+}
+
+// Service reads and writes the deploy lockfile. Entries are grouped by service and environment, oldest first,
+// so that a committed lockfile gets small diffs.
+type Service struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewService(path string) *Service {
+	return &Service{path: path}
+}
+
+func (s *Service) GetPath() string {
+	return s.path
+}
+
+// Record adds the deploy to the lockfile.
+func (s *Service) Record(entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lock, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	lock.Deployments = append(lock.Deployments, entry)
+	slices.SortStableFunc(lock.Deployments, func(a, b Entry) int {
+		return cmp.Or(cmp.Compare(a.Service, b.Service), cmp.Compare(a.Environment, b.Environment))
+	})
+
+	deployments := make([]Entry, 0, len(lock.Deployments))
+	for i, deployment := range lock.Deployments {
+		newer := 0
+		for _, other := range lock.Deployments[i+1:] {
+			if other.Service == deployment.Service && other.Environment == deployment.Environment {
+				newer++
+			}
+		}
+		if newer < maxEntries {
+			deployments = append(deployments, deployment)
+		}
+	}
+	lock.Deployments = deployments
+
+	return s.write(lock)
+}
+
+// History returns the recorded deploys of the service to the environment, newest first.
+func (s *Service) History(service, env string) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lock, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	var history []Entry
+	for _, entry := range lock.Deployments {
+		if entry.Service == service && entry.Environment == env {
+			history = append(history, entry)
+		}
+	}
+	slices.Reverse(history)
+	return history, nil
+}
+
+// Latest returns the last recorded deploy of the service to the environment, EntryNotFoundError when there is none.
+func (s *Service) Latest(service, env string) (Entry, error) {
+	history, err := s.History(service, env)
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(history) == 0 {
+		return Entry{}, fmt.Errorf("%w for service %s in %s", EntryNotFoundError, service, env)
+	}
+	return history[0], nil
+}
+
+// Previous returns the last recorded deploy of another image than the latest one, EntryNotFoundError when there is none.
+func (s *Service) Previous(service, env string) (Entry, error) {
+	history, err := s.History(service, env)
+	if err != nil {
+		return Entry{}, err
+	}
+	for _, entry := range history[min(1, len(history)):] {
+		if entry.Digest != history[0].Digest {
+			return entry, nil
+		}
+	}
+	return Entry{}, fmt.Errorf("%w for the previous image of service %s in %s", EntryNotFoundError, service, env)
+}
+
+func (s *Service) read() (file, error) {
+	var lock file
+
+	content, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return lock, nil
+	}
+	if err != nil {
+		return lock, fmt.Errorf("reading lockfile %s: %w", s.path, err)
+	}
+	if err := yaml.Unmarshal(content, &lock); err != nil {
+		return lock, fmt.Errorf("decoding lockfile %s: %w", s.path, err)
+	}
+	return lock, nil
+}
+
+// write replaces the lockfile through a temporary file, so an interrupted write does not leave a truncated lockfile.
+func (s *Service) write(lock file) error {
+	content, err := yaml.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("encoding lockfile: %w", err)
+	}
+	content = append([]byte("# Generated by cloudctl, records the images deployed to every environment\n"), content...)
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temporary lockfile: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing temporary lockfile: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing temporary lockfile: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("setting lockfile permissions: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("replacing lockfile %s: %w", s.path, err)
+	}
+	return nil
+}
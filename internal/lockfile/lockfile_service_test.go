@@ -0,0 +1,102 @@
+package lockfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Record(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	path := filepath.Join(t.TempDir(), DefaultPath)
+	svc := NewService(path)
+
+	_, err := svc.Latest("api", "staging")
+	r.ErrorIs(err, EntryNotFoundError)
+
+	deployedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, digest := range []string{"sha256:aaa", "sha256:bbb", "sha256:bbb"} {
+		r.NoError(svc.Record(Entry{
+			Service:     "api",
+			Environment: "staging",
+			Provider:    "render",
+			ImageRef:    "ghcr.io/org/api@" + digest,
+			Digest:      digest,
+			Commit:      fmt.Sprintf("commit-%d", i),
+			DeployedAt:  deployedAt.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+	r.NoError(svc.Record(Entry{Service: "api", Environment: "prod", Digest: "sha256:aaa"}))
+	r.NoError(svc.Record(Entry{Service: "admin", Environment: "staging", Digest: "sha256:ccc"}))
+
+	latest, err := svc.Latest("api", "staging")
+	r.NoError(err)
+	r.Equal("commit-2", latest.Commit)
+	r.Equal(deployedAt.Add(2*time.Hour), latest.DeployedAt)
+
+	// Redeploys of the same image are skipped
+	previous, err := svc.Previous("api", "staging")
+	r.NoError(err)
+	r.Equal("sha256:aaa", previous.Digest)
+
+	_, err = svc.Previous("api", "prod")
+	r.ErrorIs(err, EntryNotFoundError)
+
+	// A new service instance reads what the previous one wrote, grouped by service and environment
+	lock, err := NewService(path).read()
+	r.NoError(err)
+	r.Len(lock.Deployments, 5)
+	r.Equal("admin", lock.Deployments[0].Service)
+	r.Equal("prod", lock.Deployments[1].Environment)
+	r.Equal("commit-0", lock.Deployments[2].Commit)
+
+	content, err := os.ReadFile(path)
+	r.NoError(err)
+	r.Contains(string(content), "image_ref: ghcr.io/org/api@sha256:aaa")
+}
+
+func TestService_Record_keepsRecentEntries(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	svc := NewService(filepath.Join(t.TempDir(), DefaultPath))
+	for i := range maxEntries + 5 {
+		r.NoError(svc.Record(Entry{Service: "api", Environment: "prod", Digest: fmt.Sprintf("sha256:%d", i)}))
+	}
+
+	history, err := svc.History("api", "prod")
+	r.NoError(err)
+	r.Len(history, maxEntries)
+	r.Equal(fmt.Sprintf("sha256:%d", maxEntries+4), history[0].Digest)
+	r.Equal("sha256:5", history[maxEntries-1].Digest)
+}
+
+func TestEntry(t *testing.T) {
+	t.Parallel()
+
+	t.Run("pinned ref", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		r.Equal("localhost:5000/org/api@sha256:aaa", Entry{ImageRef: "localhost:5000/org/api:v1", Digest: "sha256:aaa"}.PinnedRef())
+		r.Equal("localhost:5000/org/api@sha256:aaa", Entry{ImageRef: "localhost:5000/org/api", Digest: "sha256:aaa"}.PinnedRef())
+		r.Equal("ghcr.io/org/api@sha256:aaa", Entry{ImageRef: "ghcr.io/org/api@sha256:aaa", Digest: "sha256:aaa"}.PinnedRef())
+	})
+
+	t.Run("matches", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		entry := Entry{ImageRef: "ghcr.io/org/api:v1", Digest: "sha256:aaa"}
+		r.True(entry.Matches("ghcr.io/org/api@sha256:aaa"))
+		r.False(entry.Matches("ghcr.io/org/api@sha256:bbb"))
+		r.True(entry.Matches("ghcr.io/org/api:v1"))
+		r.False(entry.Matches("ghcr.io/org/api:v2"))
+	})
+}
@@ -1,9 +1,14 @@
 package git
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
+	"slices"
 
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
 	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/config"
 	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/object"
 )
@@ -13,6 +18,10 @@ type RepositoryInfoService interface {
 	CurrentCommit() (*object.Commit, error)
 	CurrentTag() (*plumbing.Reference, error)
 	TagsPointingAt(hash plumbing.Hash) ([]*plumbing.Reference, error)
+	IsDirty() (bool, error)
+	RemoteURL(remote string) (string, error)
+	CurrentUser() (string, error)
+	ChangedFiles(since string) ([]string, error)
 }
 
 type repositoryInfoServiceImpl struct {
@@ -115,3 +124,130 @@ func (s *repositoryInfoServiceImpl) CurrentTag() (*plumbing.Reference, error) {
 
 	return tags[0], nil
 }
+
+// IsDirty reports whether the worktree has uncommitted changes, untracked files included.
+func (s *repositoryInfoServiceImpl) IsDirty() (bool, error) {
+	worktree, err := s.r.Worktree()
+	if err != nil {
+		return false, fmt.Errorf("getting worktree: %w", err)
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		return false, fmt.Errorf("getting worktree status: %w", err)
+	}
+
+	return !status.IsClean(), nil
+}
+
+// RemoteURL returns the first URL of the remote, empty when the repository has no such remote.
+func (s *repositoryInfoServiceImpl) RemoteURL(remote string) (string, error) {
+	r, err := s.r.Remote(remote)
+	if errors.Is(err, git.ErrRemoteNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("getting remote %s: %w", remote, err)
+	}
+
+	urls := r.Config().URLs
+	if len(urls) == 0 {
+		return "", nil
+	}
+	return urls[0], nil
+}
+
+// CurrentUser returns the configured git user as "Name <email>", empty when the repository and global config have none.
+func (s *repositoryInfoServiceImpl) CurrentUser() (string, error) {
+	cfg, err := s.r.ConfigScoped(config.GlobalScope)
+	if err != nil {
+		return "", fmt.Errorf("reading git config: %w", err)
+	}
+
+	switch {
+	case cfg.User.Name != "" && cfg.User.Email != "":
+		return fmt.Sprintf("%s <%s>", cfg.User.Name, cfg.User.Email), nil
+	case cfg.User.Name != "":
+		return cfg.User.Name, nil
+	default:
+		return cfg.User.Email, nil
+	}
+}
+
+// ChangedFiles returns the absolute paths of the files changed in the worktree since the revision: the commits since
+// its merge base with HEAD plus the uncommitted changes, untracked files included. Deleted files are listed as well.
+func (s *repositoryInfoServiceImpl) ChangedFiles(since string) ([]string, error) {
+	hash, err := s.r.ResolveRevision(plumbing.Revision(since))
+	if err != nil {
+		return nil, fmt.Errorf("%w - resolving revision %s: %w", lib.BadUserInputError, since, err)
+	}
+	sinceCommit, err := s.r.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("getting commit object of %s: %w", since, err)
+	}
+	headCommit, err := s.CurrentCommit()
+	if err != nil {
+		return nil, err
+	}
+
+	// Comparing with the merge base leaves out what changed on the revision branch only, like "git diff since...HEAD"
+	bases, err := sinceCommit.MergeBase(headCommit)
+	if err != nil {
+		return nil, fmt.Errorf("getting merge base of %s and HEAD: %w", since, err)
+	}
+	if len(bases) > 0 {
+		sinceCommit = bases[0]
+	}
+
+	sinceTree, err := sinceCommit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("getting tree of %s: %w", since, err)
+	}
+	headTree, err := headCommit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("getting tree of HEAD: %w", err)
+	}
+	changes, err := object.DiffTree(sinceTree, headTree)
+	if err != nil {
+		return nil, fmt.Errorf("comparing %s with HEAD: %w", since, err)
+	}
+
+	changed := make(map[string]struct{}, len(changes))
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" {
+				changed[name] = struct{}{}
+			}
+		}
+	}
+
+	worktree, err := s.r.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("getting worktree: %w", err)
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, fmt.Errorf("getting worktree status: %w", err)
+	}
+	for name, fileStatus := range status {
+		if fileStatus.Staging == git.Unmodified && fileStatus.Worktree == git.Unmodified {
+			continue
+		}
+		changed[name] = struct{}{}
+		if fileStatus.Extra != "" {
+			changed[fileStatus.Extra] = struct{}{}
+		}
+	}
+
+	root, err := filepath.Abs(worktree.Filesystem.Root())
+	if err != nil {
+		return nil, fmt.Errorf("resolving worktree root: %w", err)
+	}
+	files := make([]string, 0, len(changed))
+	for name := range changed {
+		files = append(files, filepath.Join(root, filepath.FromSlash(name)))
+	}
+	slices.Sort(files)
+
+	return files, nil
+}
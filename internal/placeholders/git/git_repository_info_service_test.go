@@ -0,0 +1,64 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositoryInfoService_ChangedFiles(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	r.NoError(err)
+	worktree, err := repo.Worktree()
+	r.NoError(err)
+
+	writeFile := func(path, content string) {
+		fullPath := filepath.Join(root, filepath.FromSlash(path))
+		r.NoError(os.MkdirAll(filepath.Dir(fullPath), 0o755))
+		r.NoError(os.WriteFile(fullPath, []byte(content), 0o644))
+	}
+	commit := func(message string, paths ...string) plumbing.Hash {
+		for _, path := range paths {
+			_, err := worktree.Add(path)
+			r.NoError(err)
+		}
+		hash, err := worktree.Commit(message, &git.CommitOptions{
+			Author: &object.Signature{Name: "Jane Doe", Email: "jane@example.com", When: time.Now()},
+		})
+		r.NoError(err)
+		return hash
+	}
+
+	writeFile("apps/api/index.ts", "v1")
+	writeFile("apps/web/index.ts", "v1")
+	base := commit("initial", "apps/api/index.ts", "apps/web/index.ts")
+	r.NoError(repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("base"), base)))
+
+	writeFile("packages/lib/index.ts", "v1")
+	commit("add lib", "packages/lib/index.ts")
+	writeFile("apps/api/index.ts", "v2")
+	writeFile("apps/admin/index.ts", "untracked")
+
+	svc := &repositoryInfoServiceImpl{r: repo}
+	files, err := svc.ChangedFiles("base")
+	r.NoError(err)
+	r.Equal([]string{
+		filepath.Join(root, "apps", "admin", "index.ts"),
+		filepath.Join(root, "apps", "api", "index.ts"),
+		filepath.Join(root, "packages", "lib", "index.ts"),
+	}, files)
+
+	_, err = svc.ChangedFiles("missing")
+	r.ErrorIs(err, lib.BadUserInputError)
+}
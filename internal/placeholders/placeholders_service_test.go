@@ -93,6 +93,22 @@ func (m mockGitRepoInfoService) TagsPointingAt(hash plumbing.Hash) ([]*plumbing.
 	return refs, nil
 }
 
+func (m mockGitRepoInfoService) IsDirty() (bool, error) {
+	return false, nil
+}
+
+func (m mockGitRepoInfoService) RemoteURL(remote string) (string, error) {
+	return "", nil
+}
+
+func (m mockGitRepoInfoService) CurrentUser() (string, error) {
+	return "", nil
+}
+
+func (m mockGitRepoInfoService) ChangedFiles(since string) ([]string, error) {
+	return nil, nil
+}
+
 func TestPlaceholdersParsing(t *testing.T) {
 	emptyRepoInfoService := mockGitRepoInfoService{}
 	r := require.New(t)
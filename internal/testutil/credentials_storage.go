@@ -0,0 +1,50 @@
+package testutil
+
+import (
+	"maps"
+	"sync"
+
+	"github.com/AnotherFullstackDev/cloud-ctl/internal/lib"
+)
+
+// MemoryCredentialsStorage is an in-memory lib.CredentialsStorage, safe for concurrent use.
+type MemoryCredentialsStorage struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+var _ lib.CredentialsStorage = (*MemoryCredentialsStorage)(nil)
+
+// NewMemoryCredentialsStorage returns a storage holding a copy of the given values.
+func NewMemoryCredentialsStorage(values map[string]string) *MemoryCredentialsStorage {
+	s := &MemoryCredentialsStorage{values: map[string]string{}}
+	maps.Copy(s.values, values)
+	return s
+}
+
+func (s *MemoryCredentialsStorage) Set(key string, value string, extra lib.KeyExtras) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	return nil
+}
+
+func (s *MemoryCredentialsStorage) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key], nil
+}
+
+func (s *MemoryCredentialsStorage) Remove(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	return nil
+}
+
+// Values returns a copy of the stored values.
+func (s *MemoryCredentialsStorage) Values() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.values)
+}
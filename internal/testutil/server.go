@@ -0,0 +1,14 @@
+package testutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// NewServer starts a stand-in HTTP server of an API and closes it when the test ends.
+func NewServer(t testing.TB, handler http.Handler) *httptest.Server {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}